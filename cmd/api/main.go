@@ -1,74 +1,158 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"go_api/internal/app/route"
+	"go_api/internal/app"
 	serverconfig "go_api/internal/config"
-	"go_api/internal/storage"
+	"go_api/internal/tracing"
+	"go_api/internal/util"
 )
 
 func main() {
-	// Load config
-	config, err := serverconfig.LoadConfig()
+	// Load config from the defaults, the config file, the environment and the flags
+	loader := serverconfig.NewLoader(flag.CommandLine)
+	printConfig := flag.Bool("print-config", false, "Print the configuration with secrets redacted, then exit")
+	flag.Parse()
+
+	config, err := loader.Load()
 	if err != nil {
-		log.Fatalf("Error loading config: %v", err)
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
 	}
-
-	// Connect to database
-	if err := storage.Connect(); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	if *printConfig {
+		if err := config.Dump(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
 	}
-	defer storage.Close()
 
-	// Run migrations
-	log.Println("Running database migrations...")
-	if err := storage.Migrate(); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	if err := run(loader, config); err != nil {
+		slog.Error("Server stopped with an error", "error", err)
+		os.Exit(1)
 	}
-	log.Println("Migrations completed successfully")
+	slog.Info("Server stopped")
+}
 
-	// Connect to Redis
-	redisClient := storage.ConnectRedis()
-	if redisClient == nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+// run starts the server and blocks until it fails or a shutdown signal is handled.
+// Deferred cleanup runs in reverse order: the app closes its workers, Redis and the database, then tracing stops.
+func run(loader *serverconfig.Loader, config *serverconfig.Config) error {
+	// Set up logging, the level changes on reload
+	logLevel := &slog.LevelVar{}
+	logLevel.Set(config.Log.Level)
+	slog.SetDefault(util.NewLogger(os.Stdout, config.Environment, logLevel))
+
+	// Set up tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), config.Tracing.Exporter, config.Tracing.SampleRate)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
 	}
-	defer redisClient.Close()
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Tracing shutdown error", "error", err)
+		}
+	}()
 
-	// Set up HTTP server
-	mux := http.NewServeMux()
+	// Connect to the database and Redis and wire the routes
+	application, err := app.New(config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := application.Close(); err != nil {
+			slog.Error("Cleanup error", "error", err)
+		}
+	}()
 
 	// Server instance
-	serverAddr := fmt.Sprintf(":%s", config.ServerPort)
+	serverAddr := fmt.Sprintf(":%d", config.Server.Port)
 	server := &http.Server{
 		Addr:    serverAddr,
-		Handler: route.SetupRoutes(mux, storage.GetDB(), redisClient),
+		Handler: application.Handler(),
 	}
 
-	// Setup graceful shutdown
+	listener, err := net.Listen("tcp", serverAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", serverAddr, err)
+	}
+
+	// Run server
+	serverErr := make(chan error, 1)
 	go func() {
-		sigint := make(chan os.Signal, 1)
-		signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
-		<-sigint
+		serverErr <- server.Serve(listener)
+	}()
+	application.Start()
+	slog.Info("Listening", "port", config.Server.Port, "url", "http://localhost"+serverAddr)
+
+	// Wait for a shutdown signal
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go reloadOnHangup(ctx, loader, config, application, logLevel)
+
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+	// A second signal kills the process
+	stop()
 
-		log.Println("Shutting down server...")
-		if err := server.Close(); err != nil {
-			log.Printf("Server shutdown error: %v", err)
+	return shutdown(server, application, config.Server.ShutdownDelay, config.Server.ShutdownTimeout)
+}
+
+// reloadOnHangup reloads the configuration on SIGHUP until ctx is done. The log level, the rate limits and
+// the CORS policy change at once; changes to the other settings are logged and wait for a restart.
+func reloadOnHangup(ctx context.Context, loader *serverconfig.Loader, config *serverconfig.Config, application *app.App, logLevel *slog.LevelVar) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
 		}
-		storage.Close()
-		os.Exit(0)
-	}()
 
-	fmt.Printf("Listening on port %s\n", config.ServerPort)
-	fmt.Printf("Serving on http://localhost%s\n", serverAddr)
+		next, err := loader.Load()
+		if err != nil {
+			slog.Error("Config reload failed, keeping the current config", "error", err)
+			continue
+		}
+		logLevel.Set(next.Log.Level)
+		application.Reload(next)
+		if settings := config.RestartRequired(next); len(settings) > 0 {
+			slog.Warn("Config reloaded, some changes need a restart", "settings", settings)
+		} else {
+			slog.Info("Config reloaded")
+		}
+	}
+}
 
-	// Run server
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Server failed: %v", err)
+// shutdown fails readiness, waits for load balancers to notice, then stops accepting connections
+// and waits for in-flight requests to finish
+func shutdown(server *http.Server, application *app.App, delay, timeout time.Duration) error {
+	slog.Info("Shutting down server", "delay", delay, "timeout", timeout)
+	application.Drain()
+	time.Sleep(delay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+		return fmt.Errorf("failed to drain connections: %w", err)
 	}
+	slog.Info("Connections drained")
+	return nil
 }
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+
+	"go_api/internal/app/model"
+	"go_api/internal/app/repository"
+	serverconfig "go_api/internal/config"
+	"go_api/internal/migration"
+	"go_api/internal/storage"
+	"go_api/internal/util"
+
+	"gorm.io/gorm"
+)
+
+const usage = `Usage: migrate [flags] <command> [arguments]
+
+Commands:
+  up [N]        Apply all pending migrations, or the next N
+  down [N]      Revert the latest applied migration, or the latest N
+  status        List migrations and whether they are applied
+  create NAME   Create empty up and down files in the migrations directory
+  promote EMAIL Give the admin role to the user with the email, e.g. to create the first admin.
+                It applies from the user's next login.
+
+Flags override the config file and the environment, run migrate -help to list them.
+`
+
+// migrationsDir is where create writes new migrations, relative to the repository root
+const migrationsDir = "migrations"
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		slog.Error("Migration failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage+"\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	loader := serverconfig.NewLoader(flags)
+	flags.Parse(args)
+	args = flags.Args()
+
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return errors.New("missing command")
+	}
+	command, args := args[0], args[1:]
+
+	// Creating files needs no database
+	if command == "create" {
+		if len(args) != 1 {
+			return errors.New("create needs a migration name")
+		}
+		paths, err := migration.Create(migrationsDir, args[0])
+		for _, path := range paths {
+			fmt.Println("Created", path)
+		}
+		return err
+	}
+
+	// promote takes an email instead of a number of migrations
+	var count int
+	if command == "promote" {
+		if len(args) != 1 {
+			return errors.New("promote needs the email of a user")
+		}
+	} else {
+		var err error
+		if count, err = countArg(args); err != nil {
+			return err
+		}
+	}
+
+	config, err := loader.Load()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	slog.SetDefault(util.NewLogger(os.Stderr, config.Environment, config.Log.Level))
+
+	db, err := storage.Connect(config, storage.NewMemoryWriteTracker())
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	migrator, err := storage.NewMigrator(db.Primary)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx, count)
+		fmt.Printf("Applied %d migrations\n", len(applied))
+		return err
+	case "down":
+		if count == 0 {
+			count = 1
+		}
+		reverted, err := migrator.Down(ctx, count)
+		fmt.Printf("Reverted %d migrations\n", len(reverted))
+		return err
+	case "promote":
+		return promote(ctx, db.Primary, args[0])
+	case "status":
+		states, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printStatus(states)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// promote gives the admin role to the user with the email
+func promote(ctx context.Context, db *gorm.DB, email string) error {
+	users := repository.NewUserRepository(db)
+	user, err := users.FindByEmail(ctx, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("no user has the email %s", email)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if err := users.UpdateRole(ctx, user.ID, model.RoleAdmin); err != nil {
+		return fmt.Errorf("failed to promote user: %w", err)
+	}
+	fmt.Printf("Promoted %s to admin\n", user.Username)
+	return nil
+}
+
+// countArg parses the optional number of migrations of up and down, 0 when missing
+func countArg(args []string) (int, error) {
+	switch len(args) {
+	case 0:
+		return 0, nil
+	case 1:
+		count, err := strconv.Atoi(args[0])
+		if err != nil || count <= 0 {
+			return 0, fmt.Errorf("number of migrations must be a positive integer: %s", args[0])
+		}
+		return count, nil
+	default:
+		return 0, errors.New("too many arguments")
+	}
+}
+
+func printStatus(states []migration.State) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, state := range states {
+		status, appliedAt := "pending", ""
+		if state.AppliedAt != nil {
+			status, appliedAt = "applied", state.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		switch {
+		case state.Modified:
+			status += ", modified since"
+		case state.Missing:
+			status += ", unknown to this binary"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", state.Version, state.Name, status, appliedAt)
+	}
+	w.Flush()
+}
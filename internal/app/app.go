@@ -0,0 +1,214 @@
+// Package app wires the API server: its connections, services, handlers and background workers
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"go_api/internal/app/cache"
+	"go_api/internal/app/handler"
+	"go_api/internal/app/openapi"
+	"go_api/internal/app/repository"
+	"go_api/internal/app/route"
+	"go_api/internal/app/service"
+	"go_api/internal/app/worker"
+	"go_api/internal/config"
+	"go_api/internal/lifecycle"
+	"go_api/internal/metrics"
+	"go_api/internal/middleware"
+	"go_api/internal/ratelimit"
+	"go_api/internal/storage"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// App is one API server. Apps share no state, so several can run in one process.
+type App struct {
+	cfg       *config.Config
+	db        *storage.Database
+	redis     *redis.Client
+	readiness lifecycle.Readiness
+	handler   http.Handler
+	scheduler *worker.BlogScheduler
+	metrics   *metrics.Metrics
+
+	// Middleware whose settings are reloaded
+	rateLimiter *middleware.RateLimiter
+	cors        *middleware.CORS
+
+	closers     []func() error // Close the connections opened by New, in reverse order
+	stopWorkers context.CancelFunc
+	workers     sync.WaitGroup
+}
+
+// Option customizes an App
+type Option func(*App)
+
+// WithDatabase makes the app use an open database instead of connecting to DATABASE_URL.
+// The app neither closes it nor exposes its pool metrics.
+func WithDatabase(db *storage.Database) Option {
+	return func(a *App) { a.db = db }
+}
+
+// WithRedis makes the app use an open Redis client instead of connecting to REDIS_ADDR.
+// The app neither closes it nor exposes its pool metrics.
+func WithRedis(client *redis.Client) Option {
+	return func(a *App) { a.redis = client }
+}
+
+// New connects to Redis and the database, applies or checks the migrations depending on MIGRATION_MODE,
+// and wires the services, handlers and middleware. Close releases what New opened, even if Start was not called.
+func New(cfg *config.Config, opts ...Option) (_ *App, err error) {
+	a := &App{cfg: cfg, metrics: metrics.New()}
+	for _, opt := range opts {
+		opt(a)
+	}
+	defer func() {
+		if err != nil {
+			a.Close()
+		}
+	}()
+
+	// Redis comes first, it tracks the writes of the database's read-your-writes routing
+	if a.redis == nil {
+		client, err := storage.ConnectRedis(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+		}
+		a.redis = client
+		a.closers = append(a.closers, func() error {
+			slog.Info("Closing Redis connection")
+			return client.Close()
+		})
+
+		if err := a.metrics.RegisterRedis(client); err != nil {
+			return nil, fmt.Errorf("failed to register Redis metrics: %w", err)
+		}
+	}
+
+	if a.db == nil {
+		db, err := storage.Connect(cfg, storage.NewRedisWriteTracker(a.redis))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		a.db = db
+		a.closers = append(a.closers, func() error {
+			slog.Info("Closing database connection")
+			return db.Close()
+		})
+
+		sqlDB, err := db.Primary.DB()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get database instance: %w", err)
+		}
+		if err := a.metrics.RegisterDB(sqlDB); err != nil {
+			return nil, fmt.Errorf("failed to register database metrics: %w", err)
+		}
+	}
+
+	if err := a.migrate(context.Background()); err != nil {
+		return nil, err
+	}
+
+	a.handler = a.routes()
+	return a, nil
+}
+
+// migrate applies the pending migrations, or refuses to start while some are pending, depending on MIGRATION_MODE
+func (a *App) migrate(ctx context.Context) error {
+	switch a.cfg.Database.MigrationMode {
+	case "apply":
+		slog.Info("Running database migrations")
+		if err := storage.Migrate(ctx, a.db.Primary); err != nil {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
+		slog.Info("Migrations completed successfully")
+	case "check":
+		version, err := storage.CheckSchema(ctx, a.db.Primary)
+		if err != nil {
+			return fmt.Errorf("database schema is not up to date, run make migrate-up: %w", err)
+		}
+		slog.Info("Database schema is up to date", "version", version)
+	}
+	return nil
+}
+
+// routes creates the services, handlers and middleware, and returns the handler of every route
+func (a *App) routes() http.Handler {
+	secret := []byte(a.cfg.Auth.JWTSecretKey)
+	exposeErrors := a.cfg.IsDevelopment()
+
+	userCache := cache.NewUserCache(a.redis, a.metrics)
+	blogs := repository.NewBlogRepository(a.db.Primary)
+	blogService := service.NewBlogService(blogs)
+	userService := service.NewUserService(repository.NewUserRepository(a.db.Primary), userCache, secret)
+	commentService := service.NewCommentService(repository.NewCommentRepository(a.db.Primary), blogs)
+	a.scheduler = worker.NewBlogScheduler(blogService, a.cfg.Scheduler.Interval)
+
+	// Rate limits are counted in Redis, and in memory while Redis is down
+	limiter := ratelimit.NewFallbackLimiter(ratelimit.NewRedisLimiter(a.redis), ratelimit.NewMemoryLimiter())
+	a.rateLimiter = middleware.NewRateLimiter(limiter, a.cfg.RateLimit, secret, a.metrics)
+
+	mux := http.NewServeMux()
+	a.cors = middleware.NewCORS(a.cfg.CORS, mux)
+
+	handlers := route.Handlers{
+		Health:      handler.NewHandler(a.healthChecker(), &a.readiness, exposeErrors),
+		User:        handler.NewUserHandler(userService, exposeErrors),
+		Blog:        handler.NewBlogHandler(blogService, exposeErrors),
+		Comment:     handler.NewCommentHandler(commentService, exposeErrors),
+		Docs:        handler.NewDocsHandler(openapi.Build()),
+		Auth:        middleware.NewAuthenticator(secret, userCache),
+		RateLimiter: a.rateLimiter,
+		CORS:        a.cors,
+		Metrics:     a.metrics,
+	}
+	return route.SetupRoutes(mux, handlers, a.cfg)
+}
+
+// Reload applies the settings that can change while the app runs: the rate limits and the CORS policy.
+// The other settings of cfg are ignored, see config.Config.RestartRequired.
+func (a *App) Reload(cfg *config.Config) {
+	a.rateLimiter.SetConfig(cfg.RateLimit)
+	a.cors.SetConfig(cfg.CORS)
+}
+
+// Handler returns the handler serving the API
+func (a *App) Handler() http.Handler {
+	return a.handler
+}
+
+// Start starts the background workers and marks the app ready for traffic. Call it once the server listens.
+func (a *App) Start() {
+	ctx, stop := context.WithCancel(context.Background())
+	a.stopWorkers = stop
+	a.workers.Go(func() { a.scheduler.Run(ctx) })
+	a.readiness.SetReady(true)
+}
+
+// Drain fails readiness so load balancers stop sending new requests, before the server drains its connections
+func (a *App) Drain() {
+	a.readiness.SetReady(false)
+}
+
+// Close stops the background workers, then closes the connections opened by New
+func (a *App) Close() error {
+	a.readiness.SetReady(false)
+	if a.stopWorkers != nil {
+		slog.Info("Stopping background workers")
+		a.stopWorkers()
+		a.workers.Wait()
+		a.stopWorkers = nil
+	}
+
+	var errs []error
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		errs = append(errs, a.closers[i]())
+	}
+	a.closers = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go_api/internal/app/model"
+	"go_api/internal/util"
+)
+
+// MemoryUserCache keeps the cache in memory, for tests. Entries expire like their Redis keys.
+type MemoryUserCache struct {
+	mu            sync.Mutex
+	users         map[uint]expiring[model.User]
+	blacklist     map[string]expiring[struct{}]
+	refreshTokens map[string]expiring[RefreshSession] // By token hash
+	families      map[refreshFamily]expiring[string]  // Current token hash of each family
+}
+
+type expiring[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+func (e expiring[T]) expired() bool {
+	return !time.Now().Before(e.expiresAt)
+}
+
+type refreshFamily struct {
+	userID   uint
+	familyID string
+}
+
+func NewMemoryUserCache() *MemoryUserCache {
+	return &MemoryUserCache{
+		users:         make(map[uint]expiring[model.User]),
+		blacklist:     make(map[string]expiring[struct{}]),
+		refreshTokens: make(map[string]expiring[RefreshSession]),
+		families:      make(map[refreshFamily]expiring[string]),
+	}
+}
+
+func expiresIn[T any](value T, ttl time.Duration) expiring[T] {
+	return expiring[T]{value: value, expiresAt: time.Now().Add(ttl)}
+}
+
+func (c *MemoryUserCache) GetUser(ctx context.Context, id uint) (*model.User, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.users[id]
+	if !ok || cached.expired() {
+		return nil, ErrUserNotCached
+	}
+	user := cached.value
+	user.Permissions = append([]model.Permission(nil), user.Permissions...)
+	return &user, nil
+}
+
+func (c *MemoryUserCache) SetUser(ctx context.Context, id uint, user *model.User) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached := *user
+	cached.Permissions = append([]model.Permission(nil), user.Permissions...)
+	c.users[id] = expiresIn(cached, userTTL)
+	return nil
+}
+
+func (c *MemoryUserCache) BlacklistToken(ctx context.Context, token string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.blacklist[token] = expiresIn(struct{}{}, ttl)
+	return nil
+}
+
+func (c *MemoryUserCache) IsTokenBlacklisted(ctx context.Context, token string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.blacklist[token]
+	return ok && !entry.expired(), nil
+}
+
+// SaveRefreshToken stores a refresh token as the current token of its family
+func (c *MemoryUserCache) SaveRefreshToken(ctx context.Context, token string, session RefreshSession, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tokenHash := util.HashToken(token)
+	c.refreshTokens[tokenHash] = expiresIn(session, ttl)
+	c.families[refreshFamily{session.UserID, session.FamilyID}] = expiresIn(tokenHash, ttl)
+	return nil
+}
+
+// RotateRefreshToken replaces oldToken with newToken in the same family.
+// Presenting a token that has already been rotated revokes the whole family.
+func (c *MemoryUserCache) RotateRefreshToken(ctx context.Context, oldToken, newToken string, ttl time.Duration) (*RefreshSession, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	oldHash := util.HashToken(oldToken)
+	cached, ok := c.refreshTokens[oldHash]
+	if !ok || cached.expired() {
+		return nil, ErrRefreshTokenNotFound
+	}
+	session := cached.value
+
+	family := refreshFamily{session.UserID, session.FamilyID}
+	current, ok := c.families[family]
+	if !ok || current.expired() {
+		// Family was revoked or has expired
+		return nil, ErrRefreshTokenNotFound
+	}
+	if current.value != oldHash {
+		delete(c.families, family)
+		return nil, ErrRefreshTokenReused
+	}
+
+	newHash := util.HashToken(newToken)
+	c.refreshTokens[newHash] = expiresIn(session, ttl)
+	c.families[family] = expiresIn(newHash, ttl)
+	return &session, nil
+}
+
+// GetRefreshSession returns the session a refresh token was issued for, even if the token was rotated since
+func (c *MemoryUserCache) GetRefreshSession(ctx context.Context, token string) (*RefreshSession, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.refreshTokens[util.HashToken(token)]
+	if !ok || cached.expired() {
+		return nil, ErrRefreshTokenNotFound
+	}
+	session := cached.value
+	return &session, nil
+}
+
+// RevokeRefreshFamily invalidates every refresh token issued in a family
+func (c *MemoryUserCache) RevokeRefreshFamily(ctx context.Context, userID uint, familyID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.families, refreshFamily{userID, familyID})
+	return nil
+}
+
+// CleanUserSession drops the cached profile and revokes every refresh token family of the user
+func (c *MemoryUserCache) CleanUserSession(ctx context.Context, userID uint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.users, userID)
+	// Also removes the user's refresh token families
+	for family := range c.families {
+		if family.userID == userID {
+			delete(c.families, family)
+		}
+	}
+	return nil
+}
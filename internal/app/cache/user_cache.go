@@ -6,46 +6,193 @@ import (
 	"errors"
 	"fmt"
 	"go_api/internal/app/model"
+	"go_api/internal/metrics"
+	"go_api/internal/util"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-type UserCache struct {
-	redis *redis.Client
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenReused   = errors.New("refresh token reused")
+)
+
+// RefreshSession is the data stored against a refresh token
+type RefreshSession struct {
+	UserID   uint   `json:"user_id"`
+	FamilyID string `json:"family_id"`
 }
 
-func NewUserCache(redis *redis.Client) *UserCache {
-	return &UserCache{redis: redis}
+// ErrUserNotCached is returned by GetUser when the user is not cached
+var ErrUserNotCached = errors.New("user not found")
+
+// UserCache caches user profiles and holds token state: revoked access tokens
+// and refresh token families
+type UserCache interface {
+	GetUser(ctx context.Context, id uint) (*model.User, error)
+	SetUser(ctx context.Context, id uint, user *model.User) error
+	BlacklistToken(ctx context.Context, token string, ttl time.Duration) error
+	IsTokenBlacklisted(ctx context.Context, token string) (bool, error)
+	SaveRefreshToken(ctx context.Context, token string, session RefreshSession, ttl time.Duration) error
+	RotateRefreshToken(ctx context.Context, oldToken, newToken string, ttl time.Duration) (*RefreshSession, error)
+	GetRefreshSession(ctx context.Context, token string) (*RefreshSession, error)
+	RevokeRefreshFamily(ctx context.Context, userID uint, familyID string) error
+	CleanUserSession(ctx context.Context, userID uint) error
 }
 
-func (c *UserCache) GetUser(ctx context.Context, id uint) (*model.User, error) {
+// userTTL is how long a user profile stays cached
+const userTTL = time.Minute * 5
+
+// RedisUserCache stores the cache in Redis
+type RedisUserCache struct {
+	redis   *redis.Client
+	metrics *metrics.Metrics // Counts the hits and misses
+}
+
+func NewUserCache(redis *redis.Client, m *metrics.Metrics) *RedisUserCache {
+	return &RedisUserCache{redis: redis, metrics: m}
+}
+
+func (c *RedisUserCache) GetUser(ctx context.Context, id uint) (*model.User, error) {
 	cacheKey := fmt.Sprintf("user:%d", id)
 	if cached, err := c.redis.Get(ctx, cacheKey).Result(); err == nil {
 		var user model.User
 		if err := json.Unmarshal([]byte(cached), &user); err == nil {
+			c.metrics.CacheHit("user")
 			return &user, nil
 		}
 	}
-	return nil, errors.New("user not found")
+	c.metrics.CacheMiss("user")
+	return nil, ErrUserNotCached
 }
 
-func (c *UserCache) SetUser(ctx context.Context, id uint, user *model.User) error {
+func (c *RedisUserCache) SetUser(ctx context.Context, id uint, user *model.User) error {
 	cacheKey := fmt.Sprintf("user:%d", id)
 	userJSON, err := json.Marshal(user)
 	if err != nil {
 		return err
 	}
-	return c.redis.Set(ctx, cacheKey, userJSON, time.Minute*5).Err()
+	return c.redis.Set(ctx, cacheKey, userJSON, userTTL).Err()
+}
+
+func (c *RedisUserCache) BlacklistToken(ctx context.Context, token string, ttl time.Duration) error {
+	return c.redis.Set(ctx, token, "blacklisted", ttl).Err()
+}
+
+func (c *RedisUserCache) IsTokenBlacklisted(ctx context.Context, token string) (bool, error) {
+	value, err := c.redis.Get(ctx, token).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	return value == "blacklisted", err
+}
+
+// SaveRefreshToken stores a refresh token as the current token of its family
+func (c *RedisUserCache) SaveRefreshToken(ctx context.Context, token string, session RefreshSession, ttl time.Duration) error {
+	sessionJSON, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	tokenHash := util.HashToken(token)
+	_, err = c.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, refreshTokenKey(tokenHash), sessionJSON, ttl)
+		pipe.Set(ctx, refreshFamilyKey(session.UserID, session.FamilyID), tokenHash, ttl)
+		return nil
+	})
+	return err
+}
+
+// RotateRefreshToken replaces oldToken with newToken in the same family.
+// Presenting a token that has already been rotated revokes the whole family.
+func (c *RedisUserCache) RotateRefreshToken(ctx context.Context, oldToken, newToken string, ttl time.Duration) (*RefreshSession, error) {
+	oldHash := util.HashToken(oldToken)
+	cached, err := c.redis.Get(ctx, refreshTokenKey(oldHash)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session RefreshSession
+	if err := json.Unmarshal([]byte(cached), &session); err != nil {
+		return nil, err
+	}
+	sessionJSON, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+
+	familyKey := refreshFamilyKey(session.UserID, session.FamilyID)
+	newHash := util.HashToken(newToken)
+	err = c.redis.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, familyKey).Result()
+		if errors.Is(err, redis.Nil) {
+			// Family was revoked or has expired
+			return ErrRefreshTokenNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if current != oldHash {
+			return ErrRefreshTokenReused
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, refreshTokenKey(newHash), sessionJSON, ttl)
+			pipe.Set(ctx, familyKey, newHash, ttl)
+			return nil
+		})
+		return err
+	}, familyKey)
+
+	switch {
+	case err == nil:
+		return &session, nil
+	case errors.Is(err, ErrRefreshTokenReused), errors.Is(err, redis.TxFailedErr):
+		// A concurrent rotation also counts as reuse of the same token
+		if err := c.RevokeRefreshFamily(ctx, session.UserID, session.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	default:
+		return nil, err
+	}
 }
 
-func (c *UserCache) BlacklistToken(ctx context.Context, token string) error {
-	return c.redis.Set(ctx, token, "blacklisted", time.Minute*5).Err()
+// GetRefreshSession returns the session a refresh token was issued for, even if the token was rotated since
+func (c *RedisUserCache) GetRefreshSession(ctx context.Context, token string) (*RefreshSession, error) {
+	cached, err := c.redis.Get(ctx, refreshTokenKey(util.HashToken(token))).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session RefreshSession
+	if err := json.Unmarshal([]byte(cached), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RevokeRefreshFamily invalidates every refresh token issued in a family
+func (c *RedisUserCache) RevokeRefreshFamily(ctx context.Context, userID uint, familyID string) error {
+	return c.redis.Del(ctx, refreshFamilyKey(userID, familyID)).Err()
 }
 
-func (c *UserCache) CleanUserSession(ctx context.Context, userId uint) error {
+// CleanUserSession drops the cached profile and revokes every refresh token family of the user
+func (c *RedisUserCache) CleanUserSession(ctx context.Context, userId uint) error {
 	userIdStr := fmt.Sprintf("user:%d", userId)
-	iter := c.redis.Scan(ctx, 0, userIdStr+"*", 0).Iterator()
+	if err := c.redis.Del(ctx, userIdStr).Err(); err != nil {
+		return fmt.Errorf("failed to clean user session: %v", err)
+	}
+
+	// Also removes the user's refresh token families
+	iter := c.redis.Scan(ctx, 0, userIdStr+":*", 0).Iterator()
 	for iter.Next(ctx) {
 		key := iter.Val()
 		if err := c.redis.Del(ctx, key).Err(); err != nil {
@@ -57,3 +204,11 @@ func (c *UserCache) CleanUserSession(ctx context.Context, userId uint) error {
 	}
 	return nil
 }
+
+func refreshTokenKey(tokenHash string) string {
+	return fmt.Sprintf("refresh_token:%s", tokenHash)
+}
+
+func refreshFamilyKey(userID uint, familyID string) string {
+	return fmt.Sprintf("user:%d:refresh_family:%s", userID, familyID)
+}
@@ -0,0 +1,37 @@
+package dto
+
+import "time"
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// PageQuery selects one page of a keyset-paginated list
+type PageQuery struct {
+	Limit  int
+	Cursor string
+	Sort   string
+	Desc   bool
+}
+
+type BlogFilter struct {
+	// Visibility: non-public blogs are only listed for their owner, or for everyone if ViewAll is set
+	ViewerID uint
+	ViewAll  bool
+
+	Status        string
+	AuthorID      uint
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Title         string   // Case-insensitive substring match
+	Tags          []string // Normalized tag names
+	MatchAllTags  bool     // Require every tag instead of any of them
+}
+
+type UserFilter struct {
+	Role          string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Username      string // Case-insensitive substring match
+}
@@ -1,9 +1,11 @@
 package dto
 
+import "time"
+
 type CreateUserRequest struct {
-	Username string `json:"username" validate:"required,min=3,max=30"`
+	Username string `json:"username" validate:"required,min=3,max=30,username"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8,max=30"`
+	Password string `json:"password" validate:"required,min=8,max=30,password"`
 }
 
 type LoginUserRequest struct {
@@ -11,7 +13,45 @@ type LoginUserRequest struct {
 	Password string `json:"password" validate:"required,min=8,max=30"`
 }
 
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user admin"`
+}
+
+type UpdateUserPermissionsRequest struct {
+	Permissions []string `json:"permissions" validate:"max=20,dive,required"` // Replaces the extra grants, an empty list removes them
+}
+
 type CreateBlogRequest struct {
-	Title   string `json:"title" validate:"required,min=3,max=100"`
-	Content string `json:"content" validate:"required,min=10"`
+	Title     string     `json:"title" validate:"required,min=3,max=100"`
+	Content   string     `json:"content" validate:"required,min=10,max=100000"`
+	Status    string     `json:"status,omitempty" validate:"omitempty,oneof=draft scheduled published"` // Defaults to published
+	PublishAt *time.Time `json:"publish_at,omitempty" validate:"required_if=Status scheduled"`
+	Tags      []string   `json:"tags,omitempty" validate:"max=10,dive,max=50"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"` // Its family is revoked, the other sessions stay logged in
+}
+
+type UpdateBlogRequest struct {
+	Title   string    `json:"title" validate:"required,min=3,max=100"`
+	Content string    `json:"content" validate:"required,min=10,max=100000"`
+	Tags    *[]string `json:"tags,omitempty" validate:"omitempty,max=10,dive,max=50"` // Replaces the tags if present, keeps them otherwise
+}
+
+type PublishBlogRequest struct {
+	PublishAt *time.Time `json:"publish_at,omitempty"` // Schedules the blog if in the future, publishes it now otherwise
+}
+
+type CreateCommentRequest struct {
+	Content  string `json:"content" validate:"required,max=5000"`
+	ParentID *uint  `json:"parent_id,omitempty"` // Comment being replied to, omitted for top-level comments
+}
+
+type UpdateCommentRequest struct {
+	Content string `json:"content" validate:"required,max=5000"`
 }
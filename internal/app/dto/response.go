@@ -0,0 +1,20 @@
+package dto
+
+import "go_api/internal/util"
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// BlogRevisionDiff is the line diff from a blog revision to the blog's current version
+type BlogRevisionDiff struct {
+	BlogID      uint            `json:"blog_id"`
+	FromVersion uint            `json:"from_version"`
+	ToVersion   uint            `json:"to_version"`
+	FromTitle   string          `json:"from_title"`
+	ToTitle     string          `json:"to_title"`
+	Lines       []util.DiffLine `json:"lines"`
+}
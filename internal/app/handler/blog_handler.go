@@ -1,23 +1,32 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strings"
 
 	"go_api/internal/app/dto"
+	"go_api/internal/app/model"
 	"go_api/internal/app/service"
 	"go_api/internal/middleware"
 	"go_api/internal/util"
 )
 
 type BlogHandler struct {
+	errorWriter
 	service *service.BlogService
 }
 
-func NewBlogHandler(service *service.BlogService) *BlogHandler {
+// NewBlogHandler creates the handler. exposeErrors returns the text of internal errors to clients.
+func NewBlogHandler(service *service.BlogService, exposeErrors bool) *BlogHandler {
 	return &BlogHandler{
-		service: service,
+		errorWriter: errorWriter{exposeErrors: exposeErrors},
+		service:     service,
 	}
 }
 
@@ -28,30 +37,20 @@ func (h *BlogHandler) CreateBlogHandler() http.HandlerFunc {
 
 		// Decode and validate request body
 		var req dto.CreateBlogRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			util.ResponseWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
-			return
-		}
-		if err := util.Validate(req); err != nil {
-			util.ResponseWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		if !decodeRequest(w, r, &req) {
 			return
 		}
 
 		// Get claims from context
-		claims, ok := ctx.Value(middleware.UserClaimsKey).(*util.UserClaims)
+		claims, ok := userClaims(w, r)
 		if !ok {
-			util.ResponseWithError(w, http.StatusUnauthorized, "Unauthorized", "Unauthorized")
 			return
 		}
 
 		// Create blog in blog service
 		blog, err := h.service.CreateBlog(ctx, req, claims.UserID)
 		if err != nil {
-			if errors.Is(err, service.ErrBlogCreation) {
-				util.ResponseWithError(w, http.StatusInternalServerError, "Failed to create blog", err.Error())
-			} else {
-				util.ResponseWithError(w, http.StatusInternalServerError, "Internal server error", err.Error())
-			}
+			h.writeError(w, r, err)
 			return
 		}
 
@@ -66,25 +65,208 @@ func (h *BlogHandler) GetBlogHandler() http.HandlerFunc {
 
 		id := r.PathValue("id")
 		if id == "" {
-			util.ResponseWithError(w, http.StatusBadRequest, "Invalid request", "Blog ID is required")
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Blog ID is required")
 			return
 		}
 
 		// Get blog from blog service
-		blog, err := h.service.GetBlog(ctx, id)
+		viewerID, viewAll := blogViewer(ctx)
+		blog, err := h.service.GetBlog(ctx, id, viewerID, viewAll)
 		if err != nil {
-			if errors.Is(err, service.ErrBlogNotFound) {
-				util.ResponseWithError(w, http.StatusNotFound, "Blog not found", err.Error())
-			} else {
-				util.ResponseWithError(w, http.StatusInternalServerError, "Internal server error", err.Error())
-			}
+			h.writeError(w, r, err)
 			return
 		}
 
+		w.Header().Set("ETag", util.VersionETag(blog.Version))
 		util.ResponseWithSuccess(w, http.StatusOK, "Blog retrieved successfully", blog)
 	}
 }
 
+// UpdateBlogHandler replaces a blog's title and content.
+// The If-Match header must carry the blog's current ETag.
+func (h *BlogHandler) UpdateBlogHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id := r.PathValue("id")
+		if id == "" {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Blog ID is required")
+			return
+		}
+
+		ifMatch, ok := parseIfMatch(w, r, true)
+		if !ok {
+			return
+		}
+
+		// Decode and validate request body
+		var req dto.UpdateBlogRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+
+		// Get claims from context
+		claims, ok := userClaims(w, r)
+		if !ok {
+			return
+		}
+
+		// Update blog in blog service
+		manageAny := claims.HasPermission(string(model.PermManageBlogs))
+		blog, err := h.service.UpdateBlog(ctx, id, req, ifMatch, claims.UserID, manageAny)
+		h.writeBlogUpdateResponse(w, r, blog, err)
+	}
+}
+
+// PatchBlogHandler partially updates a blog with a JSON Merge Patch (RFC 7396).
+// The If-Match header must carry the blog's current ETag.
+func (h *BlogHandler) PatchBlogHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id := r.PathValue("id")
+		if id == "" {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Blog ID is required")
+			return
+		}
+
+		contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if contentType != "application/merge-patch+json" && contentType != "application/json" {
+			util.ResponseWithError(w, r, http.StatusUnsupportedMediaType, util.CodeUnsupportedMediaType, "Content-Type must be application/merge-patch+json")
+			return
+		}
+
+		ifMatch, ok := parseIfMatch(w, r, true)
+		if !ok {
+			return
+		}
+
+		patch, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyError(w, r, err, "Request body could not be read")
+			return
+		}
+
+		// Get claims from context
+		claims, ok := userClaims(w, r)
+		if !ok {
+			return
+		}
+
+		// Patch blog in blog service
+		manageAny := claims.HasPermission(string(model.PermManageBlogs))
+		blog, err := h.service.PatchBlog(ctx, id, patch, ifMatch, claims.UserID, manageAny)
+		h.writeBlogUpdateResponse(w, r, blog, err)
+	}
+}
+
+// PublishBlogHandler publishes a blog, or schedules it when publish_at is in the future
+func (h *BlogHandler) PublishBlogHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The request body is optional
+		var req dto.PublishBlogRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeBodyError(w, r, err, "Request body must be a valid JSON object")
+			return
+		}
+
+		h.blogStatusHandler(w, r, "Blog published successfully", func(ctx context.Context, id string, ifMatch util.IfMatch, userID uint, manageAny bool) (*model.Blog, error) {
+			return h.service.PublishBlog(ctx, id, req, ifMatch, userID, manageAny)
+		})
+	}
+}
+
+// UnpublishBlogHandler turns a blog back into a draft
+func (h *BlogHandler) UnpublishBlogHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.blogStatusHandler(w, r, "Blog unpublished successfully", h.service.UnpublishBlog)
+	}
+}
+
+// ArchiveBlogHandler archives a blog
+func (h *BlogHandler) ArchiveBlogHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.blogStatusHandler(w, r, "Blog archived successfully", h.service.ArchiveBlog)
+	}
+}
+
+// blogStatusHandler runs a blog status change. If-Match is optional for status changes.
+func (h *BlogHandler) blogStatusHandler(w http.ResponseWriter, r *http.Request, message string, change func(ctx context.Context, id string, ifMatch util.IfMatch, userID uint, manageAny bool) (*model.Blog, error)) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	if id == "" {
+		util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Blog ID is required")
+		return
+	}
+
+	ifMatch, ok := parseIfMatch(w, r, false)
+	if !ok {
+		return
+	}
+
+	// Get claims from context
+	claims, ok := userClaims(w, r)
+	if !ok {
+		return
+	}
+
+	manageAny := claims.HasPermission(string(model.PermManageBlogs))
+	blog, err := change(ctx, id, ifMatch, claims.UserID, manageAny)
+	if err != nil {
+		h.writeBlogUpdateResponse(w, r, blog, err)
+		return
+	}
+
+	w.Header().Set("ETag", util.VersionETag(blog.Version))
+	util.ResponseWithSuccess(w, http.StatusOK, message, blog)
+}
+
+// blogViewer returns the requesting user's ID and whether they can see every blog,
+// on routes where authentication is optional
+func blogViewer(ctx context.Context) (uint, bool) {
+	claims, ok := ctx.Value(middleware.UserClaimsKey).(*util.UserClaims)
+	if !ok {
+		return 0, false
+	}
+	return claims.UserID, claims.HasPermission(string(model.PermManageBlogs))
+}
+
+// parseIfMatch reads the If-Match header, writing a problem if it is malformed or
+// if it is required and missing. A missing optional header matches any version.
+func parseIfMatch(w http.ResponseWriter, r *http.Request, required bool) (util.IfMatch, bool) {
+	header := strings.Join(r.Header.Values("If-Match"), ",")
+	if header == "" {
+		if !required {
+			return util.IfMatch{}, true
+		}
+		util.ResponseWithError(w, r, http.StatusPreconditionRequired, util.CodePreconditionRequired, "If-Match header is required")
+		return util.IfMatch{}, false
+	}
+
+	ifMatch, err := util.ParseIfMatch(header)
+	if err != nil {
+		util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Invalid If-Match header: "+err.Error())
+		return util.IfMatch{}, false
+	}
+	return ifMatch, true
+}
+
+// writeBlogUpdateResponse writes the response shared by the update and patch handlers
+func (h *BlogHandler) writeBlogUpdateResponse(w http.ResponseWriter, r *http.Request, blog *model.Blog, err error) {
+	if err != nil {
+		if errors.Is(err, service.ErrBlogVersionConflict) {
+			w.Header().Set("ETag", util.VersionETag(blog.Version))
+			err = fmt.Errorf("%w, current version is %d", err, blog.Version)
+		}
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", util.VersionETag(blog.Version))
+	util.ResponseWithSuccess(w, http.StatusOK, "Blog updated successfully", blog)
+}
+
 // DeleteBlogHandler deletes a blog by its ID
 func (h *BlogHandler) DeleteBlogHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -92,25 +274,21 @@ func (h *BlogHandler) DeleteBlogHandler() http.HandlerFunc {
 
 		id := r.PathValue("id")
 		if id == "" {
-			util.ResponseWithError(w, http.StatusBadRequest, "Invalid request", "Blog ID is required")
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Blog ID is required")
 			return
 		}
 
 		// Get claims from context
-		claims, ok := ctx.Value(middleware.UserClaimsKey).(*util.UserClaims)
+		claims, ok := userClaims(w, r)
 		if !ok {
-			util.ResponseWithError(w, http.StatusUnauthorized, "Unauthorized", "Unauthorized")
 			return
 		}
 
 		// Delete blog from blog service
-		err := h.service.DeleteBlog(ctx, id, claims.UserID)
+		manageAny := claims.HasPermission(string(model.PermManageBlogs))
+		err := h.service.DeleteBlog(ctx, id, claims.UserID, manageAny)
 		if err != nil {
-			if errors.Is(err, service.ErrBlogDeletion) {
-				util.ResponseWithError(w, http.StatusInternalServerError, "Failed to delete blog", err.Error())
-			} else {
-				util.ResponseWithError(w, http.StatusInternalServerError, "Internal server error", err.Error())
-			}
+			h.writeError(w, r, err)
 			return
 		}
 
@@ -118,18 +296,68 @@ func (h *BlogHandler) DeleteBlogHandler() http.HandlerFunc {
 	}
 }
 
-// ListBlogsHandler lists all blogs
+// ListBlogsHandler lists blogs with cursor pagination, filtering and sorting
 func (h *BlogHandler) ListBlogsHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		// List all blogs from blog service
-		blogs, err := h.service.ListBlogs(ctx)
+		// Parse pagination and filters
+		query := r.URL.Query()
+		page, err := parsePageQuery(query, "-created_at")
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidQuery, err.Error())
+			return
+		}
+		filter, err := parseBlogFilter(query)
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidQuery, err.Error())
+			return
+		}
+
+		// List blogs from blog service
+		filter.ViewerID, filter.ViewAll = blogViewer(ctx)
+		blogs, nextCursor, err := h.service.ListBlogs(ctx, filter, page)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		util.ResponseWithPage(w, http.StatusOK, "List of blogs", blogs, nextCursor)
+	}
+}
+
+// SearchBlogsHandler runs a full-text search over blogs, ranked by relevance by default
+func (h *BlogHandler) SearchBlogsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		query := r.URL.Query()
+		q := query.Get("q")
+		if q == "" {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidQuery, "q is required")
+			return
+		}
+
+		// Parse pagination and filters
+		page, err := parsePageQuery(query, "-rank")
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidQuery, err.Error())
+			return
+		}
+		filter, err := parseBlogFilter(query)
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidQuery, err.Error())
+			return
+		}
+
+		// Search blogs in blog service
+		filter.ViewerID, filter.ViewAll = blogViewer(ctx)
+		results, nextCursor, err := h.service.SearchBlogs(ctx, q, filter, page)
 		if err != nil {
-			util.ResponseWithError(w, http.StatusInternalServerError, "Failed to list blogs", err.Error())
+			h.writeError(w, r, err)
 			return
 		}
 
-		util.ResponseWithSuccess(w, http.StatusOK, "List of all blogs", blogs)
+		util.ResponseWithPage(w, http.StatusOK, "Search results", results, nextCursor)
 	}
 }
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"go_api/internal/app/model"
+	"go_api/internal/util"
+)
+
+// ListRevisionsHandler lists the previous versions of a blog, newest first
+func (h *BlogHandler) ListRevisionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id := r.PathValue("id")
+		if id == "" {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Blog ID is required")
+			return
+		}
+
+		page, err := parsePageQuery(r.URL.Query(), "-version")
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidQuery, err.Error())
+			return
+		}
+
+		// Get claims from context
+		claims, ok := userClaims(w, r)
+		if !ok {
+			return
+		}
+
+		// List revisions from blog service
+		manageAny := claims.HasPermission(string(model.PermManageBlogs))
+		revisions, nextCursor, err := h.service.ListRevisions(ctx, id, page, claims.UserID, manageAny)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		util.ResponseWithPage(w, http.StatusOK, "List of revisions", revisions, nextCursor)
+	}
+}
+
+// GetRevisionHandler gets a previous version of a blog
+func (h *BlogHandler) GetRevisionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, version, ok := parseRevisionPath(w, r)
+		if !ok {
+			return
+		}
+
+		// Get claims from context
+		claims, ok := userClaims(w, r)
+		if !ok {
+			return
+		}
+
+		// Get revision from blog service
+		manageAny := claims.HasPermission(string(model.PermManageBlogs))
+		revision, err := h.service.GetRevision(ctx, id, version, claims.UserID, manageAny)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		util.ResponseWithSuccess(w, http.StatusOK, "Revision retrieved successfully", revision)
+	}
+}
+
+// DiffRevisionHandler shows the line diff from a previous version of a blog to its current content
+func (h *BlogHandler) DiffRevisionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, version, ok := parseRevisionPath(w, r)
+		if !ok {
+			return
+		}
+
+		// Get claims from context
+		claims, ok := userClaims(w, r)
+		if !ok {
+			return
+		}
+
+		// Diff revision in blog service
+		manageAny := claims.HasPermission(string(model.PermManageBlogs))
+		diff, err := h.service.DiffRevision(ctx, id, version, claims.UserID, manageAny)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		util.ResponseWithSuccess(w, http.StatusOK, "Revision diff", diff)
+	}
+}
+
+// RestoreRevisionHandler restores the title and content of a previous version of a blog.
+// If-Match is optional.
+func (h *BlogHandler) RestoreRevisionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, version, ok := parseRevisionPath(w, r)
+		if !ok {
+			return
+		}
+
+		ifMatch, ok := parseIfMatch(w, r, false)
+		if !ok {
+			return
+		}
+
+		// Get claims from context
+		claims, ok := userClaims(w, r)
+		if !ok {
+			return
+		}
+
+		// Restore revision in blog service
+		manageAny := claims.HasPermission(string(model.PermManageBlogs))
+		blog, err := h.service.RestoreRevision(ctx, id, version, ifMatch, claims.UserID, manageAny)
+		if err != nil {
+			h.writeBlogUpdateResponse(w, r, blog, err)
+			return
+		}
+
+		w.Header().Set("ETag", util.VersionETag(blog.Version))
+		util.ResponseWithSuccess(w, http.StatusOK, "Revision restored successfully", blog)
+	}
+}
+
+// parseRevisionPath reads the blog ID and revision version from the path
+func parseRevisionPath(w http.ResponseWriter, r *http.Request) (string, uint, bool) {
+	id := r.PathValue("id")
+	if id == "" {
+		util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Blog ID is required")
+		return "", 0, false
+	}
+
+	version, err := strconv.ParseUint(r.PathValue("rev"), 10, 64)
+	if err != nil {
+		util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Revision must be a version number")
+		return "", 0, false
+	}
+	return id, uint(version), true
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"go_api/internal/app/dto"
+	"go_api/internal/util"
+)
+
+// ListTagsHandler lists the tags of published blogs with their number of blogs, most used first
+func (h *BlogHandler) ListTagsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		limit := dto.MaxPageLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > dto.MaxPageLimit {
+				util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidQuery, fmt.Sprintf("limit must be a number between 1 and %d", dto.MaxPageLimit))
+				return
+			}
+			limit = n
+		}
+
+		// List tags from blog service
+		tags, err := h.service.ListTags(ctx, limit)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		util.ResponseWithSuccess(w, http.StatusOK, "List of tags", tags)
+	}
+}
+
+// ListBlogsByTagHandler lists the blogs with a tag, with the same pagination and filters as ListBlogsHandler
+func (h *BlogHandler) ListBlogsByTagHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		tag := util.NormalizeTag(r.PathValue("tag"))
+		if tag == "" {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Tag is required")
+			return
+		}
+
+		// Parse pagination and filters
+		query := r.URL.Query()
+		page, err := parsePageQuery(query, "-created_at")
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidQuery, err.Error())
+			return
+		}
+		filter, err := parseBlogFilter(query)
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidQuery, err.Error())
+			return
+		}
+		filter.Tags, filter.MatchAllTags = []string{tag}, false
+
+		// List blogs from blog service
+		filter.ViewerID, filter.ViewAll = blogViewer(ctx)
+		blogs, nextCursor, err := h.service.ListBlogs(ctx, filter, page)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		util.ResponseWithPage(w, http.StatusOK, "List of blogs", blogs, nextCursor)
+	}
+}
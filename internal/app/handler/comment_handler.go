@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"net/http"
+
+	"go_api/internal/app/dto"
+	"go_api/internal/app/model"
+	"go_api/internal/app/service"
+	"go_api/internal/util"
+)
+
+type CommentHandler struct {
+	errorWriter
+	service *service.CommentService
+}
+
+// NewCommentHandler creates the handler. exposeErrors returns the text of internal errors to clients.
+func NewCommentHandler(service *service.CommentService, exposeErrors bool) *CommentHandler {
+	return &CommentHandler{
+		errorWriter: errorWriter{exposeErrors: exposeErrors},
+		service:     service,
+	}
+}
+
+// ListCommentsHandler lists a blog's comment threads, paginated by top-level comment
+func (h *CommentHandler) ListCommentsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		blogID := r.PathValue("id")
+		if blogID == "" {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Blog ID is required")
+			return
+		}
+
+		page, err := parsePageQuery(r.URL.Query(), "created_at")
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidQuery, err.Error())
+			return
+		}
+
+		// List comments from comment service
+		viewerID, viewAll := blogViewer(ctx)
+		comments, nextCursor, err := h.service.ListComments(ctx, blogID, page, viewerID, viewAll)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		util.ResponseWithPage(w, http.StatusOK, "List of comments", comments, nextCursor)
+	}
+}
+
+// ListRepliesHandler lists the replies in the thread of a top-level comment, oldest first by default
+func (h *CommentHandler) ListRepliesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		blogID, commentID := r.PathValue("id"), r.PathValue("commentId")
+		if blogID == "" || commentID == "" {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Blog ID and comment ID are required")
+			return
+		}
+
+		page, err := parsePageQuery(r.URL.Query(), "created_at")
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidQuery, err.Error())
+			return
+		}
+
+		// List replies from comment service
+		viewerID, viewAll := blogViewer(ctx)
+		replies, nextCursor, err := h.service.ListReplies(ctx, blogID, commentID, page, viewerID, viewAll)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		util.ResponseWithPage(w, http.StatusOK, "List of replies", replies, nextCursor)
+	}
+}
+
+// CreateCommentHandler comments on a blog or replies to a comment
+func (h *CommentHandler) CreateCommentHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		blogID := r.PathValue("id")
+		if blogID == "" {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Blog ID is required")
+			return
+		}
+
+		// Decode and validate request body
+		var req dto.CreateCommentRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+
+		// Get claims from context
+		claims, ok := userClaims(w, r)
+		if !ok {
+			return
+		}
+
+		// Create comment in comment service
+		viewAll := claims.HasPermission(string(model.PermManageBlogs))
+		comment, err := h.service.CreateComment(ctx, blogID, req, claims.UserID, viewAll)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		util.ResponseWithSuccess(w, http.StatusCreated, "Comment created successfully", comment)
+	}
+}
+
+// UpdateCommentHandler edits a comment (author only)
+func (h *CommentHandler) UpdateCommentHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		blogID, commentID := r.PathValue("id"), r.PathValue("commentId")
+		if blogID == "" || commentID == "" {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Blog ID and comment ID are required")
+			return
+		}
+
+		// Decode and validate request body
+		var req dto.UpdateCommentRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+
+		// Get claims from context
+		claims, ok := userClaims(w, r)
+		if !ok {
+			return
+		}
+
+		// Update comment in comment service
+		viewAll := claims.HasPermission(string(model.PermManageBlogs))
+		comment, err := h.service.UpdateComment(ctx, blogID, commentID, req, claims.UserID, viewAll)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		util.ResponseWithSuccess(w, http.StatusOK, "Comment updated successfully", comment)
+	}
+}
+
+// DeleteCommentHandler deletes a comment (author, blog owner or admin)
+func (h *CommentHandler) DeleteCommentHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		blogID, commentID := r.PathValue("id"), r.PathValue("commentId")
+		if blogID == "" || commentID == "" {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "Blog ID and comment ID are required")
+			return
+		}
+
+		// Get claims from context
+		claims, ok := userClaims(w, r)
+		if !ok {
+			return
+		}
+
+		// Delete comment in comment service
+		viewAll := claims.HasPermission(string(model.PermManageBlogs))
+		manageAny := claims.HasPermission(string(model.PermManageComments))
+		err := h.service.DeleteComment(ctx, blogID, commentID, claims.UserID, viewAll, manageAny)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		util.ResponseWithSuccess(w, http.StatusOK, "Comment deleted successfully", nil)
+	}
+}
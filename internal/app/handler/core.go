@@ -1,8 +1,17 @@
 package handler
 
+import (
+	"go_api/internal/health"
+	"go_api/internal/lifecycle"
+)
+
 type Handler struct {
+	health       *health.Checker
+	readiness    *lifecycle.Readiness
+	exposeErrors bool
 }
 
-func NewHandler() *Handler {
-	return &Handler{}
+// NewHandler creates the health handler. exposeErrors returns the errors of failed dependency checks to clients.
+func NewHandler(checker *health.Checker, readiness *lifecycle.Readiness, exposeErrors bool) *Handler {
+	return &Handler{health: checker, readiness: readiness, exposeErrors: exposeErrors}
 }
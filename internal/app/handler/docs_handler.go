@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	swaggerfiles "github.com/swaggo/files/v2"
+
+	"go_api/internal/app/openapi"
+)
+
+// swaggerUI is where the docs page loads Swagger UI from. The files are embedded in the binary
+// by github.com/swaggo/files/v2 v2.0.2, which pins Swagger UI 5.18.2.
+const swaggerUI = "/docs"
+
+// docsAssets are the Swagger UI files served under swaggerUI, with their content types
+var docsAssets = map[string]docsAsset{
+	"swagger-ui.css":       loadDocsAsset("swagger-ui.css", "text/css; charset=utf-8"),
+	"swagger-ui-bundle.js": loadDocsAsset("swagger-ui-bundle.js", "text/javascript; charset=utf-8"),
+}
+
+type docsAsset struct {
+	contentType string
+	data        []byte
+	integrity   string // Subresource Integrity of data, checked by the browser before using the file
+}
+
+func loadDocsAsset(name, contentType string) docsAsset {
+	data, err := fs.ReadFile(swaggerfiles.FS, name)
+	if err != nil {
+		panic("swagger ui file " + name + " is missing: " + err.Error())
+	}
+	return docsAsset{contentType: contentType, data: data, integrity: integrity(data)}
+}
+
+// integrity returns the Subresource Integrity value of data, like "sha384-..."
+func integrity(data []byte) string {
+	sum := sha512.Sum384(data)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// docsScript starts Swagger UI. Its hash is the only inline script allowed by docsPolicy.
+const docsScript = `
+		window.onload = () => {
+			window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+		};
+	`
+
+// docsPage renders the OpenAPI document with Swagger UI
+var docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<title>Go API Docs</title>
+	<link rel="stylesheet" href="` + swaggerUI + `/swagger-ui.css" integrity="` + docsAssets["swagger-ui.css"].integrity + `">
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script src="` + swaggerUI + `/swagger-ui-bundle.js" integrity="` + docsAssets["swagger-ui-bundle.js"].integrity + `"></script>
+	<script>` + docsScript + `</script>
+</body>
+</html>
+`
+
+// docsPolicy lets the docs page load nothing but the pinned Swagger UI files, its own script and the OpenAPI document
+var docsPolicy = strings.Join([]string{
+	"default-src 'none'",
+	"script-src 'self' 'sha256-" + scriptHash(docsScript) + "'",
+	"style-src 'self'",
+	"img-src 'self' data:",
+	"connect-src 'self'",
+	"base-uri 'none'",
+	"form-action 'none'",
+	"frame-ancestors 'none'",
+}, "; ")
+
+func scriptHash(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+type DocsHandler struct {
+	doc *openapi.Document
+}
+
+func NewDocsHandler(doc *openapi.Document) *DocsHandler {
+	return &DocsHandler{
+		doc: doc,
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document
+func (h *DocsHandler) OpenAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.doc)
+	}
+}
+
+// DocsUIHandler serves the interactive documentation page
+func (h *DocsHandler) DocsUIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", docsPolicy)
+		w.Write([]byte(docsPage))
+	}
+}
+
+// DocsAssetHandler serves the Swagger UI files of the docs page
+func (h *DocsHandler) DocsAssetHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		asset, ok := docsAssets[r.PathValue("file")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", asset.contentType)
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Write(asset.data)
+	}
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"go_api/internal/app/service"
+	"go_api/internal/middleware"
+	"go_api/internal/util"
+)
+
+// apiError is how a service error is reported to clients
+type apiError struct {
+	err    error
+	status int
+	code   string // Stable, clients can match on it
+}
+
+// apiErrors maps the service errors to their status and code. Wrapped errors match too.
+var apiErrors = []apiError{
+	// Users
+	{service.ErrUserNotFound, http.StatusNotFound, "user_not_found"},
+	{service.ErrInvalidPassword, http.StatusUnauthorized, "invalid_password"},
+	{service.ErrInvalidRefreshToken, http.StatusUnauthorized, "invalid_refresh_token"},
+	{service.ErrRefreshTokenReuse, http.StatusUnauthorized, "refresh_token_reused"},
+	{service.ErrInvalidRole, http.StatusBadRequest, "invalid_role"},
+	{service.ErrInvalidPermission, http.StatusBadRequest, "invalid_permission"},
+	{service.ErrTokenGeneration, http.StatusInternalServerError, "token_generation_failed"},
+	{service.ErrPasswordHashing, http.StatusInternalServerError, "password_hashing_failed"},
+	{service.ErrUserCreation, http.StatusInternalServerError, "user_creation_failed"},
+	{service.ErrUserUpdate, http.StatusInternalServerError, "user_update_failed"},
+	{service.ErrUserList, http.StatusInternalServerError, "user_list_failed"},
+	{service.ErrCacheOperation, http.StatusInternalServerError, "cache_operation_failed"},
+	{service.ErrTokenBlacklist, http.StatusInternalServerError, "token_blacklist_failed"},
+	{service.ErrSessionCleanup, http.StatusInternalServerError, "session_cleanup_failed"},
+
+	// Blogs
+	{service.ErrBlogNotFound, http.StatusNotFound, "blog_not_found"},
+	{service.ErrBlogForbidden, http.StatusForbidden, "blog_forbidden"},
+	{service.ErrBlogVersionConflict, http.StatusPreconditionFailed, "version_conflict"},
+	{service.ErrInvalidBlogUpdate, http.StatusBadRequest, "invalid_blog_update"},
+	{service.ErrInvalidPublishTime, http.StatusBadRequest, "invalid_publish_time"},
+	{service.ErrInvalidSearchQuery, http.StatusBadRequest, "invalid_search_query"},
+	{service.ErrBlogCreation, http.StatusInternalServerError, "blog_creation_failed"},
+	{service.ErrBlogUpdate, http.StatusInternalServerError, "blog_update_failed"},
+	{service.ErrBlogDeletion, http.StatusInternalServerError, "blog_deletion_failed"},
+	{service.ErrBlogListFailed, http.StatusInternalServerError, "blog_list_failed"},
+	{service.ErrBlogSearchFailed, http.StatusInternalServerError, "blog_search_failed"},
+	{service.ErrTagListFailed, http.StatusInternalServerError, "tag_list_failed"},
+
+	// Revisions
+	{service.ErrRevisionNotFound, http.StatusNotFound, "revision_not_found"},
+	{service.ErrRevisionListFailed, http.StatusInternalServerError, "revision_list_failed"},
+	{service.ErrRevisionDiffTooLarge, http.StatusUnprocessableEntity, "revision_diff_too_large"},
+
+	// Comments
+	{service.ErrCommentNotFound, http.StatusNotFound, "comment_not_found"},
+	{service.ErrCommentForbidden, http.StatusForbidden, "comment_forbidden"},
+	{service.ErrInvalidParentComment, http.StatusBadRequest, "invalid_parent_comment"},
+	{service.ErrCommentTooDeep, http.StatusBadRequest, "comment_too_deep"},
+	{service.ErrCommentCreation, http.StatusInternalServerError, "comment_creation_failed"},
+	{service.ErrCommentUpdate, http.StatusInternalServerError, "comment_update_failed"},
+	{service.ErrCommentDeletion, http.StatusInternalServerError, "comment_deletion_failed"},
+	{service.ErrCommentListFailed, http.StatusInternalServerError, "comment_list_failed"},
+
+	// Lists
+	{service.ErrInvalidListQuery, http.StatusBadRequest, util.CodeInvalidQuery},
+}
+
+// errorWriter writes the problems of service errors, embedded in every handler
+type errorWriter struct {
+	exposeErrors bool // Returns the text of internal errors to clients, in development
+}
+
+// writeError writes the problem matching a service error. Errors without a mapping are internal
+// errors, their text is only returned when exposeErrors is set.
+func (ew errorWriter) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	for _, e := range apiErrors {
+		if errors.Is(err, e.err) {
+			if e.status >= http.StatusInternalServerError {
+				slog.ErrorContext(r.Context(), "Request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+			}
+			problem := util.NewProblem(r, e.status, e.code, err.Error())
+			var fieldErrors util.ValidationErrors
+			if errors.As(err, &fieldErrors) {
+				problem.Errors = fieldErrors
+			}
+			util.ResponseWithProblem(w, problem)
+			return
+		}
+	}
+	util.ResponseWithInternalError(w, r, err, ew.exposeErrors)
+}
+
+// decodeRequest decodes and validates a JSON request body, writing a problem if it is invalid
+func decodeRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeBodyError(w, r, err, "Request body must be a valid JSON object")
+		return false
+	}
+	if err := util.Validate(req); err != nil {
+		var fieldErrors util.ValidationErrors
+		if !errors.As(err, &fieldErrors) {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeValidationFailed, err.Error())
+			return false
+		}
+		problem := util.NewProblem(r, http.StatusBadRequest, util.CodeValidationFailed, "Request body has invalid fields")
+		problem.Errors = fieldErrors
+		util.ResponseWithProblem(w, problem)
+		return false
+	}
+	return true
+}
+
+// writeBodyError writes the problem of a request body that could not be read or decoded
+func writeBodyError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		util.ResponseWithError(w, r, http.StatusRequestEntityTooLarge, util.CodeBodyTooLarge, fmt.Sprintf("Request body must not exceed %d bytes", tooLarge.Limit))
+		return
+	}
+	util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidBody, message)
+}
+
+// userClaims returns the claims set by the auth middleware, writing a problem if they are missing
+func userClaims(w http.ResponseWriter, r *http.Request) (*util.UserClaims, bool) {
+	claims, ok := r.Context().Value(middleware.UserClaimsKey).(*util.UserClaims)
+	if !ok {
+		util.ResponseWithError(w, r, http.StatusUnauthorized, util.CodeUnauthorized, "Authentication is required")
+	}
+	return claims, ok
+}
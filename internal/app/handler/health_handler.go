@@ -3,14 +3,57 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+
+	"go_api/internal/health"
+	"go_api/internal/util"
 )
 
+// HealthHandler reports whether the server accepts traffic. It fails while the server shuts down,
+// so load balancers stop routing requests to it.
 func (h *Handler) HealthHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
+		if !h.readiness.IsReady() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]string{
+				"message": "Server is shutting down",
+			})
+			return
+		}
+
 		response := map[string]string{
 			"message": "Server is okay",
 		}
 		json.NewEncoder(w).Encode(response)
 	}
 }
+
+// LivenessHandler reports that the process can answer requests. It does not check dependencies,
+// so an outage of the database or Redis does not get the server restarted.
+func (h *Handler) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		util.ResponseWithSuccess(w, http.StatusOK, "Server is alive", nil)
+	}
+}
+
+// ReadinessHandler reports whether the server and its dependencies can serve traffic,
+// with the status, latency and version of each dependency
+func (h *Handler) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.readiness.IsReady() {
+			util.ResponseWithSuccess(w, http.StatusServiceUnavailable, "Server is shutting down", nil)
+			return
+		}
+
+		report := h.health.Check(r.Context())
+		if !h.exposeErrors {
+			report = report.WithoutErrors()
+		}
+
+		if report.Status != health.StatusUp {
+			util.ResponseWithSuccess(w, http.StatusServiceUnavailable, "Server is not ready", report)
+			return
+		}
+		util.ResponseWithSuccess(w, http.StatusOK, "Server is ready", report)
+	}
+}
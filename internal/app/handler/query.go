@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_api/internal/app/dto"
+	"go_api/internal/app/model"
+	"go_api/internal/util"
+)
+
+// parsePageQuery reads the limit, cursor and sort query parameters.
+// A sort field prefixed with "-" sorts in descending order.
+func parsePageQuery(query url.Values, defaultSort string) (dto.PageQuery, error) {
+	page := dto.PageQuery{
+		Limit:  dto.DefaultPageLimit,
+		Cursor: query.Get("cursor"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > dto.MaxPageLimit {
+			return page, fmt.Errorf("limit must be a number between 1 and %d", dto.MaxPageLimit)
+		}
+		page.Limit = n
+	}
+
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = defaultSort
+	}
+	page.Sort, page.Desc = strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+
+	return page, nil
+}
+
+// parseTimeQuery reads an optional RFC 3339 timestamp query parameter
+func parseTimeQuery(query url.Values, key string) (*time.Time, error) {
+	value := query.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 timestamp", key)
+	}
+	return &t, nil
+}
+
+// parseCreatedRange reads the created_after and created_before query parameters
+func parseCreatedRange(query url.Values) (*time.Time, *time.Time, error) {
+	after, err := parseTimeQuery(query, "created_after")
+	if err != nil {
+		return nil, nil, err
+	}
+	before, err := parseTimeQuery(query, "created_before")
+	if err != nil {
+		return nil, nil, err
+	}
+	return after, before, nil
+}
+
+// parseBlogFilter reads the blog list filters from the query parameters
+func parseBlogFilter(query url.Values) (dto.BlogFilter, error) {
+	filter := dto.BlogFilter{
+		Status: query.Get("status"),
+		Title:  query.Get("title"),
+	}
+
+	switch model.BlogStatus(filter.Status) {
+	case "", model.BlogStatusDraft, model.BlogStatusScheduled, model.BlogStatusPublished, model.BlogStatusArchived:
+	default:
+		return filter, fmt.Errorf("status must be one of draft, scheduled, published or archived")
+	}
+
+	if author := query.Get("author"); author != "" {
+		id, err := strconv.ParseUint(author, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("author must be a user ID")
+		}
+		filter.AuthorID = uint(id)
+	}
+
+	// Tags can be repeated (tag=a&tag=b) or comma-separated (tag=a,b)
+	for _, tags := range query["tag"] {
+		filter.Tags = append(filter.Tags, strings.Split(tags, ",")...)
+	}
+	filter.Tags = util.NormalizeTags(filter.Tags)
+
+	switch query.Get("tag_match") {
+	case "", "any":
+	case "all":
+		filter.MatchAllTags = true
+	default:
+		return filter, fmt.Errorf("tag_match must be any or all")
+	}
+
+	var err error
+	filter.CreatedAfter, filter.CreatedBefore, err = parseCreatedRange(query)
+	return filter, err
+}
+
+// parseUserFilter reads the user list filters from the query parameters
+func parseUserFilter(query url.Values) (dto.UserFilter, error) {
+	filter := dto.UserFilter{
+		Role:     query.Get("role"),
+		Username: query.Get("username"),
+	}
+
+	var err error
+	filter.CreatedAfter, filter.CreatedBefore, err = parseCreatedRange(query)
+	return filter, err
+}
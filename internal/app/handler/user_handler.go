@@ -1,23 +1,25 @@
 package handler
 
 import (
-	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 
 	"go_api/internal/app/dto"
 	"go_api/internal/app/service"
-	"go_api/internal/middleware"
 	"go_api/internal/util"
 )
 
 type UserHandler struct {
+	errorWriter
 	service *service.UserService
 }
 
-func NewUserHandler(service *service.UserService) *UserHandler {
+// NewUserHandler creates the handler. exposeErrors returns the text of internal errors to clients.
+func NewUserHandler(service *service.UserService, exposeErrors bool) *UserHandler {
 	return &UserHandler{
-		service: service,
+		errorWriter: errorWriter{exposeErrors: exposeErrors},
+		service:     service,
 	}
 }
 
@@ -27,23 +29,15 @@ func (h *UserHandler) UserProfileHandler() http.HandlerFunc {
 		ctx := r.Context()
 
 		// Get claims from context
-		claims, ok := ctx.Value(middleware.UserClaimsKey).(*util.UserClaims)
+		claims, ok := userClaims(w, r)
 		if !ok {
-			util.ResponseWithError(w, http.StatusUnauthorized, "Unauthorized", "Unauthorized")
 			return
 		}
 
 		// Get user profile from user service
 		user, fromCache, err := h.service.GetUserProfile(ctx, claims.UserID)
 		if err != nil {
-			switch {
-			case errors.Is(err, service.ErrUserNotFound):
-				util.ResponseWithError(w, http.StatusNotFound, "User not found", err.Error())
-			case errors.Is(err, service.ErrCacheOperation):
-				util.ResponseWithError(w, http.StatusInternalServerError, "Failed to cache user", err.Error())
-			default:
-				util.ResponseWithError(w, http.StatusInternalServerError, "Internal server error", err.Error())
-			}
+			h.writeError(w, r, err)
 			return
 		}
 
@@ -59,29 +53,17 @@ func (h *UserHandler) UserProfileHandler() http.HandlerFunc {
 func (h *UserHandler) CreateUserHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		var req dto.CreateUserRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			util.ResponseWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
-			return
-		}
 
-		// Validate request
-		if err := util.Validate(req); err != nil {
-			util.ResponseWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		// Decode and validate request body
+		var req dto.CreateUserRequest
+		if !decodeRequest(w, r, &req) {
 			return
 		}
 
 		// Create user in user service
 		user, err := h.service.CreateUser(ctx, req)
 		if err != nil {
-			switch {
-			case errors.Is(err, service.ErrPasswordHashing):
-				util.ResponseWithError(w, http.StatusInternalServerError, "Failed to hash password", err.Error())
-			case errors.Is(err, service.ErrUserCreation):
-				util.ResponseWithError(w, http.StatusInternalServerError, "Failed to create user", err.Error())
-			default:
-				util.ResponseWithError(w, http.StatusInternalServerError, "Internal server error", err.Error())
-			}
+			h.writeError(w, r, err)
 			return
 		}
 
@@ -93,76 +75,78 @@ func (h *UserHandler) CreateUserHandler() http.HandlerFunc {
 func (h *UserHandler) LoginUserHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
+
+		// Decode and validate request body
 		var req dto.LoginUserRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			util.ResponseWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		if !decodeRequest(w, r, &req) {
 			return
 		}
 
-		// Validate request
-		if err := util.Validate(req); err != nil {
-			util.ResponseWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		// Login user in user service
+		tokens, err := h.service.LoginUser(ctx, req)
+		if err != nil {
+			h.writeError(w, r, err)
 			return
 		}
 
-		// Login user in user service
-		token, err := h.service.LoginUser(ctx, req)
+		util.ResponseWithSuccess(w, http.StatusOK, "Login successful", tokens)
+	}
+}
+
+// RefreshTokenHandler exchanges a refresh token for a new token pair
+func (h *UserHandler) RefreshTokenHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		// Decode and validate request body
+		var req dto.RefreshTokenRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+
+		// Rotate refresh token in user service
+		tokens, err := h.service.RefreshToken(ctx, req)
 		if err != nil {
-			switch {
-			case errors.Is(err, service.ErrUserNotFound):
-				util.ResponseWithError(w, http.StatusNotFound, "User not found", err.Error())
-			case errors.Is(err, service.ErrInvalidPassword):
-				util.ResponseWithError(w, http.StatusUnauthorized, "Invalid password", "")
-			case errors.Is(err, service.ErrTokenGeneration):
-				util.ResponseWithError(w, http.StatusInternalServerError, "Failed to generate token", err.Error())
-			default:
-				util.ResponseWithError(w, http.StatusInternalServerError, "Internal server error", err.Error())
+			if errors.Is(err, service.ErrUserNotFound) {
+				// The token belongs to a deleted user
+				err = service.ErrInvalidRefreshToken
 			}
+			h.writeError(w, r, err)
 			return
 		}
 
-		util.ResponseWithSuccess(w, http.StatusOK, "Login successful", map[string]string{
-			"token": token,
-		})
+		util.ResponseWithSuccess(w, http.StatusOK, "Token refreshed successfully", tokens)
 	}
 }
 
-// LogoutUserHandler logs out a user
+// LogoutUserHandler logs out the session of the presented tokens
 func (h *UserHandler) LogoutUserHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
 		// Get claims from context
-		claims, ok := ctx.Value(middleware.UserClaimsKey).(*util.UserClaims)
+		claims, ok := userClaims(w, r)
 		if !ok {
-			util.ResponseWithError(w, http.StatusBadRequest, "Missing claims", "Missing claims")
 			return
 		}
 
 		// Extract token from Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			util.ResponseWithError(w, http.StatusUnauthorized, "Missing Authorization header", "Missing Authorization header")
+		token, err := util.ExtractTokenFromHeader(r.Header.Get("Authorization"))
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusUnauthorized, util.CodeUnauthorized, "Authorization header must hold a bearer token")
 			return
 		}
 
-		token, err := util.ExtractTokenFromHeader(authHeader)
-		if err != nil {
-			util.ResponseWithError(w, http.StatusUnauthorized, "Missing authorization token", err.Error())
+		// Decode and validate request body
+		var req dto.LogoutRequest
+		if !decodeRequest(w, r, &req) {
 			return
 		}
 
 		// Logout user in user service
-		err = h.service.LogoutUser(ctx, claims.UserID, token, claims.ExpiresAt)
+		err = h.service.LogoutUser(ctx, claims.UserID, token, claims.ExpiresAt, req)
 		if err != nil {
-			switch {
-			case errors.Is(err, service.ErrTokenBlacklist):
-				util.ResponseWithError(w, http.StatusInternalServerError, "Failed to blacklist token", err.Error())
-			case errors.Is(err, service.ErrSessionCleanup):
-				util.ResponseWithError(w, http.StatusInternalServerError, "Failed to clean user session", err.Error())
-			default:
-				util.ResponseWithError(w, http.StatusInternalServerError, "Internal server error", err.Error())
-			}
+			h.writeError(w, r, err)
 			return
 		}
 
@@ -170,17 +154,110 @@ func (h *UserHandler) LogoutUserHandler() http.HandlerFunc {
 	}
 }
 
-// ListAllUsersHandler lists all users (with optional pagination)
+// LogoutAllSessionsHandler logs out every session of the user
+func (h *UserHandler) LogoutAllSessionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		claims, ok := userClaims(w, r)
+		if !ok {
+			return
+		}
+
+		token, err := util.ExtractTokenFromHeader(r.Header.Get("Authorization"))
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusUnauthorized, util.CodeUnauthorized, "Authorization header must hold a bearer token")
+			return
+		}
+
+		if err := h.service.LogoutAllSessions(ctx, claims.UserID, token, claims.ExpiresAt); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		util.ResponseWithSuccess(w, http.StatusOK, "Logged out of every session", nil)
+	}
+}
+
+// ListAllUsersHandler lists users with cursor pagination, filtering and sorting
 func (h *UserHandler) ListAllUsersHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		// List all users from user service
-		users, err := h.service.ListAllUsers(ctx)
+		// Parse pagination and filters
+		query := r.URL.Query()
+		page, err := parsePageQuery(query, "-created_at")
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidQuery, err.Error())
+			return
+		}
+		filter, err := parseUserFilter(query)
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidQuery, err.Error())
+			return
+		}
+
+		// List users from user service
+		users, nextCursor, err := h.service.ListAllUsers(ctx, filter, page)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		util.ResponseWithPage(w, http.StatusOK, "List of users", users, nextCursor)
+	}
+}
+
+// UpdateUserRoleHandler changes the role of a user
+func (h *UserHandler) UpdateUserRoleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "User ID must be a number")
+			return
+		}
+
+		// Decode and validate request body
+		var req dto.UpdateUserRoleRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+
+		// Update role in user service
+		user, err := h.service.UpdateUserRole(ctx, uint(id), req)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		util.ResponseWithSuccess(w, http.StatusOK, "User role updated successfully", user)
+	}
+}
+
+// UpdateUserPermissionsHandler replaces the extra permissions of a user
+func (h *UserHandler) UpdateUserPermissionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+		if err != nil {
+			util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidRequest, "User ID must be a number")
+			return
+		}
+
+		// Decode and validate request body
+		var req dto.UpdateUserPermissionsRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+
+		user, err := h.service.UpdateUserPermissions(ctx, uint(id), req)
 		if err != nil {
-			util.ResponseWithError(w, http.StatusInternalServerError, "Failed to list users", err.Error())
+			h.writeError(w, r, err)
 			return
 		}
-		util.ResponseWithSuccess(w, http.StatusOK, "List of all users", users)
+
+		util.ResponseWithSuccess(w, http.StatusOK, "User permissions updated successfully", user)
 	}
 }
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"strconv"
+
+	"go_api/internal/health"
+	"go_api/internal/storage"
+)
+
+// healthChecker creates the readiness checks of the database, Redis, and the optional migration and disk checks.
+// The read connections are reported without failing readiness, reads skip those that are down and fall back to
+// the primary.
+func (a *App) healthChecker() *health.Checker {
+	cfg := a.cfg.Health
+
+	checker := health.NewChecker(cfg.Timeout, cfg.CacheTTL)
+	checker.Register("database", health.Database(a.db.Primary))
+	for i, replica := range a.db.Replicas {
+		name := "database_pooler"
+		if i > 0 {
+			name = "database_replica_" + strconv.Itoa(i)
+		}
+		check := health.Database(replica)
+		checker.RegisterOptional(name, func(ctx context.Context) (health.Component, error) {
+			component, err := check(ctx)
+			a.db.SetReplicaUp(i, err == nil)
+			return component, err
+		})
+	}
+	checker.Register("redis", health.Redis(a.redis))
+	if cfg.CheckMigrations {
+		checker.Register("migrations", func(ctx context.Context) (health.Component, error) {
+			version, err := storage.CheckSchema(ctx, a.db.Primary)
+			return health.Component{Version: strconv.FormatInt(version, 10)}, err
+		})
+	}
+	if cfg.DiskPath != "" {
+		checker.Register("disk", health.Disk(cfg.DiskPath, cfg.DiskMinFree))
+	}
+	return checker
+}
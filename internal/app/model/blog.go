@@ -2,12 +2,44 @@ package model
 
 import "time"
 
+type BlogStatus string
+
+const (
+	BlogStatusDraft     BlogStatus = "draft"
+	BlogStatusScheduled BlogStatus = "scheduled"
+	BlogStatusPublished BlogStatus = "published"
+	BlogStatusArchived  BlogStatus = "archived"
+)
+
 type Blog struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Title     string    `gorm:"not null" json:"title"`
-	Content   string    `gorm:"type:text" json:"content"`
-	UserID    uint      `gorm:"index;not null" json:"user_id"`
-	User      User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Title       string     `gorm:"not null" json:"title"`
+	Content     string     `gorm:"type:text" json:"content"`
+	UserID      uint       `gorm:"index;not null" json:"user_id"`
+	User        User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Status      BlogStatus `gorm:"type:varchar(20);not null;default:published;index" json:"status"`
+	PublishedAt *time.Time `gorm:"index" json:"published_at"`         // Publication time, or the planned one while scheduled
+	Version     uint       `gorm:"not null;default:1" json:"version"` // Incremented on every update, used as the ETag
+	Tags        []Tag      `gorm:"many2many:blog_tags;constraint:OnDelete:CASCADE" json:"tags"`
+	CreatedAt   time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// IsPublic reports whether the blog is visible to users other than its owner
+func (b *Blog) IsPublic() bool {
+	return b.Status == BlogStatusPublished
+}
+
+// VisibleTo reports whether a viewer can see the blog. viewerID is 0 for anonymous viewers
+// and viewAll is set for viewers who can see every blog.
+func (b *Blog) VisibleTo(viewerID uint, viewAll bool) bool {
+	return b.IsPublic() || viewAll || (viewerID != 0 && b.UserID == viewerID)
+}
+
+// BlogSearchResult is a blog matched by a full-text search
+type BlogSearchResult struct {
+	Blog
+	Rank           float32 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"` // Matching fragments of the content, with matches wrapped in <mark>
 }
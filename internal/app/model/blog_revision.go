@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// BlogRevision is a previous version of a blog, stored whenever the blog is changed
+type BlogRevision struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	BlogID      uint       `gorm:"not null;uniqueIndex:idx_blog_revisions_blog_version" json:"blog_id"`
+	Blog        Blog       `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Version     uint       `gorm:"not null;uniqueIndex:idx_blog_revisions_blog_version" json:"version"` // Blog version this revision captured
+	Title       string     `gorm:"not null" json:"title"`
+	Content     string     `gorm:"type:text" json:"content"`
+	Status      BlogStatus `gorm:"type:varchar(20);not null" json:"status"`
+	PublishedAt *time.Time `json:"published_at"`
+	ReplacedBy  *uint      `json:"replaced_by"` // User whose change replaced this version, nil for the scheduler
+	CreatedAt   time.Time  `json:"created_at"`  // When this version was replaced
+}
+
+// NewBlogRevision captures the current state of a blog as a revision
+func NewBlogRevision(blog *Blog, replacedBy *uint) *BlogRevision {
+	return &BlogRevision{
+		BlogID:      blog.ID,
+		Version:     blog.Version,
+		Title:       blog.Title,
+		Content:     blog.Content,
+		Status:      blog.Status,
+		PublishedAt: blog.PublishedAt,
+		ReplacedBy:  replacedBy,
+	}
+}
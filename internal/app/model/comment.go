@@ -0,0 +1,106 @@
+package model
+
+import "time"
+
+// MaxCommentDepth is how deeply replies can be nested, top-level comments having depth 0
+const MaxCommentDepth = 8
+
+// MaxThreadReplies is how many replies are listed with a top-level comment. The rest of the thread
+// is paged separately.
+const MaxThreadReplies = 50
+
+type Comment struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	BlogID    uint       `gorm:"index;not null" json:"blog_id"`
+	Blog      Blog       `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	ParentID  *uint      `gorm:"index" json:"parent_id"`
+	Parent    *Comment   `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	RootID    *uint      `gorm:"index" json:"root_id"` // Top-level comment of the thread, nil for top-level comments
+	Depth     uint       `gorm:"not null;default:0" json:"depth"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	Content   string     `gorm:"type:text;not null" json:"content"`
+	EditedAt  *time.Time `json:"edited_at"`
+	DeletedAt *time.Time `json:"deleted_at"` // Deleted comments stay as placeholders so their replies keep their place
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// IsDeleted reports whether the comment has been deleted and is only a placeholder
+func (c *Comment) IsDeleted() bool {
+	return c.DeletedAt != nil
+}
+
+// CommentNode is a comment together with its nested replies
+type CommentNode struct {
+	Comment
+	Replies     []*CommentNode `json:"replies"`
+	MoreReplies bool           `json:"more_replies,omitempty"` // Set on top-level comments whose thread has replies left out
+}
+
+// BuildCommentTree nests replies under their parents. Replies must be ordered oldest first
+// and belong to the threads of roots, truncated holds the roots of the threads whose replies
+// were limited, see LimitThreadReplies. Deleted comments without remaining replies are left out,
+// except in truncated threads, where their replies may be among the ones left out.
+func BuildCommentTree(roots []Comment, replies []Comment, truncated map[uint]bool) []*CommentNode {
+	nodes := make(map[uint]*CommentNode, len(roots)+len(replies))
+
+	tree := make([]*CommentNode, 0, len(roots))
+	for _, root := range roots {
+		node := &CommentNode{Comment: root, Replies: []*CommentNode{}, MoreReplies: truncated[root.ID]}
+		nodes[root.ID] = node
+		tree = append(tree, node)
+	}
+
+	for _, reply := range replies {
+		if reply.ParentID == nil {
+			continue
+		}
+		parent, ok := nodes[*reply.ParentID]
+		if !ok {
+			continue
+		}
+		node := &CommentNode{Comment: reply, Replies: []*CommentNode{}}
+		nodes[reply.ID] = node
+		parent.Replies = append(parent.Replies, node)
+	}
+
+	return pruneDeleted(tree, truncated)
+}
+
+// LimitThreadReplies keeps the first max replies of each thread and returns the roots of the
+// threads that had more. Replies must be ordered oldest first.
+func LimitThreadReplies(replies []Comment, max int) ([]Comment, map[uint]bool) {
+	counts := map[uint]int{}
+	truncated := map[uint]bool{}
+	kept := make([]Comment, 0, len(replies))
+	for _, reply := range replies {
+		if reply.RootID == nil {
+			continue
+		}
+		counts[*reply.RootID]++
+		if counts[*reply.RootID] > max {
+			truncated[*reply.RootID] = true
+			continue
+		}
+		kept = append(kept, reply)
+	}
+	return kept, truncated
+}
+
+// pruneDeleted removes deleted comments that no longer have any replies, unless their thread
+// was truncated and their replies may have been left out
+func pruneDeleted(nodes []*CommentNode, truncated map[uint]bool) []*CommentNode {
+	kept := nodes[:0]
+	for _, node := range nodes {
+		node.Replies = pruneDeleted(node.Replies, truncated)
+		rootID := node.ID
+		if node.RootID != nil {
+			rootID = *node.RootID
+		}
+		if node.IsDeleted() && len(node.Replies) == 0 && !truncated[rootID] {
+			continue
+		}
+		kept = append(kept, node)
+	}
+	return kept
+}
@@ -0,0 +1,69 @@
+package model
+
+import "slices"
+
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
+type Permission string
+
+const (
+	PermReadUsers   Permission = "users:read"
+	PermManageUsers Permission = "users:manage"
+	PermWriteBlogs  Permission = "blogs:write"
+	PermManageBlogs Permission = "blogs:manage" // Edit or delete any blog, not only owned ones
+
+	PermWriteComments  Permission = "comments:write"
+	PermManageComments Permission = "comments:manage" // Delete any comment, not only owned ones
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleUser: {
+		PermWriteBlogs,
+		PermWriteComments,
+	},
+	RoleAdmin: {
+		PermReadUsers,
+		PermManageUsers,
+		PermWriteBlogs,
+		PermManageBlogs,
+		PermWriteComments,
+		PermManageComments,
+	},
+}
+
+// IsValid reports whether the role is a known role
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// IsValid reports whether a role grants the permission
+func (p Permission) IsValid() bool {
+	for _, permissions := range rolePermissions {
+		if slices.Contains(permissions, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns the permissions granted by the role
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+// EffectivePermissions returns the user's role permissions combined with any extra grants
+func (u *User) EffectivePermissions() []Permission {
+	permissions := slices.Clone(u.Role.Permissions())
+	for _, permission := range u.Permissions {
+		if !slices.Contains(permissions, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions
+}
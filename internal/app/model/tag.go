@@ -0,0 +1,32 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Tag struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"type:varchar(50);uniqueIndex;not null"` // Normalized, see util.NormalizeTag
+	CreatedAt time.Time
+}
+
+// MarshalJSON encodes a tag as its name
+func (t Tag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Name)
+}
+
+// TagCount is a tag with the number of published blogs using it
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// TagNames returns the names of the tags
+func TagNames(tags []Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
@@ -3,10 +3,12 @@ package model
 import "time"
 
 type User struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Username  string    `gorm:"uniqueIndex;not null" json:"username"`
-	Email     string    `gorm:"uniqueIndex;not null" json:"email"`
-	Password  string    `gorm:"not null" json:"-"` // Exclude password from JSON
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Username    string       `gorm:"uniqueIndex;not null" json:"username"`
+	Email       string       `gorm:"uniqueIndex;not null" json:"email"`
+	Password    string       `gorm:"not null" json:"-"` // Exclude password from JSON
+	Role        Role         `gorm:"type:varchar(20);not null;default:user" json:"role"`
+	Permissions []Permission `gorm:"serializer:json" json:"permissions,omitempty"` // Extra grants on top of the role
+	CreatedAt   time.Time    `gorm:"index" json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
 }
@@ -0,0 +1,193 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_api/internal/app/model"
+	"go_api/internal/util"
+)
+
+// schemaOverrides describes types whose JSON encoding differs from their Go structure
+var schemaOverrides = map[reflect.Type]Schema{
+	reflect.TypeOf(time.Time{}): {Type: "string", Format: "date-time"},
+	reflect.TypeOf(model.Tag{}): {Type: "string", Description: "Normalized tag name"},
+}
+
+// schemaGenerator builds schemas from Go types using their json and validate tags.
+// Named structs are stored once as components and referenced.
+type schemaGenerator struct {
+	schemas map[string]*Schema
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{schemas: make(map[string]*Schema)}
+}
+
+// schemaOf returns the schema of the type of v
+func (g *schemaGenerator) schemaOf(v any) *Schema {
+	return g.schema(reflect.TypeOf(v))
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) *Schema {
+	if override, ok := schemaOverrides[t]; ok {
+		return &override
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			g.schemas[t.Name()] = nil // Reserve the name first so recursive types reference it
+			g.schemas[t.Name()] = g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		// Interfaces can hold any value
+		return &Schema{}
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t)
+	return s
+}
+
+// addFields adds the JSON fields of a struct to s, including the fields of embedded structs
+func (g *schemaGenerator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			g.addFields(s, fieldType)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fs := g.schema(field.Type)
+		if field.Type.Kind() == reflect.Pointer && fs.Ref == "" {
+			fs.Type = []any{fs.Type, "null"}
+		}
+		if applyValidation(fs, field.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// applyValidation documents the validate tag rules on s and reports whether the field is required.
+// Rules after "dive" apply to the items of a slice.
+func applyValidation(s *Schema, tag string) bool {
+	required := false
+	target := s
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = required || target == s
+		case "dive":
+			if target.Items == nil {
+				return required
+			}
+			target = target.Items
+		case "min", "max":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			setBound(target, name == "min", n)
+		case "email":
+			target.Format = "email"
+		case "username":
+			target.Pattern = util.UsernamePattern
+		case "password":
+			target.Description = "Must contain an uppercase letter, a lowercase letter and a number"
+		case "oneof":
+			target.Enum = strings.Fields(param)
+		case "required_if":
+			field, value, _ := strings.Cut(param, " ")
+			target.Description = "Required when " + strings.ToLower(field) + " is " + value
+		}
+	}
+	return required
+}
+
+// setBound sets the lower or upper bound matching the schema type: length, item count or value
+func setBound(s *Schema, lower bool, n int) {
+	switch schemaType(s) {
+	case "string":
+		if lower {
+			s.MinLength = &n
+		} else {
+			s.MaxLength = &n
+		}
+	case "array":
+		if lower {
+			s.MinItems = &n
+		} else {
+			s.MaxItems = &n
+		}
+	case "integer", "number":
+		if lower {
+			s.Minimum = float(n)
+		} else {
+			s.Maximum = float(n)
+		}
+	}
+}
+
+// schemaType returns the type of a schema, ignoring "null" for nullable values
+func schemaType(s *Schema) string {
+	switch t := s.Type.(type) {
+	case string:
+		return t
+	case []any:
+		if len(t) > 0 {
+			name, _ := t[0].(string)
+			return name
+		}
+	}
+	return ""
+}
+
+func float(n int) *float64 {
+	f := float64(n)
+	return &f
+}
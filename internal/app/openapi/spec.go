@@ -0,0 +1,429 @@
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go_api/internal/app/dto"
+	"go_api/internal/app/model"
+	"go_api/internal/health"
+	"go_api/internal/util"
+)
+
+type authMode int
+
+const (
+	authNone authMode = iota
+	authOptional
+	authRequired
+)
+
+type ifMatchMode int
+
+const (
+	ifMatchNone ifMatchMode = iota
+	ifMatchOptional
+	ifMatchRequired
+)
+
+const bearerScheme = "bearerAuth"
+
+// endpoint describes one registered route. Keep this list in sync with the route package,
+// the OpenAPI test fails when a route is missing.
+type endpoint struct {
+	pattern     string // http.ServeMux pattern the route is registered with
+	id          string
+	summary     string
+	description string
+	tag         string
+	auth        authMode
+	permission  model.Permission // Permission required on top of authentication
+	query       []Parameter
+	ifMatch     ifMatchMode
+	request     any // Request body, nil for none
+	requestType string
+	optionalReq bool
+	status      int
+	data        any // Response data, nil for none
+	etag        bool
+	unavailable bool // Also responds 503 with the success body, when a dependency is down
+	errors      []int
+}
+
+var endpoints = []endpoint{
+	// Health
+	{pattern: "/health", id: "health", summary: "Check that the server is running and not shutting down", tag: "Health",
+		status: http.StatusOK},
+	{pattern: "GET /health/live", id: "getLiveness", summary: "Check that the process is alive, without checking dependencies", tag: "Health",
+		status: http.StatusOK},
+	{pattern: "GET /health/ready", id: "getReadiness", summary: "Check that the server and its dependencies can serve traffic", tag: "Health",
+		description: "Reports the status, latency and version of the database, Redis and the optional migration and disk checks. Results are cached for HEALTH_CACHE_TTL.",
+		status:      http.StatusOK, data: health.Report{}, unavailable: true},
+
+	// Docs
+	{pattern: "GET /openapi.json", id: "getOpenAPI", summary: "Get this OpenAPI document", tag: "Docs",
+		status: http.StatusOK},
+	{pattern: "GET /docs", id: "getDocs", summary: "Interactive API documentation", tag: "Docs",
+		status: http.StatusOK},
+	{pattern: "GET /docs/{file}", id: "getDocsAsset", summary: "Get a Swagger UI file of the docs page", tag: "Docs",
+		description: "Serves swagger-ui.css and swagger-ui-bundle.js, which are embedded in the binary.",
+		status:      http.StatusOK, errors: []int{http.StatusNotFound}},
+
+	// Metrics
+	{pattern: "GET /metrics", id: "getMetrics", summary: "Prometheus metrics in the text exposition format", tag: "Metrics",
+		status: http.StatusOK},
+
+	// Users
+	{pattern: "POST /users/register", id: "registerUser", summary: "Register a new user", tag: "Users",
+		description: "Limited by the strict rate limit policy per IP address by default.",
+		request:     dto.CreateUserRequest{}, status: http.StatusCreated, data: model.User{},
+		errors: []int{http.StatusBadRequest}},
+	{pattern: "POST /users/login", id: "loginUser", summary: "Log in and get an access and a refresh token", tag: "Users",
+		description: "Limited by the strict rate limit policy per IP address by default.",
+		request:     dto.LoginUserRequest{}, status: http.StatusOK, data: dto.TokenResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound}},
+	{pattern: "POST /users/refresh", id: "refreshToken", summary: "Rotate a refresh token and get new tokens", tag: "Users",
+		description: "Replaying a refresh token that was already used revokes every token issued from the same login. " +
+			"Limited by the strict rate limit policy per IP address by default.",
+		request: dto.RefreshTokenRequest{}, status: http.StatusOK, data: dto.TokenResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusUnauthorized}},
+	{pattern: "GET /users/profile", id: "getProfile", summary: "Get the current user's profile", tag: "Users",
+		auth: authRequired, status: http.StatusOK, data: model.User{},
+		errors: []int{http.StatusNotFound}},
+	{pattern: "POST /users/logout", id: "logoutUser", summary: "Log out and revoke the current tokens", tag: "Users",
+		description: "Revokes the access token and the refresh tokens issued from the same login as the presented one. " +
+			"The other sessions of the user stay logged in.",
+		auth: authRequired, request: dto.LogoutRequest{}, status: http.StatusOK,
+		errors: []int{http.StatusBadRequest}},
+	{pattern: "POST /users/logout-all", id: "logoutAllSessions", summary: "Log out of every session", tag: "Users",
+		description: "Revokes the access token and every refresh token of the user. " +
+			"Access tokens of the other sessions stay valid until they expire.",
+		auth: authRequired, status: http.StatusOK},
+	{pattern: "GET /users/", id: "listUsers", summary: "List users", tag: "Users",
+		auth: authRequired, permission: model.PermReadUsers,
+		query:  concat(pageParams("-created_at", "id", "created_at", "username"), userFilterParams()),
+		status: http.StatusOK, data: []model.User{},
+		errors: []int{http.StatusBadRequest}},
+	{pattern: "PATCH /users/{id}/role", id: "updateUserRole", summary: "Change a user's role", tag: "Users",
+		auth: authRequired, permission: model.PermManageUsers,
+		request: dto.UpdateUserRoleRequest{}, status: http.StatusOK, data: model.User{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{pattern: "PUT /users/{id}/permissions", id: "updateUserPermissions", summary: "Replace the extra permissions of a user", tag: "Users",
+		auth: authRequired, permission: model.PermManageUsers,
+		request: dto.UpdateUserPermissionsRequest{}, status: http.StatusOK, data: model.User{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+	// Blogs
+	{pattern: "POST /blogs/", id: "createBlog", summary: "Create a blog", tag: "Blogs",
+		auth: authRequired, permission: model.PermWriteBlogs,
+		request: dto.CreateBlogRequest{}, status: http.StatusCreated, data: model.Blog{},
+		errors: []int{http.StatusBadRequest}},
+	{pattern: "GET /blogs/", id: "listBlogs", summary: "List blogs", tag: "Blogs",
+		auth:   authOptional,
+		query:  concat(pageParams("-created_at", blogSorts...), blogFilterParams()),
+		status: http.StatusOK, data: []model.Blog{},
+		errors: []int{http.StatusBadRequest}},
+	{pattern: "GET /blogs/search", id: "searchBlogs", summary: "Full-text search over blogs", tag: "Blogs",
+		auth: authOptional,
+		query: concat(
+			[]Parameter{{Name: "q", In: "query", Required: true, Schema: &Schema{Type: "string"},
+				Description: `Words are all required, quoted text matches a phrase and a trailing "*" matches a prefix`}},
+			pageParams("-rank", append([]string{"rank"}, blogSorts...)...),
+			blogFilterParams(),
+		),
+		status: http.StatusOK, data: []model.BlogSearchResult{},
+		errors: []int{http.StatusBadRequest}},
+	{pattern: "GET /blogs/{id}", id: "getBlog", summary: "Get a blog", tag: "Blogs",
+		auth: authOptional, status: http.StatusOK, data: model.Blog{}, etag: true,
+		errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{pattern: "PUT /blogs/{id}", id: "updateBlog", summary: "Replace a blog's title, content and tags", tag: "Blogs",
+		auth: authRequired, permission: model.PermWriteBlogs, ifMatch: ifMatchRequired,
+		request: dto.UpdateBlogRequest{}, status: http.StatusOK, data: model.Blog{}, etag: true,
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusPreconditionFailed, http.StatusPreconditionRequired}},
+	{pattern: "PATCH /blogs/{id}", id: "patchBlog", summary: "Partially update a blog", tag: "Blogs",
+		description: "The body is a JSON Merge Patch (RFC 7396) of the update request.",
+		auth:        authRequired, permission: model.PermWriteBlogs, ifMatch: ifMatchRequired,
+		request: dto.UpdateBlogRequest{}, requestType: "application/merge-patch+json",
+		status: http.StatusOK, data: model.Blog{}, etag: true,
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusPreconditionFailed, http.StatusPreconditionRequired, http.StatusUnsupportedMediaType}},
+	{pattern: "DELETE /blogs/{id}", id: "deleteBlog", summary: "Delete a blog", tag: "Blogs",
+		auth: authRequired, permission: model.PermWriteBlogs, status: http.StatusOK,
+		errors: []int{http.StatusBadRequest}},
+	{pattern: "POST /blogs/{id}/publish", id: "publishBlog", summary: "Publish a blog, or schedule it", tag: "Blogs",
+		auth: authRequired, permission: model.PermWriteBlogs, ifMatch: ifMatchOptional,
+		request: dto.PublishBlogRequest{}, optionalReq: true, status: http.StatusOK, data: model.Blog{}, etag: true,
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusPreconditionFailed}},
+	{pattern: "POST /blogs/{id}/unpublish", id: "unpublishBlog", summary: "Turn a blog back into a draft", tag: "Blogs",
+		auth: authRequired, permission: model.PermWriteBlogs, ifMatch: ifMatchOptional,
+		status: http.StatusOK, data: model.Blog{}, etag: true,
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusPreconditionFailed}},
+	{pattern: "POST /blogs/{id}/archive", id: "archiveBlog", summary: "Archive a blog", tag: "Blogs",
+		auth: authRequired, permission: model.PermWriteBlogs, ifMatch: ifMatchOptional,
+		status: http.StatusOK, data: model.Blog{}, etag: true,
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusPreconditionFailed}},
+
+	// Revisions
+	{pattern: "GET /blogs/{id}/revisions", id: "listRevisions", summary: "List previous versions of a blog", tag: "Revisions",
+		auth: authRequired, query: pageParams("-version", "version"),
+		status: http.StatusOK, data: []model.BlogRevision{},
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound}},
+	{pattern: "GET /blogs/{id}/revisions/{rev}", id: "getRevision", summary: "Get a previous version of a blog", tag: "Revisions",
+		auth: authRequired, status: http.StatusOK, data: model.BlogRevision{},
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound}},
+	{pattern: "GET /blogs/{id}/revisions/{rev}/diff", id: "diffRevision", summary: "Line diff from a previous version to the current content", tag: "Revisions",
+		auth: authRequired, status: http.StatusOK, data: dto.BlogRevisionDiff{},
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusUnprocessableEntity}},
+	{pattern: "POST /blogs/{id}/revisions/{rev}/restore", id: "restoreRevision", summary: "Restore a previous version of a blog", tag: "Revisions",
+		auth: authRequired, permission: model.PermWriteBlogs, ifMatch: ifMatchOptional,
+		status: http.StatusOK, data: model.Blog{}, etag: true,
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusPreconditionFailed}},
+
+	// Comments
+	{pattern: "GET /blogs/{id}/comments", id: "listComments", summary: "List a blog's comment threads", tag: "Comments",
+		description: "Paginated by top-level comment, each returned with the first " + strconv.Itoa(model.MaxThreadReplies) + " replies of its thread nested. " +
+			"more_replies is set on threads with more, whose replies are paged by listReplies.",
+		auth: authOptional, query: pageParams("created_at", "id", "created_at"),
+		status: http.StatusOK, data: []*model.CommentNode{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{pattern: "GET /blogs/{id}/comments/{commentId}/replies", id: "listReplies", summary: "List the replies in a comment thread", tag: "Comments",
+		description: "Every reply in the thread of a top-level comment, unnested. parent_id tells where each reply belongs.",
+		auth:        authOptional, query: pageParams("created_at", "id", "created_at"),
+		status: http.StatusOK, data: []model.Comment{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{pattern: "POST /blogs/{id}/comments", id: "createComment", summary: "Comment on a blog or reply to a comment", tag: "Comments",
+		auth: authRequired, permission: model.PermWriteComments,
+		request: dto.CreateCommentRequest{}, status: http.StatusCreated, data: model.Comment{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{pattern: "PUT /blogs/{id}/comments/{commentId}", id: "updateComment", summary: "Edit a comment", tag: "Comments",
+		auth: authRequired, permission: model.PermWriteComments,
+		request: dto.UpdateCommentRequest{}, status: http.StatusOK, data: model.Comment{},
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound}},
+	{pattern: "DELETE /blogs/{id}/comments/{commentId}", id: "deleteComment", summary: "Delete a comment", tag: "Comments",
+		auth: authRequired, permission: model.PermWriteComments, status: http.StatusOK,
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound}},
+
+	// Tags
+	{pattern: "GET /tags", id: "listTags", summary: "List tags of published blogs with their number of blogs", tag: "Tags",
+		query: []Parameter{{Name: "limit", In: "query", Description: "Number of tags, most used first",
+			Schema: &Schema{Type: "integer", Minimum: float(1), Maximum: float(dto.MaxPageLimit), Default: dto.MaxPageLimit}}},
+		status: http.StatusOK, data: []model.TagCount{},
+		errors: []int{http.StatusBadRequest}},
+	{pattern: "GET /tags/{tag}/blogs", id: "listBlogsByTag", summary: "List blogs with a tag", tag: "Tags",
+		auth:   authOptional,
+		query:  concat(pageParams("-created_at", blogSorts...), without(blogFilterParams(), "tag", "tag_match")),
+		status: http.StatusOK, data: []model.Blog{},
+		errors: []int{http.StatusBadRequest}},
+}
+
+var blogSorts = []string{"id", "created_at", "updated_at", "title"}
+
+// Build returns the OpenAPI document of every endpoint
+func Build() *Document {
+	g := newSchemaGenerator()
+	doc := &Document{
+		OpenAPI: "3.1.0",
+		Info: Info{
+			Title:       "Go API",
+			Version:     "1.0.0",
+			Description: "User and blog management API",
+		},
+		Paths: make(map[string]PathItem),
+		Components: Components{
+			SecuritySchemes: map[string]*SecurityScheme{
+				bearerScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	for _, e := range endpoints {
+		method, path := SplitPattern(e.pattern)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(PathItem)
+		}
+		doc.Paths[path][strings.ToLower(method)] = e.operation(g, path)
+	}
+
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}.]+)(?:\.\.\.)?\}`)
+
+func (e endpoint) operation(g *schemaGenerator, path string) *Operation {
+	op := &Operation{
+		OperationID: e.id,
+		Summary:     e.summary,
+		Description: e.description,
+		Tags:        []string{e.tag},
+		Responses:   make(map[string]*Response),
+	}
+
+	switch e.auth {
+	case authOptional:
+		op.Security = []SecurityRequirement{{}, {bearerScheme: {}}}
+	case authRequired:
+		op.Security = []SecurityRequirement{{bearerScheme: {}}}
+	}
+	if e.permission != "" {
+		op.Description = strings.TrimSpace(op.Description + " Requires the `" + string(e.permission) + "` permission.")
+	}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		schema := &Schema{Type: "integer", Minimum: float(1)}
+		if match[1] == "tag" {
+			schema = &Schema{Type: "string"}
+		}
+		op.Parameters = append(op.Parameters, Parameter{Name: match[1], In: "path", Required: true, Schema: schema})
+	}
+	if e.ifMatch != ifMatchNone {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        "If-Match",
+			In:          "header",
+			Required:    e.ifMatch == ifMatchRequired,
+			Description: `Expected blog versions as quoted ETags, like "3" or "3", "4", or "*" to skip the check. Weak ETags never match`,
+			Schema:      &Schema{Type: "string"},
+		})
+	}
+	op.Parameters = append(op.Parameters, e.query...)
+
+	if e.request != nil {
+		contentType := e.requestType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		op.RequestBody = &RequestBody{
+			Required: !e.optionalReq,
+			Content:  map[string]MediaType{contentType: {Schema: g.schemaOf(e.request)}},
+		}
+	}
+
+	success := &Response{
+		Description: http.StatusText(e.status),
+		Content:     map[string]MediaType{"application/json": {Schema: g.envelope(e.data)}},
+	}
+	if e.etag {
+		success.Headers = map[string]*Header{"ETag": etagHeader()}
+	}
+	op.Responses[strconv.Itoa(e.status)] = success
+	if e.unavailable {
+		op.Responses[strconv.Itoa(http.StatusServiceUnavailable)] = &Response{
+			Description: "A dependency is down or the server is shutting down",
+			Content:     success.Content,
+		}
+	}
+
+	// Every route is rate limited
+	errors := append([]int{http.StatusTooManyRequests, http.StatusInternalServerError}, e.errors...)
+	if e.auth == authRequired {
+		errors = append(errors, http.StatusUnauthorized)
+	}
+	if e.permission != "" {
+		errors = append(errors, http.StatusForbidden)
+	}
+	if e.request != nil {
+		errors = append(errors, http.StatusRequestEntityTooLarge)
+	}
+	for _, status := range errors {
+		response := &Response{
+			Description: http.StatusText(status),
+			Content:     map[string]MediaType{util.ProblemContentType: {Schema: g.schemaOf(util.Problem{})}},
+		}
+		if status == http.StatusPreconditionFailed {
+			response.Description = "The blog was modified since the given version"
+			response.Headers = map[string]*Header{"ETag": etagHeader()}
+		}
+		if status == http.StatusTooManyRequests {
+			response.Headers = map[string]*Header{"Retry-After": {
+				Description: "Seconds until a request is allowed again",
+				Schema:      &Schema{Type: "integer"},
+			}}
+		}
+		op.Responses[strconv.Itoa(status)] = response
+	}
+	return op
+}
+
+// envelope returns the schema of a util.SuccessResponse holding data
+func (g *schemaGenerator) envelope(data any) *Schema {
+	envelope := g.schemaOf(util.SuccessResponse{})
+	if data == nil {
+		return envelope
+	}
+	return &Schema{AllOf: []*Schema{
+		envelope,
+		{Type: "object", Properties: map[string]*Schema{"data": g.schemaOf(data)}},
+	}}
+}
+
+func etagHeader() *Header {
+	return &Header{Description: "Current blog version", Schema: &Schema{Type: "string"}}
+}
+
+// pageParams returns the keyset pagination parameters, with the fields a list can be sorted by
+func pageParams(defaultSort string, sorts ...string) []Parameter {
+	values := make([]string, 0, 2*len(sorts))
+	for _, field := range sorts {
+		values = append(values, field, "-"+field)
+	}
+
+	return []Parameter{
+		{Name: "limit", In: "query", Description: "Page size",
+			Schema: &Schema{Type: "integer", Minimum: float(1), Maximum: float(dto.MaxPageLimit), Default: dto.DefaultPageLimit}},
+		{Name: "cursor", In: "query", Description: "next_cursor from the previous page, only valid with the same sort",
+			Schema: &Schema{Type: "string"}},
+		{Name: "sort", In: "query", Description: `Sort field, prefixed with "-" for descending order`,
+			Schema: &Schema{Type: "string", Enum: values, Default: defaultSort}},
+	}
+}
+
+func createdRangeParams() []Parameter {
+	return []Parameter{
+		{Name: "created_after", In: "query", Description: "Only items created at or after this time",
+			Schema: &Schema{Type: "string", Format: "date-time"}},
+		{Name: "created_before", In: "query", Description: "Only items created before this time",
+			Schema: &Schema{Type: "string", Format: "date-time"}},
+	}
+}
+
+func blogFilterParams() []Parameter {
+	return concat([]Parameter{
+		{Name: "author", In: "query", Description: "Author user ID", Schema: &Schema{Type: "integer", Minimum: float(1)}},
+		{Name: "status", In: "query", Schema: &Schema{Type: "string", Enum: []string{
+			string(model.BlogStatusDraft), string(model.BlogStatusScheduled), string(model.BlogStatusPublished), string(model.BlogStatusArchived),
+		}}},
+		{Name: "title", In: "query", Description: "Case-insensitive substring of the title", Schema: &Schema{Type: "string"}},
+		{Name: "tag", In: "query", Description: "Tags, repeated or comma-separated",
+			Schema: &Schema{Type: "array", Items: &Schema{Type: "string"}}},
+		{Name: "tag_match", In: "query", Description: "Whether blogs need any or all of the tags",
+			Schema: &Schema{Type: "string", Enum: []string{"any", "all"}, Default: "any"}},
+	}, createdRangeParams())
+}
+
+func userFilterParams() []Parameter {
+	return concat([]Parameter{
+		{Name: "role", In: "query", Schema: &Schema{Type: "string", Enum: []string{string(model.RoleUser), string(model.RoleAdmin)}}},
+		{Name: "username", In: "query", Description: "Case-insensitive substring of the username", Schema: &Schema{Type: "string"}},
+	}, createdRangeParams())
+}
+
+// without returns params except those with the given names
+func without(params []Parameter, names ...string) []Parameter {
+	var kept []Parameter
+	for _, param := range params {
+		if !slices.Contains(names, param.Name) {
+			kept = append(kept, param)
+		}
+	}
+	return kept
+}
+
+func concat(lists ...[]Parameter) []Parameter {
+	var all []Parameter
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	return all
+}
@@ -3,46 +3,251 @@ package repository
 import (
 	"context"
 	"errors"
+	"go_api/internal/app/dto"
 	"go_api/internal/app/model"
+	"go_api/internal/storage"
+	"strconv"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type BlogRepository struct {
+var ErrVersionConflict = errors.New("version conflict")
+
+// BlogRepository stores blogs with their tags and revisions. Missing blogs and revisions are
+// reported as gorm.ErrRecordNotFound, and duplicate revisions as gorm.ErrDuplicatedKey.
+type BlogRepository interface {
+	// Transaction runs fn with a repository whose changes are all kept, or all discarded if fn fails
+	Transaction(ctx context.Context, fn func(repo BlogRepository) error) error
+
+	CreateBlog(ctx context.Context, blog *model.Blog) error
+	GetBlog(ctx context.Context, id string) (*model.Blog, error)
+	UpdateBlog(ctx context.Context, blog *model.Blog) error
+	DeleteBlog(ctx context.Context, id string, userID uint) error
+	DeleteAnyBlog(ctx context.Context, id string) error
+	ListBlogs(ctx context.Context, filter dto.BlogFilter, page dto.PageQuery) ([]model.Blog, string, error)
+	SearchBlogs(ctx context.Context, tsQuery string, filter dto.BlogFilter, page dto.PageQuery) ([]model.BlogSearchResult, string, error)
+	LockDueBlogs(ctx context.Context, now time.Time) ([]model.Blog, error)
+	MarkPublished(ctx context.Context, ids []uint, now time.Time) error
+
+	CreateRevision(ctx context.Context, revision *model.BlogRevision) error
+	GetRevision(ctx context.Context, blogID uint, version uint) (*model.BlogRevision, error)
+	ListRevisions(ctx context.Context, blogID uint, page dto.PageQuery) ([]model.BlogRevision, string, error)
+
+	SetTags(ctx context.Context, blogID uint, names []string) ([]model.Tag, error)
+	ListTagCounts(ctx context.Context, limit int) ([]model.TagCount, error)
+}
+
+// ErrBlogNotFound is returned when deleting a blog that does not exist or is not owned by the user
+var ErrBlogNotFound = errors.New("blog not found")
+
+// GormBlogRepository stores blogs in Postgres
+type GormBlogRepository struct {
 	db *gorm.DB
 }
 
-func NewBlogRepository(db *gorm.DB) *BlogRepository {
-	return &BlogRepository{db: db}
+func NewBlogRepository(db *gorm.DB) *GormBlogRepository {
+	return &GormBlogRepository{db: db}
+}
+
+// Transaction runs fn with a repository bound to a single database transaction
+func (r *GormBlogRepository) Transaction(ctx context.Context, fn func(repo BlogRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&GormBlogRepository{db: tx})
+	})
 }
 
-func (r *BlogRepository) CreateBlog(ctx context.Context, blog *model.Blog) error {
+func (r *GormBlogRepository) CreateBlog(ctx context.Context, blog *model.Blog) error {
 	return r.db.WithContext(ctx).Create(blog).Error
 }
 
-func (r *BlogRepository) GetBlog(ctx context.Context, id string) (*model.Blog, error) {
+// GetBlog reads a blog from a replica, unless ctx reads from the primary
+func (r *GormBlogRepository) GetBlog(ctx context.Context, id string) (*model.Blog, error) {
+	ctx = storage.ReadFromReplica(ctx)
 	var blog model.Blog
 	if err := r.db.WithContext(ctx).First(&blog, id).Error; err != nil {
 		return nil, err
 	}
+	if err := r.loadTags(ctx, []*model.Blog{&blog}); err != nil {
+		return nil, err
+	}
 	return &blog, nil
 }
 
-func (r *BlogRepository) DeleteBlog(ctx context.Context, id string, userID uint) error {
+// UpdateBlog saves the blog's title, content and publication state if its stored version
+// still equals blog.Version, then increments the version
+func (r *GormBlogRepository) UpdateBlog(ctx context.Context, blog *model.Blog) error {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&model.Blog{}).
+		Where("id = ? AND version = ?", blog.ID, blog.Version).
+		Updates(map[string]interface{}{
+			"title":        blog.Title,
+			"content":      blog.Content,
+			"status":       blog.Status,
+			"published_at": blog.PublishedAt,
+			"version":      gorm.Expr("version + 1"),
+			"updated_at":   now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+
+	blog.Version++
+	blog.UpdatedAt = now
+	return nil
+}
+
+func (r *GormBlogRepository) DeleteBlog(ctx context.Context, id string, userID uint) error {
 	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&model.Blog{})
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("blog not found")
+		return ErrBlogNotFound
 	}
 	return nil
 }
 
-func (r *BlogRepository) ListBlogs(ctx context.Context) ([]model.Blog, error) {
+// DeleteAnyBlog deletes a blog regardless of its owner
+func (r *GormBlogRepository) DeleteAnyBlog(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.Blog{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBlogNotFound
+	}
+	return nil
+}
+
+var blogSortFields = map[string]sortField[model.Blog]{
+	"id":         idSortField("blogs.id", func(b *model.Blog) uint { return b.ID }),
+	"created_at": timeSortField("blogs.created_at", func(b *model.Blog) time.Time { return b.CreatedAt }),
+	"updated_at": timeSortField("blogs.updated_at", func(b *model.Blog) time.Time { return b.UpdatedAt }),
+	"title":      stringSortField("blogs.title", func(b *model.Blog) string { return b.Title }),
+}
+
+var blogSearchSortFields = map[string]sortField[model.BlogSearchResult]{
+	"rank": {column: "blogs.rank", value: formatRank, parse: parseRank,
+		key: func(b *model.BlogSearchResult) any { return b.Rank }},
+	"id":         idSortField("blogs.id", func(b *model.BlogSearchResult) uint { return b.ID }),
+	"created_at": timeSortField("blogs.created_at", func(b *model.BlogSearchResult) time.Time { return b.CreatedAt }),
+	"updated_at": timeSortField("blogs.updated_at", func(b *model.BlogSearchResult) time.Time { return b.UpdatedAt }),
+	"title":      stringSortField("blogs.title", func(b *model.BlogSearchResult) string { return b.Title }),
+}
+
+// ts_headline options for the highlighted title and the content snippet
+const (
+	titleHeadlineOptions   = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+	contentHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=\" ... \""
+)
+
+// ListBlogs returns one page of blogs matching the filter and the cursor of the next page, read from a replica
+func (r *GormBlogRepository) ListBlogs(ctx context.Context, filter dto.BlogFilter, page dto.PageQuery) ([]model.Blog, string, error) {
+	ctx = storage.ReadFromReplica(ctx)
+	query := filterBlogs(r.db.WithContext(ctx).Model(&model.Blog{}), filter)
+	blogs, nextCursor, err := paginate(query, page, blogSortFields, "blogs.id", func(b *model.Blog) uint { return b.ID })
+	if err != nil {
+		return nil, "", err
+	}
+
+	refs := make([]*model.Blog, 0, len(blogs))
+	for i := range blogs {
+		refs = append(refs, &blogs[i])
+	}
+	return blogs, nextCursor, r.loadTags(ctx, refs)
+}
+
+// SearchBlogs returns one page of blogs matching a to_tsquery expression and the filter,
+// ranked by relevance and with highlighted matches
+func (r *GormBlogRepository) SearchBlogs(ctx context.Context, tsQuery string, filter dto.BlogFilter, page dto.PageQuery) ([]model.BlogSearchResult, string, error) {
+	matches := r.db.Model(&model.Blog{}).
+		Select(
+			"blogs.*, ts_rank_cd(blogs.search_vector, query) AS rank, "+
+				"ts_headline('english', blogs.title, query, ?) AS title_highlight, "+
+				"ts_headline('english', blogs.content, query, ?) AS snippet",
+			titleHeadlineOptions, contentHeadlineOptions,
+		).
+		Joins("CROSS JOIN to_tsquery('english', ?) AS query", tsQuery).
+		Where("blogs.search_vector @@ query")
+	matches = filterBlogs(matches, filter)
+
+	// Wrap the matches so rank can be used like a column for sorting and cursors
+	query := r.db.WithContext(ctx).Table("(?) AS blogs", matches)
+	results, nextCursor, err := paginate(query, page, blogSearchSortFields, "blogs.id", func(b *model.BlogSearchResult) uint { return b.ID })
+	if err != nil {
+		return nil, "", err
+	}
+
+	refs := make([]*model.Blog, 0, len(results))
+	for i := range results {
+		refs = append(refs, &results[i].Blog)
+	}
+	return results, nextCursor, r.loadTags(ctx, refs)
+}
+
+// LockDueBlogs locks and returns the scheduled blogs whose publication time has passed.
+// Blogs locked by another transaction are skipped. It must run inside a transaction.
+func (r *GormBlogRepository) LockDueBlogs(ctx context.Context, now time.Time) ([]model.Blog, error) {
 	var blogs []model.Blog
-	if err := r.db.WithContext(ctx).Find(&blogs).Error; err != nil {
-		return nil, err
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND published_at <= ?", model.BlogStatusScheduled, now).
+		Find(&blogs).Error
+	return blogs, err
+}
+
+// MarkPublished publishes the given scheduled blogs
+func (r *GormBlogRepository) MarkPublished(ctx context.Context, ids []uint, now time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.Blog{}).
+		Where("id IN ? AND status = ?", ids, model.BlogStatusScheduled).
+		Updates(map[string]interface{}{
+			"status":     model.BlogStatusPublished,
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": now,
+		}).Error
+}
+
+// filterBlogs applies the visibility rules and list filters shared by listing and searching
+func filterBlogs(query *gorm.DB, filter dto.BlogFilter) *gorm.DB {
+	if !filter.ViewAll {
+		if filter.ViewerID != 0 {
+			query = query.Where("(blogs.status = ? OR blogs.user_id = ?)", model.BlogStatusPublished, filter.ViewerID)
+		} else {
+			query = query.Where("blogs.status = ?", model.BlogStatusPublished)
+		}
+	}
+
+	if filter.Status != "" {
+		query = query.Where("blogs.status = ?", filter.Status)
+	}
+	if filter.AuthorID != 0 {
+		query = query.Where("blogs.user_id = ?", filter.AuthorID)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("blogs.created_at >= ?", *filter.CreatedAfter)
 	}
-	return blogs, nil
+	if filter.CreatedBefore != nil {
+		query = query.Where("blogs.created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.Title != "" {
+		query = query.Where("blogs.title ILIKE ?", containsPattern(filter.Title))
+	}
+	if len(filter.Tags) > 0 {
+		query = tagFilter(query, filter.Tags, filter.MatchAllTags)
+	}
+	return query
+}
+
+func formatRank(b *model.BlogSearchResult) string {
+	return strconv.FormatFloat(float64(b.Rank), 'g', -1, 32)
+}
+
+func parseRank(value string) (any, error) {
+	rank, err := strconv.ParseFloat(value, 32)
+	return float32(rank), err
 }
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+
+	"go_api/internal/app/dto"
+	"go_api/internal/app/model"
+)
+
+var blogRevisionSortFields = map[string]sortField[model.BlogRevision]{
+	"version": idSortField("blog_revisions.version", func(r *model.BlogRevision) uint { return r.Version }),
+}
+
+func (r *GormBlogRepository) CreateRevision(ctx context.Context, revision *model.BlogRevision) error {
+	return r.db.WithContext(ctx).Create(revision).Error
+}
+
+func (r *GormBlogRepository) GetRevision(ctx context.Context, blogID uint, version uint) (*model.BlogRevision, error) {
+	var revision model.BlogRevision
+	err := r.db.WithContext(ctx).Where("blog_id = ? AND version = ?", blogID, version).First(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// ListRevisions returns one page of a blog's revisions and the cursor of the next page
+func (r *GormBlogRepository) ListRevisions(ctx context.Context, blogID uint, page dto.PageQuery) ([]model.BlogRevision, string, error) {
+	query := r.db.WithContext(ctx).Model(&model.BlogRevision{}).Where("blog_revisions.blog_id = ?", blogID)
+	return paginate(query, page, blogRevisionSortFields, "blog_revisions.id", func(r *model.BlogRevision) uint { return r.ID })
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+
+	"go_api/internal/app/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SetTags replaces a blog's tags, creating the tags that do not exist yet.
+// names must already be normalized.
+func (r *GormBlogRepository) SetTags(ctx context.Context, blogID uint, names []string) ([]model.Tag, error) {
+	db := r.db.WithContext(ctx)
+	if err := db.Exec("DELETE FROM blog_tags WHERE blog_id = ?", blogID).Error; err != nil {
+		return nil, err
+	}
+
+	tags := make([]model.Tag, 0, len(names))
+	if len(names) == 0 {
+		return tags, nil
+	}
+	for _, name := range names {
+		tags = append(tags, model.Tag{Name: name})
+	}
+
+	// Existing tags are skipped, then every tag is read back to get its ID
+	err := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	tags = tags[:0]
+	if err := db.Where("name IN ?", names).Order("name").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	links := make([]map[string]interface{}, 0, len(tags))
+	for _, tag := range tags {
+		links = append(links, map[string]interface{}{"blog_id": blogID, "tag_id": tag.ID})
+	}
+	if err := db.Table("blog_tags").Create(&links).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// ListTagCounts returns the tags used by published blogs, most used first
+func (r *GormBlogRepository) ListTagCounts(ctx context.Context, limit int) ([]model.TagCount, error) {
+	var counts []model.TagCount
+	err := r.db.WithContext(ctx).Table("tags").
+		Select("tags.name, COUNT(*) AS count").
+		Joins("JOIN blog_tags ON blog_tags.tag_id = tags.id").
+		Joins("JOIN blogs ON blogs.id = blog_tags.blog_id").
+		Where("blogs.status = ?", model.BlogStatusPublished).
+		Group("tags.name").
+		Order("count DESC, tags.name").
+		Limit(limit).
+		Scan(&counts).Error
+	return counts, err
+}
+
+// loadTags fills in the tags of the given blogs with a single query
+func (r *GormBlogRepository) loadTags(ctx context.Context, blogs []*model.Blog) error {
+	if len(blogs) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(blogs))
+	byID := make(map[uint]*model.Blog, len(blogs))
+	for _, blog := range blogs {
+		blog.Tags = []model.Tag{}
+		ids = append(ids, blog.ID)
+		byID[blog.ID] = blog
+	}
+
+	var rows []struct {
+		BlogID uint
+		TagID  uint
+		Name   string
+	}
+	err := r.db.WithContext(ctx).Table("blog_tags").
+		Select("blog_tags.blog_id, tags.id AS tag_id, tags.name").
+		Joins("JOIN tags ON tags.id = blog_tags.tag_id").
+		Where("blog_tags.blog_id IN ?", ids).
+		Order("tags.name").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		blog := byID[row.BlogID]
+		blog.Tags = append(blog.Tags, model.Tag{ID: row.TagID, Name: row.Name})
+	}
+	return nil
+}
+
+// tagFilter restricts a blog query to blogs with any, or all, of the given tags
+func tagFilter(query *gorm.DB, tags []string, matchAll bool) *gorm.DB {
+	tagged := query.Session(&gorm.Session{NewDB: true}).Table("blog_tags").
+		Select("blog_tags.blog_id").
+		Joins("JOIN tags ON tags.id = blog_tags.tag_id").
+		Where("tags.name IN ?", tags)
+	if matchAll {
+		tagged = tagged.Group("blog_tags.blog_id").Having("COUNT(*) = ?", len(tags))
+	}
+	return query.Where("blogs.id IN (?)", tagged)
+}
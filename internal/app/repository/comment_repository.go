@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go_api/internal/app/dto"
+	"go_api/internal/app/model"
+
+	"gorm.io/gorm"
+)
+
+// CommentRepository stores the comments of blogs. Missing comments are reported as gorm.ErrRecordNotFound.
+type CommentRepository interface {
+	CreateComment(ctx context.Context, comment *model.Comment) error
+	GetComment(ctx context.Context, blogID uint, id string) (*model.Comment, error)
+	UpdateContent(ctx context.Context, comment *model.Comment) error
+	SoftDelete(ctx context.Context, id uint) error
+	ListRootComments(ctx context.Context, blogID uint, page dto.PageQuery) ([]model.Comment, string, error)
+	ListReplies(ctx context.Context, rootIDs []uint, perThread int) ([]model.Comment, error)
+	ListThreadReplies(ctx context.Context, rootID uint, page dto.PageQuery) ([]model.Comment, string, error)
+}
+
+// GormCommentRepository stores comments in Postgres
+type GormCommentRepository struct {
+	db *gorm.DB
+}
+
+func NewCommentRepository(db *gorm.DB) *GormCommentRepository {
+	return &GormCommentRepository{db: db}
+}
+
+var commentSortFields = map[string]sortField[model.Comment]{
+	"id":         idSortField("comments.id", func(c *model.Comment) uint { return c.ID }),
+	"created_at": timeSortField("comments.created_at", func(c *model.Comment) time.Time { return c.CreatedAt }),
+}
+
+func (r *GormCommentRepository) CreateComment(ctx context.Context, comment *model.Comment) error {
+	return r.db.WithContext(ctx).Create(comment).Error
+}
+
+func (r *GormCommentRepository) GetComment(ctx context.Context, blogID uint, id string) (*model.Comment, error) {
+	var comment model.Comment
+	err := r.db.WithContext(ctx).Where("id = ? AND blog_id = ?", id, blogID).First(&comment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// UpdateContent replaces the content of a comment that has not been deleted
+func (r *GormCommentRepository) UpdateContent(ctx context.Context, comment *model.Comment) error {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&model.Comment{}).
+		Where("id = ? AND deleted_at IS NULL", comment.ID).
+		Updates(map[string]interface{}{
+			"content":    comment.Content,
+			"edited_at":  now,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	comment.EditedAt = &now
+	comment.UpdatedAt = now
+	return nil
+}
+
+// SoftDelete clears the content of a comment and marks it deleted, keeping it as a placeholder
+func (r *GormCommentRepository) SoftDelete(ctx context.Context, id uint) error {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&model.Comment{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"content":    "",
+			"deleted_at": now,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListRootComments returns one page of a blog's top-level comments and the cursor of the next page
+func (r *GormCommentRepository) ListRootComments(ctx context.Context, blogID uint, page dto.PageQuery) ([]model.Comment, string, error) {
+	query := r.db.WithContext(ctx).Model(&model.Comment{}).
+		Where("comments.blog_id = ? AND comments.parent_id IS NULL", blogID)
+	return paginate(query, page, commentSortFields, "comments.id", func(c *model.Comment) uint { return c.ID })
+}
+
+// ListReplies returns the first perThread replies in each thread of the given top-level comments, oldest first
+func (r *GormCommentRepository) ListReplies(ctx context.Context, rootIDs []uint, perThread int) ([]model.Comment, error) {
+	replies := []model.Comment{}
+	if len(rootIDs) == 0 {
+		return replies, nil
+	}
+
+	numbered := r.db.Model(&model.Comment{}).
+		Select("comments.*, ROW_NUMBER() OVER (PARTITION BY root_id ORDER BY created_at ASC, id ASC) AS thread_position").
+		Where("root_id IN ?", rootIDs)
+	err := r.db.WithContext(ctx).
+		Table("(?) AS comments", numbered).
+		Where("thread_position <= ?", perThread).
+		Order("created_at ASC, id ASC").
+		Find(&replies).Error
+	return replies, err
+}
+
+// ListThreadReplies returns one page of every reply in the thread of a top-level comment and the cursor of the next page
+func (r *GormCommentRepository) ListThreadReplies(ctx context.Context, rootID uint, page dto.PageQuery) ([]model.Comment, string, error) {
+	query := r.db.WithContext(ctx).Model(&model.Comment{}).
+		Where("comments.root_id = ?", rootID)
+	return paginate(query, page, commentSortFields, "comments.id", func(c *model.Comment) uint { return c.ID })
+}
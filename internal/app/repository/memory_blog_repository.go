@@ -0,0 +1,400 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go_api/internal/app/dto"
+	"go_api/internal/app/model"
+
+	"gorm.io/gorm"
+)
+
+// MemoryBlogRepository stores blogs in memory, for tests. It follows the behavior of
+// GormBlogRepository, except that search matches words without stemming and ranks
+// them approximately.
+type MemoryBlogRepository struct {
+	mu   *sync.Mutex
+	data *blogData
+	inTx bool // Set on the repository of a transaction, which already holds mu
+}
+
+type blogData struct {
+	blogs     map[uint]model.Blog
+	tags      map[string]model.Tag // By name
+	blogTags  map[uint][]uint      // Tag IDs by blog ID
+	revisions map[uint]model.BlogRevision
+	lastID    uint // Shared by every table, IDs only need to be unique per table
+}
+
+func (d *blogData) clone() *blogData {
+	blogTags := make(map[uint][]uint, len(d.blogTags))
+	for blogID, tagIDs := range d.blogTags {
+		blogTags[blogID] = slices.Clone(tagIDs)
+	}
+	return &blogData{
+		blogs:     maps.Clone(d.blogs),
+		tags:      maps.Clone(d.tags),
+		blogTags:  blogTags,
+		revisions: maps.Clone(d.revisions),
+		lastID:    d.lastID,
+	}
+}
+
+func (d *blogData) nextID() uint {
+	d.lastID++
+	return d.lastID
+}
+
+func NewMemoryBlogRepository() *MemoryBlogRepository {
+	return &MemoryBlogRepository{
+		mu: &sync.Mutex{},
+		data: &blogData{
+			blogs:     make(map[uint]model.Blog),
+			tags:      make(map[string]model.Tag),
+			blogTags:  make(map[uint][]uint),
+			revisions: make(map[uint]model.BlogRevision),
+		},
+	}
+}
+
+func (r *MemoryBlogRepository) lock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.mu.Lock()
+	return r.mu.Unlock
+}
+
+// Transaction runs fn while holding the repository lock, and restores the previous data if fn fails
+func (r *MemoryBlogRepository) Transaction(ctx context.Context, fn func(repo BlogRepository) error) error {
+	defer r.lock()()
+
+	snapshot := r.data.clone()
+	if err := fn(&MemoryBlogRepository{mu: r.mu, data: r.data, inTx: true}); err != nil {
+		*r.data = *snapshot
+		return err
+	}
+	return nil
+}
+
+func (r *MemoryBlogRepository) CreateBlog(ctx context.Context, blog *model.Blog) error {
+	defer r.lock()()
+
+	now := time.Now()
+	blog.ID = r.data.nextID()
+	if blog.Status == "" {
+		blog.Status = model.BlogStatusPublished
+	}
+	if blog.Version == 0 {
+		blog.Version = 1
+	}
+	if blog.CreatedAt.IsZero() {
+		blog.CreatedAt = now
+	}
+	if blog.UpdatedAt.IsZero() {
+		blog.UpdatedAt = now
+	}
+	r.data.blogs[blog.ID] = stored(*blog)
+	return nil
+}
+
+func (r *MemoryBlogRepository) GetBlog(ctx context.Context, id string) (*model.Blog, error) {
+	defer r.lock()()
+
+	blog, ok := r.data.blogs[parseID(id)]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	blog.Tags = r.tagsOf(blog.ID)
+	return &blog, nil
+}
+
+func (r *MemoryBlogRepository) UpdateBlog(ctx context.Context, blog *model.Blog) error {
+	defer r.lock()()
+
+	current, ok := r.data.blogs[blog.ID]
+	if !ok || current.Version != blog.Version {
+		return ErrVersionConflict
+	}
+
+	now := time.Now()
+	current.Title = blog.Title
+	current.Content = blog.Content
+	current.Status = blog.Status
+	current.PublishedAt = blog.PublishedAt
+	current.Version++
+	current.UpdatedAt = now
+	r.data.blogs[blog.ID] = current
+
+	blog.Version++
+	blog.UpdatedAt = now
+	return nil
+}
+
+func (r *MemoryBlogRepository) DeleteBlog(ctx context.Context, id string, userID uint) error {
+	defer r.lock()()
+
+	blog, ok := r.data.blogs[parseID(id)]
+	if !ok || blog.UserID != userID {
+		return ErrBlogNotFound
+	}
+	r.deleteBlog(blog.ID)
+	return nil
+}
+
+func (r *MemoryBlogRepository) DeleteAnyBlog(ctx context.Context, id string) error {
+	defer r.lock()()
+
+	blog, ok := r.data.blogs[parseID(id)]
+	if !ok {
+		return ErrBlogNotFound
+	}
+	r.deleteBlog(blog.ID)
+	return nil
+}
+
+// deleteBlog deletes a blog with its tag links and revisions, like the foreign key cascades
+func (r *MemoryBlogRepository) deleteBlog(id uint) {
+	delete(r.data.blogs, id)
+	delete(r.data.blogTags, id)
+	for revisionID, revision := range r.data.revisions {
+		if revision.BlogID == id {
+			delete(r.data.revisions, revisionID)
+		}
+	}
+}
+
+func (r *MemoryBlogRepository) ListBlogs(ctx context.Context, filter dto.BlogFilter, page dto.PageQuery) ([]model.Blog, string, error) {
+	defer r.lock()()
+
+	var blogs []model.Blog
+	for _, blog := range r.data.blogs {
+		if r.matches(&blog, filter) {
+			blogs = append(blogs, blog)
+		}
+	}
+	blogs, nextCursor, err := paginateSlice(blogs, page, blogSortFields, func(b *model.Blog) uint { return b.ID })
+	if err != nil {
+		return nil, "", err
+	}
+	for i := range blogs {
+		blogs[i].Tags = r.tagsOf(blogs[i].ID)
+	}
+	return blogs, nextCursor, nil
+}
+
+func (r *MemoryBlogRepository) SearchBlogs(ctx context.Context, tsQuery string, filter dto.BlogFilter, page dto.PageQuery) ([]model.BlogSearchResult, string, error) {
+	defer r.lock()()
+
+	query := parseTSQuery(tsQuery)
+	var results []model.BlogSearchResult
+	for _, blog := range r.data.blogs {
+		if !r.matches(&blog, filter) {
+			continue
+		}
+		if result, ok := query.search(blog); ok {
+			results = append(results, result)
+		}
+	}
+	results, nextCursor, err := paginateSlice(results, page, blogSearchSortFields, func(b *model.BlogSearchResult) uint { return b.ID })
+	if err != nil {
+		return nil, "", err
+	}
+	for i := range results {
+		results[i].Tags = r.tagsOf(results[i].ID)
+	}
+	return results, nextCursor, nil
+}
+
+func (r *MemoryBlogRepository) LockDueBlogs(ctx context.Context, now time.Time) ([]model.Blog, error) {
+	defer r.lock()()
+
+	var blogs []model.Blog
+	for _, blog := range r.data.blogs {
+		if blog.Status == model.BlogStatusScheduled && blog.PublishedAt != nil && !blog.PublishedAt.After(now) {
+			blogs = append(blogs, blog)
+		}
+	}
+	return blogs, nil
+}
+
+func (r *MemoryBlogRepository) MarkPublished(ctx context.Context, ids []uint, now time.Time) error {
+	defer r.lock()()
+
+	for _, id := range ids {
+		blog, ok := r.data.blogs[id]
+		if !ok || blog.Status != model.BlogStatusScheduled {
+			continue
+		}
+		blog.Status = model.BlogStatusPublished
+		blog.Version++
+		blog.UpdatedAt = now
+		r.data.blogs[id] = blog
+	}
+	return nil
+}
+
+func (r *MemoryBlogRepository) CreateRevision(ctx context.Context, revision *model.BlogRevision) error {
+	defer r.lock()()
+
+	if _, ok := r.data.blogs[revision.BlogID]; !ok {
+		return gorm.ErrForeignKeyViolated
+	}
+	for _, existing := range r.data.revisions {
+		if existing.BlogID == revision.BlogID && existing.Version == revision.Version {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	revision.ID = r.data.nextID()
+	if revision.CreatedAt.IsZero() {
+		revision.CreatedAt = time.Now()
+	}
+	r.data.revisions[revision.ID] = *revision
+	return nil
+}
+
+func (r *MemoryBlogRepository) GetRevision(ctx context.Context, blogID uint, version uint) (*model.BlogRevision, error) {
+	defer r.lock()()
+
+	for _, revision := range r.data.revisions {
+		if revision.BlogID == blogID && revision.Version == version {
+			return &revision, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryBlogRepository) ListRevisions(ctx context.Context, blogID uint, page dto.PageQuery) ([]model.BlogRevision, string, error) {
+	defer r.lock()()
+
+	var revisions []model.BlogRevision
+	for _, revision := range r.data.revisions {
+		if revision.BlogID == blogID {
+			revisions = append(revisions, revision)
+		}
+	}
+	return paginateSlice(revisions, page, blogRevisionSortFields, func(r *model.BlogRevision) uint { return r.ID })
+}
+
+func (r *MemoryBlogRepository) SetTags(ctx context.Context, blogID uint, names []string) ([]model.Tag, error) {
+	defer r.lock()()
+
+	tags := make([]model.Tag, 0, len(names))
+	tagIDs := make([]uint, 0, len(names))
+	for _, name := range names {
+		tag, ok := r.data.tags[name]
+		if !ok {
+			tag = model.Tag{ID: r.data.nextID(), Name: name, CreatedAt: time.Now()}
+			r.data.tags[name] = tag
+		}
+		if !slices.Contains(tagIDs, tag.ID) {
+			tags = append(tags, tag)
+			tagIDs = append(tagIDs, tag.ID)
+		}
+	}
+	r.data.blogTags[blogID] = tagIDs
+
+	slices.SortFunc(tags, func(a, b model.Tag) int { return strings.Compare(a.Name, b.Name) })
+	return tags, nil
+}
+
+func (r *MemoryBlogRepository) ListTagCounts(ctx context.Context, limit int) ([]model.TagCount, error) {
+	defer r.lock()()
+
+	names := make(map[uint]string, len(r.data.tags))
+	for _, tag := range r.data.tags {
+		names[tag.ID] = tag.Name
+	}
+	counts := make(map[string]int64)
+	for blogID, tagIDs := range r.data.blogTags {
+		if blog, ok := r.data.blogs[blogID]; !ok || blog.Status != model.BlogStatusPublished {
+			continue
+		}
+		for _, tagID := range tagIDs {
+			counts[names[tagID]]++
+		}
+	}
+
+	result := make([]model.TagCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, model.TagCount{Name: name, Count: count})
+	}
+	slices.SortFunc(result, func(a, b model.TagCount) int {
+		if a.Count != b.Count {
+			return int(b.Count - a.Count)
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// tagsOf returns the tags of a blog sorted by name
+func (r *MemoryBlogRepository) tagsOf(blogID uint) []model.Tag {
+	tags := []model.Tag{}
+	for _, tag := range r.data.tags {
+		if slices.Contains(r.data.blogTags[blogID], tag.ID) {
+			tags = append(tags, tag)
+		}
+	}
+	slices.SortFunc(tags, func(a, b model.Tag) int { return strings.Compare(a.Name, b.Name) })
+	return tags
+}
+
+// matches applies the same visibility rules and filters as filterBlogs
+func (r *MemoryBlogRepository) matches(blog *model.Blog, filter dto.BlogFilter) bool {
+	if !filter.ViewAll && !blog.IsPublic() && (filter.ViewerID == 0 || blog.UserID != filter.ViewerID) {
+		return false
+	}
+	switch {
+	case filter.Status != "" && string(blog.Status) != filter.Status,
+		filter.AuthorID != 0 && blog.UserID != filter.AuthorID,
+		filter.CreatedAfter != nil && blog.CreatedAt.Before(*filter.CreatedAfter),
+		filter.CreatedBefore != nil && !blog.CreatedAt.Before(*filter.CreatedBefore),
+		filter.Title != "" && !containsFold(blog.Title, filter.Title):
+		return false
+	}
+	if len(filter.Tags) == 0 {
+		return true
+	}
+
+	matched := 0
+	for _, name := range filter.Tags {
+		if tag, ok := r.data.tags[name]; ok && slices.Contains(r.data.blogTags[blog.ID], tag.ID) {
+			matched++
+		}
+	}
+	if filter.MatchAllTags {
+		return matched == len(filter.Tags)
+	}
+	return matched > 0
+}
+
+// stored returns the copy of a blog kept by the repository, tags are stored separately
+func stored(blog model.Blog) model.Blog {
+	blog.Tags = nil
+	return blog
+}
+
+// parseID parses a blog ID given as a string, invalid IDs match no blog
+func parseID(id string) uint {
+	value, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(value)
+}
+
+// containsFold reports whether substr is in s, ignoring case like ILIKE
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"strings"
+	"unicode"
+
+	"go_api/internal/app/model"
+)
+
+// Search weights of the title and the content, close to the A and B weights of ts_rank_cd
+const (
+	titleSearchWeight   = 1.0
+	contentSearchWeight = 0.4
+)
+
+// memoryQuery is a query built by util.ParseSearchQuery: terms that must all match
+type memoryQuery []memoryTerm
+
+// memoryTerm is a word or a phrase of consecutive words
+type memoryTerm []memoryWord
+
+type memoryWord struct {
+	text   string
+	prefix bool // Matches any word starting with text ("text:*")
+}
+
+// searchWord is a word of a searched text, with its byte offsets
+type searchWord struct {
+	text       string
+	start, end int
+}
+
+// parseTSQuery parses the subset of the tsquery syntax produced by util.ParseSearchQuery
+func parseTSQuery(tsQuery string) memoryQuery {
+	var query memoryQuery
+	for _, part := range strings.Split(tsQuery, " & ") {
+		part = strings.TrimSuffix(strings.TrimPrefix(part, "("), ")")
+
+		var term memoryTerm
+		for _, word := range strings.Split(part, " <-> ") {
+			text, prefix := strings.CutSuffix(word, ":*")
+			if text != "" {
+				term = append(term, memoryWord{text: text, prefix: prefix})
+			}
+		}
+		if len(term) > 0 {
+			query = append(query, term)
+		}
+	}
+	return query
+}
+
+// search matches a blog against the query. Words are compared without stemming.
+func (q memoryQuery) search(blog model.Blog) (model.BlogSearchResult, bool) {
+	if len(q) == 0 {
+		return model.BlogSearchResult{}, false
+	}
+
+	title, content := splitSearchWords(blog.Title), splitSearchWords(blog.Content)
+	titleMatches, contentMatches := make([]bool, len(title)), make([]bool, len(content))
+	var rank float32
+	for _, term := range q {
+		titleHits, contentHits := term.mark(title, titleMatches), term.mark(content, contentMatches)
+		if titleHits+contentHits == 0 {
+			return model.BlogSearchResult{}, false
+		}
+		rank += titleSearchWeight*float32(titleHits) + contentSearchWeight*float32(contentHits)
+	}
+
+	return model.BlogSearchResult{
+		Blog:           stored(blog),
+		Rank:           rank,
+		TitleHighlight: highlight(blog.Title, title, titleMatches),
+		Snippet:        highlight(blog.Content, content, contentMatches),
+	}, true
+}
+
+// mark flags the words of every occurrence of the term and returns the number of occurrences
+func (t memoryTerm) mark(words []searchWord, matches []bool) int {
+	hits := 0
+	for i := 0; i+len(t) <= len(words); i++ {
+		if !t.matchesAt(words, i) {
+			continue
+		}
+		for j := range t {
+			matches[i+j] = true
+		}
+		hits++
+	}
+	return hits
+}
+
+func (t memoryTerm) matchesAt(words []searchWord, i int) bool {
+	for j, word := range t {
+		text := words[i+j].text
+		if text != word.text && !(word.prefix && strings.HasPrefix(text, word.text)) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitSearchWords splits text into lowercase words made of letters and digits, like util.ParseSearchQuery
+func splitSearchWords(text string) []searchWord {
+	var words []searchWord
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			words = append(words, searchWord{text: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, searchWord{text: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+	return words
+}
+
+// highlight wraps the matched words of text in <mark>, like ts_headline with HighlightAll
+func highlight(text string, words []searchWord, matches []bool) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	last := 0
+	for i, word := range words {
+		if !matches[i] {
+			continue
+		}
+		b.WriteString(text[last:word.start])
+		b.WriteString("<mark>")
+		b.WriteString(text[word.start:word.end])
+		b.WriteString("</mark>")
+		last = word.end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go_api/internal/app/dto"
+	"go_api/internal/app/model"
+
+	"gorm.io/gorm"
+)
+
+// MemoryCommentRepository stores comments in memory, for tests. It follows the behavior of GormCommentRepository.
+type MemoryCommentRepository struct {
+	mu       sync.Mutex
+	comments map[uint]model.Comment
+	lastID   uint
+}
+
+func NewMemoryCommentRepository() *MemoryCommentRepository {
+	return &MemoryCommentRepository{comments: make(map[uint]model.Comment)}
+}
+
+func (r *MemoryCommentRepository) CreateComment(ctx context.Context, comment *model.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.lastID++
+	comment.ID = r.lastID
+	if comment.CreatedAt.IsZero() {
+		comment.CreatedAt = now
+	}
+	if comment.UpdatedAt.IsZero() {
+		comment.UpdatedAt = now
+	}
+	r.comments[comment.ID] = *comment
+	return nil
+}
+
+func (r *MemoryCommentRepository) GetComment(ctx context.Context, blogID uint, id string) (*model.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, ok := r.comments[parseID(id)]
+	if !ok || comment.BlogID != blogID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &comment, nil
+}
+
+// UpdateContent replaces the content of a comment that has not been deleted
+func (r *MemoryCommentRepository) UpdateContent(ctx context.Context, comment *model.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.comments[comment.ID]
+	if !ok || stored.IsDeleted() {
+		return gorm.ErrRecordNotFound
+	}
+
+	now := time.Now()
+	stored.Content = comment.Content
+	stored.EditedAt = &now
+	stored.UpdatedAt = now
+	r.comments[comment.ID] = stored
+
+	comment.EditedAt = &now
+	comment.UpdatedAt = now
+	return nil
+}
+
+// SoftDelete clears the content of a comment and marks it deleted, keeping it as a placeholder
+func (r *MemoryCommentRepository) SoftDelete(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.comments[id]
+	if !ok || stored.IsDeleted() {
+		return gorm.ErrRecordNotFound
+	}
+
+	now := time.Now()
+	stored.Content = ""
+	stored.DeletedAt = &now
+	stored.UpdatedAt = now
+	r.comments[id] = stored
+	return nil
+}
+
+// ListRootComments returns one page of a blog's top-level comments and the cursor of the next page
+func (r *MemoryCommentRepository) ListRootComments(ctx context.Context, blogID uint, page dto.PageQuery) ([]model.Comment, string, error) {
+	return r.listPage(page, func(c *model.Comment) bool {
+		return c.BlogID == blogID && c.ParentID == nil
+	})
+}
+
+// ListReplies returns the first perThread replies in each thread of the given top-level comments, oldest first
+func (r *MemoryCommentRepository) ListReplies(ctx context.Context, rootIDs []uint, perThread int) ([]model.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	threads := map[uint][]model.Comment{}
+	for _, comment := range r.comments {
+		if comment.RootID != nil && slices.Contains(rootIDs, *comment.RootID) {
+			threads[*comment.RootID] = append(threads[*comment.RootID], comment)
+		}
+	}
+
+	replies := []model.Comment{}
+	for _, thread := range threads {
+		slices.SortFunc(thread, compareCommentAge)
+		replies = append(replies, thread[:min(len(thread), perThread)]...)
+	}
+	slices.SortFunc(replies, compareCommentAge)
+	return replies, nil
+}
+
+// ListThreadReplies returns one page of every reply in the thread of a top-level comment and the cursor of the next page
+func (r *MemoryCommentRepository) ListThreadReplies(ctx context.Context, rootID uint, page dto.PageQuery) ([]model.Comment, string, error) {
+	return r.listPage(page, func(c *model.Comment) bool {
+		return c.RootID != nil && *c.RootID == rootID
+	})
+}
+
+// listPage returns one page of the comments matching keep
+func (r *MemoryCommentRepository) listPage(page dto.PageQuery, keep func(*model.Comment) bool) ([]model.Comment, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var comments []model.Comment
+	for _, comment := range r.comments {
+		if keep(&comment) {
+			comments = append(comments, comment)
+		}
+	}
+	return paginateSlice(comments, page, commentSortFields, func(c *model.Comment) uint { return c.ID })
+}
+
+// compareCommentAge orders comments oldest first, like ORDER BY created_at, id
+func compareCommentAge(a, b model.Comment) int {
+	return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+}
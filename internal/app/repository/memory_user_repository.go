@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go_api/internal/app/dto"
+	"go_api/internal/app/model"
+
+	"gorm.io/gorm"
+)
+
+// MemoryUserRepository stores users in memory, for tests. It follows the behavior of GormUserRepository.
+type MemoryUserRepository struct {
+	mu     sync.Mutex
+	users  map[uint]model.User
+	lastID uint
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[uint]model.User)}
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Username == user.Username || existing.Email == user.Email {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	now := time.Now()
+	r.lastID++
+	user.ID = r.lastID
+	if user.Role == "" {
+		user.Role = model.RoleUser
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+	r.users[user.ID] = cloneUser(*user)
+	return nil
+}
+
+func (r *MemoryUserRepository) FindByID(ctx context.Context, id uint) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return &model.User{}, gorm.ErrRecordNotFound
+	}
+	user = cloneUser(user)
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			user = cloneUser(user)
+			return &user, nil
+		}
+	}
+	return &model.User{}, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryUserRepository) ListAllUsers(ctx context.Context, filter dto.UserFilter, page dto.PageQuery) ([]model.User, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []model.User
+	for _, user := range r.users {
+		switch {
+		case filter.Role != "" && string(user.Role) != filter.Role,
+			filter.CreatedAfter != nil && user.CreatedAt.Before(*filter.CreatedAfter),
+			filter.CreatedBefore != nil && !user.CreatedAt.Before(*filter.CreatedBefore),
+			filter.Username != "" && !containsFold(user.Username, filter.Username):
+			continue
+		}
+		users = append(users, cloneUser(user))
+	}
+	return paginateSlice(users, page, userSortFields, func(u *model.User) uint { return u.ID })
+}
+
+func (r *MemoryUserRepository) UpdateRole(ctx context.Context, id uint, role model.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.Role = role
+	user.UpdatedAt = time.Now()
+	r.users[id] = user
+	return nil
+}
+
+func (r *MemoryUserRepository) UpdatePermissions(ctx context.Context, id uint, permissions []model.Permission) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.Permissions = permissions
+	user.UpdatedAt = time.Now()
+	r.users[id] = cloneUser(user)
+	return nil
+}
+
+// cloneUser copies a user so callers cannot modify the stored permissions
+func cloneUser(user model.User) model.User {
+	if user.Permissions != nil {
+		user.Permissions = append([]model.Permission(nil), user.Permissions...)
+	}
+	return user
+}
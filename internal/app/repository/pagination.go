@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_api/internal/app/dto"
+	"go_api/internal/util"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidPageQuery = errors.New("invalid page query")
+
+// sortField is a whitelisted column a list can be sorted by
+type sortField[T any] struct {
+	column string
+	key    func(*T) any              // Sort value of the item, of the type parse returns
+	value  func(*T) string           // Encodes the item's sort value into a cursor
+	parse  func(string) (any, error) // Decodes a cursor value back into a query argument
+}
+
+func timeSortField[T any](column string, get func(*T) time.Time) sortField[T] {
+	return sortField[T]{
+		column: column,
+		key:    func(item *T) any { return get(item) },
+		value:  func(item *T) string { return get(item).Format(time.RFC3339Nano) },
+		parse: func(value string) (any, error) {
+			return time.Parse(time.RFC3339Nano, value)
+		},
+	}
+}
+
+func stringSortField[T any](column string, get func(*T) string) sortField[T] {
+	return sortField[T]{
+		column: column,
+		key:    func(item *T) any { return get(item) },
+		value:  get,
+		parse:  func(value string) (any, error) { return value, nil },
+	}
+}
+
+func idSortField[T any](column string, get func(*T) uint) sortField[T] {
+	return sortField[T]{
+		column: column,
+		key:    func(item *T) any { return uint64(get(item)) },
+		value:  func(item *T) string { return strconv.FormatUint(uint64(get(item)), 10) },
+		parse: func(value string) (any, error) {
+			return strconv.ParseUint(value, 10, 64)
+		},
+	}
+}
+
+// paginate runs a keyset-paginated query ordered by the sort field and then by idColumn.
+// It returns the page of items and the cursor of the next page, which is empty on the last page.
+func paginate[T any](query *gorm.DB, page dto.PageQuery, fields map[string]sortField[T], idColumn string, id func(*T) uint) ([]T, string, error) {
+	field, cursor, err := pageStart(page, fields)
+	if err != nil {
+		return nil, "", err
+	}
+
+	operator, direction := ">", "ASC"
+	if page.Desc {
+		operator, direction = "<", "DESC"
+	}
+	if cursor != nil {
+		query = query.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", field.column, idColumn, operator), cursor.value, cursor.id)
+	}
+
+	// Fetch one extra item to know whether there is a next page
+	items := make([]T, 0, page.Limit+1)
+	err = query.
+		Order(fmt.Sprintf("%s %s, %s %s", field.column, direction, idColumn, direction)).
+		Limit(page.Limit + 1).
+		Find(&items).Error
+	if err != nil {
+		return nil, "", err
+	}
+	return pageEnd(items, page, field, id)
+}
+
+// paginateSlice paginates items held in memory with the same order and cursors as paginate
+func paginateSlice[T any](items []T, page dto.PageQuery, fields map[string]sortField[T], id func(*T) uint) ([]T, string, error) {
+	field, cursor, err := pageStart(page, fields)
+	if err != nil {
+		return nil, "", err
+	}
+
+	order := func(a *T, key any, itemID uint) int {
+		c := cmp.Or(compareKeys(field.key(a), key), cmp.Compare(id(a), itemID))
+		if page.Desc {
+			return -c
+		}
+		return c
+	}
+	slices.SortFunc(items, func(a, b T) int {
+		return order(&a, field.key(&b), id(&b))
+	})
+
+	if cursor != nil {
+		start := len(items)
+		for i := range items {
+			if order(&items[i], cursor.value, cursor.id) > 0 {
+				start = i
+				break
+			}
+		}
+		items = items[start:]
+	}
+	if len(items) > page.Limit+1 {
+		items = items[:page.Limit+1]
+	}
+	return pageEnd(items, page, field, id)
+}
+
+type pageCursor struct {
+	value any
+	id    uint
+}
+
+// pageStart checks the sort field and decodes the cursor of a page query
+func pageStart[T any](page dto.PageQuery, fields map[string]sortField[T]) (sortField[T], *pageCursor, error) {
+	field, ok := fields[page.Sort]
+	if !ok {
+		return field, nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidPageQuery, page.Sort)
+	}
+	if page.Cursor == "" {
+		return field, nil, nil
+	}
+
+	cursor, err := util.DecodeCursor(page.Cursor)
+	if err != nil || cursor.Sort != page.Sort || cursor.Desc != page.Desc {
+		return field, nil, fmt.Errorf("%w: invalid cursor", ErrInvalidPageQuery)
+	}
+	value, err := field.parse(cursor.Value)
+	if err != nil {
+		return field, nil, fmt.Errorf("%w: invalid cursor", ErrInvalidPageQuery)
+	}
+	return field, &pageCursor{value: value, id: cursor.ID}, nil
+}
+
+// pageEnd trims the extra item fetched past the page and encodes the cursor of the next page
+func pageEnd[T any](items []T, page dto.PageQuery, field sortField[T], id func(*T) uint) ([]T, string, error) {
+	if len(items) <= page.Limit {
+		return items, "", nil
+	}
+
+	items = items[:page.Limit]
+	last := &items[len(items)-1]
+	nextCursor := util.EncodeCursor(util.Cursor{
+		Sort:  page.Sort,
+		Desc:  page.Desc,
+		Value: field.value(last),
+		ID:    id(last),
+	})
+	return items, nextCursor, nil
+}
+
+// compareKeys compares two sort values of the same type
+func compareKeys(a, b any) int {
+	switch a := a.(type) {
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	case uint64:
+		return cmp.Compare(a, b.(uint64))
+	case float32:
+		return cmp.Compare(a, b.(float32))
+	}
+	panic(fmt.Sprintf("unsupported sort value %T", a))
+}
+
+// containsPattern builds an ILIKE pattern matching values that contain s
+func containsPattern(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(s) + "%"
+}
@@ -2,40 +2,98 @@ package repository
 
 import (
 	"context"
+	"time"
 
+	"go_api/internal/app/dto"
 	"go_api/internal/app/model"
+	"go_api/internal/storage"
 
 	"gorm.io/gorm"
 )
 
-type UserRepository struct {
+// UserRepository stores users. Missing users are reported as gorm.ErrRecordNotFound, and
+// a taken username or email as gorm.ErrDuplicatedKey.
+type UserRepository interface {
+	Create(ctx context.Context, user *model.User) error
+	FindByID(ctx context.Context, id uint) (*model.User, error)
+	FindByEmail(ctx context.Context, email string) (*model.User, error)
+	ListAllUsers(ctx context.Context, filter dto.UserFilter, page dto.PageQuery) ([]model.User, string, error)
+	UpdateRole(ctx context.Context, id uint, role model.Role) error
+	UpdatePermissions(ctx context.Context, id uint, permissions []model.Permission) error
+}
+
+// GormUserRepository stores users in Postgres
+type GormUserRepository struct {
 	db *gorm.DB
 }
 
-func NewUserRepository(db *gorm.DB) *UserRepository {
-	return &UserRepository{
+func NewUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{
 		db: db,
 	}
 }
 
-func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+func (r *GormUserRepository) Create(ctx context.Context, user *model.User) error {
 	return r.db.WithContext(ctx).Create(user).Error
 }
 
-func (r *UserRepository) FindByID(ctx context.Context, id uint) (*model.User, error) {
+// FindByID reads a user from a replica, unless ctx reads from the primary
+func (r *GormUserRepository) FindByID(ctx context.Context, id uint) (*model.User, error) {
 	var user model.User
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error
+	err := r.db.WithContext(storage.ReadFromReplica(ctx)).Where("id = ?", id).First(&user).Error
 	return &user, err
 }
 
-func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+func (r *GormUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
 	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
 	return &user, err
 }
 
-func (r *UserRepository) ListAllUsers(ctx context.Context) ([]model.User, error) {
-	var users []model.User
-	err := r.db.WithContext(ctx).Find(&users).Error
-	return users, err
+var userSortFields = map[string]sortField[model.User]{
+	"id":         idSortField("users.id", func(u *model.User) uint { return u.ID }),
+	"created_at": timeSortField("users.created_at", func(u *model.User) time.Time { return u.CreatedAt }),
+	"username":   stringSortField("users.username", func(u *model.User) string { return u.Username }),
+}
+
+// ListAllUsers returns one page of users matching the filter and the cursor of the next page, read from a replica
+func (r *GormUserRepository) ListAllUsers(ctx context.Context, filter dto.UserFilter, page dto.PageQuery) ([]model.User, string, error) {
+	query := r.db.WithContext(storage.ReadFromReplica(ctx)).Model(&model.User{})
+	if filter.Role != "" {
+		query = query.Where("users.role = ?", filter.Role)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("users.created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("users.created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.Username != "" {
+		query = query.Where("users.username ILIKE ?", containsPattern(filter.Username))
+	}
+
+	return paginate(query, page, userSortFields, "users.id", func(u *model.User) uint { return u.ID })
+}
+
+func (r *GormUserRepository) UpdateRole(ctx context.Context, id uint, role model.Role) error {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *GormUserRepository) UpdatePermissions(ctx context.Context, id uint, permissions []model.Permission) error {
+	// Updating through the model applies the JSON serializer of the column
+	result := r.db.WithContext(ctx).Model(&model.User{ID: id}).Select("permissions").Updates(&model.User{Permissions: permissions})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
@@ -1,15 +1,26 @@
 package route
 
 import (
-	"net/http"
-
 	"go_api/internal/app/handler"
+	"go_api/internal/app/model"
 	"go_api/internal/middleware"
 )
 
-func SetupBlogRoute(mux *http.ServeMux, blogHandler *handler.BlogHandler) {
-	mux.Handle("POST /blogs/", middleware.AuthMiddleware(blogHandler.CreateBlogHandler()))
-	mux.Handle("GET /blogs/{id}", blogHandler.GetBlogHandler())
-	mux.Handle("DELETE /blogs/{id}", middleware.AuthMiddleware(blogHandler.DeleteBlogHandler()))
-	mux.Handle("GET /blogs/", blogHandler.ListBlogsHandler())
+func SetupBlogRoute(router Router, blogHandler *handler.BlogHandler, auth *middleware.Authenticator) {
+	writeBlogs := middleware.RequirePermission(model.PermWriteBlogs)
+
+	router.Handle("POST /blogs/", auth.Required(writeBlogs(blogHandler.CreateBlogHandler())))
+	router.Handle("GET /blogs/search", auth.Optional(blogHandler.SearchBlogsHandler()))
+	router.Handle("GET /blogs/{id}", auth.Optional(blogHandler.GetBlogHandler()))
+	router.Handle("PUT /blogs/{id}", auth.Required(writeBlogs(blogHandler.UpdateBlogHandler())))
+	router.Handle("PATCH /blogs/{id}", auth.Required(writeBlogs(blogHandler.PatchBlogHandler())))
+	router.Handle("DELETE /blogs/{id}", auth.Required(writeBlogs(blogHandler.DeleteBlogHandler())))
+	router.Handle("POST /blogs/{id}/publish", auth.Required(writeBlogs(blogHandler.PublishBlogHandler())))
+	router.Handle("POST /blogs/{id}/unpublish", auth.Required(writeBlogs(blogHandler.UnpublishBlogHandler())))
+	router.Handle("POST /blogs/{id}/archive", auth.Required(writeBlogs(blogHandler.ArchiveBlogHandler())))
+	router.Handle("GET /blogs/{id}/revisions", auth.Required(blogHandler.ListRevisionsHandler()))
+	router.Handle("GET /blogs/{id}/revisions/{rev}", auth.Required(blogHandler.GetRevisionHandler()))
+	router.Handle("GET /blogs/{id}/revisions/{rev}/diff", auth.Required(blogHandler.DiffRevisionHandler()))
+	router.Handle("POST /blogs/{id}/revisions/{rev}/restore", auth.Required(writeBlogs(blogHandler.RestoreRevisionHandler())))
+	router.Handle("GET /blogs/", auth.Optional(blogHandler.ListBlogsHandler()))
 }
@@ -0,0 +1,17 @@
+package route
+
+import (
+	"go_api/internal/app/handler"
+	"go_api/internal/app/model"
+	"go_api/internal/middleware"
+)
+
+func SetupCommentRoute(router Router, commentHandler *handler.CommentHandler, auth *middleware.Authenticator) {
+	writeComments := middleware.RequirePermission(model.PermWriteComments)
+
+	router.Handle("GET /blogs/{id}/comments", auth.Optional(commentHandler.ListCommentsHandler()))
+	router.Handle("GET /blogs/{id}/comments/{commentId}/replies", auth.Optional(commentHandler.ListRepliesHandler()))
+	router.Handle("POST /blogs/{id}/comments", auth.Required(writeComments(commentHandler.CreateCommentHandler())))
+	router.Handle("PUT /blogs/{id}/comments/{commentId}", auth.Required(writeComments(commentHandler.UpdateCommentHandler())))
+	router.Handle("DELETE /blogs/{id}/comments/{commentId}", auth.Required(writeComments(commentHandler.DeleteCommentHandler())))
+}
@@ -0,0 +1,9 @@
+package route
+
+import "go_api/internal/app/handler"
+
+func SetupDocsRoute(router Router, docsHandler *handler.DocsHandler) {
+	router.Handle("GET /openapi.json", docsHandler.OpenAPIHandler())
+	router.Handle("GET /docs", docsHandler.DocsUIHandler())
+	router.Handle("GET /docs/{file}", docsHandler.DocsAssetHandler())
+}
@@ -1,11 +1,11 @@
 package route
 
 import (
-	"net/http"
-
 	"go_api/internal/app/handler"
 )
 
-func SetupHealthRoute(mux *http.ServeMux, handler *handler.Handler) {
-	mux.HandleFunc("/health", handler.HealthHandler())
+func SetupHealthRoute(router Router, handler *handler.Handler) {
+	router.Handle("/health", handler.HealthHandler())
+	router.Handle("GET /health/live", handler.LivenessHandler())
+	router.Handle("GET /health/ready", handler.ReadinessHandler())
 }
@@ -0,0 +1,7 @@
+package route
+
+import "go_api/internal/metrics"
+
+func SetupMetricsRoute(router Router, m *metrics.Metrics) {
+	router.Handle("GET /metrics", m.Handler())
+}
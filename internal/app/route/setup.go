@@ -4,42 +4,74 @@ import (
 	"net/http"
 
 	"go_api/internal/app/handler"
-	"go_api/internal/app/service"
+	"go_api/internal/config"
+	"go_api/internal/metrics"
 	"go_api/internal/middleware"
-
-	"github.com/redis/go-redis/v9"
-	"gorm.io/gorm"
 )
 
-func SetupRoutes(mux *http.ServeMux, db *gorm.DB, redis *redis.Client) http.Handler {
+// maxBodyBytes is the largest request body read, well above a blog of the most content allowed
+const maxBodyBytes = 1 << 20
 
-	// Create services
-	blogService := service.NewBlogService(db)
-	userService := service.NewUserService(db, redis)
+// Router registers handlers for http.ServeMux patterns. *http.ServeMux implements it.
+type Router interface {
+	Handle(pattern string, handler http.Handler)
+}
 
-	// Create handlers
-	blogHandler := handler.NewBlogHandler(blogService)
-	userHandler := handler.NewUserHandler(userService)
-	handler := handler.NewHandler()
+// Handlers are the handlers and middleware the routes are served by
+type Handlers struct {
+	Health  *handler.Handler
+	User    *handler.UserHandler
+	Blog    *handler.BlogHandler
+	Comment *handler.CommentHandler
+	Docs    *handler.DocsHandler
 
-	// Register routes
-	SetupHealthRoute(mux, handler)
-	SetupUserRoute(mux, userHandler)
-	SetupBlogRoute(mux, blogHandler)
+	Auth        *middleware.Authenticator
+	RateLimiter *middleware.RateLimiter
+	CORS        *middleware.CORS // Created on mux, to answer preflights with its routes
+	Metrics     *metrics.Metrics
+}
+
+// SetupRoutes registers every route on mux and wraps it with the middleware chain
+func SetupRoutes(mux *http.ServeMux, handlers Handlers, cfg *config.Config) http.Handler {
+	RegisterRoutes(mux, handlers)
 
 	// Create middleware chain
 	middlewares := []func(http.Handler) http.Handler{
+		middleware.RequestIDMiddleware,
 		middleware.LoggerMiddleware,
-		middleware.RecoveryMiddleware,
-		middleware.RateLimiterMiddleware,
-		middleware.CorsMiddleware,
+		middleware.RecoveryMiddleware(cfg.IsDevelopment()),
+		handlers.CORS.Middleware,
+		handlers.RateLimiter.Middleware,
+		middleware.BodyLimitMiddleware(maxBodyBytes),
 	}
 
-	// Wrap the mux with all middlewares in order
-	var wrappedHandler http.Handler = mux
+	// Wrap the mux with all middlewares in order. Tracing and metrics wrap the mux directly to read the route pattern.
+	wrappedHandler := middleware.TracingMiddleware(middleware.MetricsMiddleware(handlers.Metrics)(mux))
 	for i := len(middlewares) - 1; i >= 0; i-- {
 		wrappedHandler = middlewares[i](wrappedHandler)
 	}
 
 	return wrappedHandler
 }
+
+// RegisterRoutes registers every route of the API on router, each with its rate limit policy
+func RegisterRoutes(router Router, handlers Handlers) {
+	router = limitedRouter{router: router, limiter: handlers.RateLimiter}
+	SetupHealthRoute(router, handlers.Health)
+	SetupUserRoute(router, handlers.User, handlers.Auth)
+	SetupBlogRoute(router, handlers.Blog, handlers.Auth)
+	SetupCommentRoute(router, handlers.Comment, handlers.Auth)
+	SetupTagRoute(router, handlers.Blog, handlers.Auth)
+	SetupDocsRoute(router, handlers.Docs)
+	SetupMetricsRoute(router, handlers.Metrics)
+}
+
+// limitedRouter wraps every route with the rate limit policy configured for its pattern
+type limitedRouter struct {
+	router  Router
+	limiter *middleware.RateLimiter
+}
+
+func (r limitedRouter) Handle(pattern string, handler http.Handler) {
+	r.router.Handle(pattern, r.limiter.RouteMiddleware(pattern, handler))
+}
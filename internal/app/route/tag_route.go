@@ -0,0 +1,11 @@
+package route
+
+import (
+	"go_api/internal/app/handler"
+	"go_api/internal/middleware"
+)
+
+func SetupTagRoute(router Router, blogHandler *handler.BlogHandler, auth *middleware.Authenticator) {
+	router.Handle("GET /tags", blogHandler.ListTagsHandler())
+	router.Handle("GET /tags/{tag}/blogs", auth.Optional(blogHandler.ListBlogsByTagHandler()))
+}
@@ -1,20 +1,25 @@
 package route
 
 import (
-	"net/http"
-
 	"go_api/internal/app/handler"
+	"go_api/internal/app/model"
 	"go_api/internal/middleware"
 )
 
-func SetupUserRoute(mux *http.ServeMux, userHandler *handler.UserHandler) {
-	userMux := http.NewServeMux()
-
-	userMux.HandleFunc("POST /register", userHandler.CreateUserHandler())
-	userMux.HandleFunc("POST /login", userHandler.LoginUserHandler())
-	userMux.Handle("GET /profile", middleware.AuthMiddleware(userHandler.UserProfileHandler()))
-	userMux.Handle("POST /logout", middleware.AuthMiddleware(userHandler.LogoutUserHandler()))
-	userMux.Handle("GET /", middleware.AuthMiddleware(userHandler.ListAllUsersHandler()))
-
-	mux.Handle("/users/", http.StripPrefix("/users", userMux))
+func SetupUserRoute(router Router, userHandler *handler.UserHandler, auth *middleware.Authenticator) {
+	router.Handle("POST /users/register", userHandler.CreateUserHandler())
+	router.Handle("POST /users/login", userHandler.LoginUserHandler())
+	router.Handle("POST /users/refresh", userHandler.RefreshTokenHandler())
+	router.Handle("GET /users/profile", auth.Required(userHandler.UserProfileHandler()))
+	router.Handle("POST /users/logout", auth.Required(userHandler.LogoutUserHandler()))
+	router.Handle("POST /users/logout-all", auth.Required(userHandler.LogoutAllSessionsHandler()))
+	router.Handle("GET /users/", auth.Required(
+		middleware.RequirePermission(model.PermReadUsers)(userHandler.ListAllUsersHandler()),
+	))
+	router.Handle("PATCH /users/{id}/role", auth.Required(
+		middleware.RequirePermission(model.PermManageUsers)(userHandler.UpdateUserRoleHandler()),
+	))
+	router.Handle("PUT /users/{id}/permissions", auth.Required(
+		middleware.RequirePermission(model.PermManageUsers)(userHandler.UpdateUserPermissionsHandler()),
+	))
 }
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"go_api/internal/app/dto"
+	"go_api/internal/app/model"
+	"go_api/internal/util"
+)
+
+var (
+	ErrRevisionNotFound     = errors.New("revision not found")
+	ErrRevisionListFailed   = errors.New("failed to list revisions")
+	ErrRevisionDiffTooLarge = errors.New("revision and current content are too large to diff")
+)
+
+// ListRevisions retrieves one page of a blog's previous versions and the cursor of the next page.
+// Revisions are only visible to those who can edit the blog.
+func (s *BlogService) ListRevisions(ctx context.Context, id string, page dto.PageQuery, userID uint, manageAny bool) ([]model.BlogRevision, string, error) {
+	blog, err := s.editableBlog(ctx, id, util.IfMatch{}, userID, manageAny)
+	if err != nil {
+		return nil, "", err
+	}
+
+	revisions, nextCursor, err := s.repo.ListRevisions(ctx, blog.ID, page)
+	if err != nil {
+		return nil, "", listError(err, ErrRevisionListFailed)
+	}
+	return revisions, nextCursor, nil
+}
+
+// GetRevision retrieves a previous version of a blog
+func (s *BlogService) GetRevision(ctx context.Context, id string, version uint, userID uint, manageAny bool) (*model.BlogRevision, error) {
+	blog, err := s.editableBlog(ctx, id, util.IfMatch{}, userID, manageAny)
+	if err != nil {
+		return nil, err
+	}
+	return s.revision(ctx, blog, version)
+}
+
+// DiffRevision returns the line diff from a previous version of a blog to its current content
+func (s *BlogService) DiffRevision(ctx context.Context, id string, version uint, userID uint, manageAny bool) (*dto.BlogRevisionDiff, error) {
+	blog, err := s.editableBlog(ctx, id, util.IfMatch{}, userID, manageAny)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := s.revision(ctx, blog, version)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := util.DiffLines(revision.Content, blog.Content)
+	if err != nil {
+		return nil, ErrRevisionDiffTooLarge
+	}
+
+	return &dto.BlogRevisionDiff{
+		BlogID:      blog.ID,
+		FromVersion: revision.Version,
+		ToVersion:   blog.Version,
+		FromTitle:   revision.Title,
+		ToTitle:     blog.Title,
+		Lines:       lines,
+	}, nil
+}
+
+// RestoreRevision brings back the title and content of a previous version as a new version.
+// Ownership and versioning follow UpdateBlog.
+func (s *BlogService) RestoreRevision(ctx context.Context, id string, version uint, ifMatch util.IfMatch, userID uint, manageAny bool) (*model.Blog, error) {
+	blog, err := s.editableBlog(ctx, id, ifMatch, userID, manageAny)
+	if err != nil {
+		return blog, err
+	}
+	previous := *blog
+
+	revision, err := s.revision(ctx, blog, version)
+	if err != nil {
+		return nil, err
+	}
+
+	blog.Title = revision.Title
+	blog.Content = revision.Content
+	return s.saveBlog(ctx, &previous, blog, userID, nil)
+}
+
+func (s *BlogService) revision(ctx context.Context, blog *model.Blog, version uint) (*model.BlogRevision, error) {
+	revision, err := s.repo.GetRevision(ctx, blog.ID, version)
+	if err != nil {
+		return nil, ErrRevisionNotFound
+	}
+	return revision, nil
+}
@@ -2,13 +2,16 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 
 	"go_api/internal/app/dto"
 	"go_api/internal/app/model"
 	"go_api/internal/app/repository"
-
-	"gorm.io/gorm"
+	"go_api/internal/storage"
+	"go_api/internal/util"
 )
 
 var (
@@ -16,55 +19,304 @@ var (
 	ErrBlogCreation   = errors.New("failed to create blog")
 	ErrBlogDeletion   = errors.New("failed to delete blog")
 	ErrBlogListFailed = errors.New("failed to list blogs")
+
+	ErrBlogSearchFailed    = errors.New("failed to search blogs")
+	ErrInvalidSearchQuery  = errors.New("search query has no searchable words")
+	ErrBlogUpdate          = errors.New("failed to update blog")
+	ErrBlogForbidden       = errors.New("not allowed to modify blog")
+	ErrInvalidBlogUpdate   = errors.New("invalid blog update")
+	ErrBlogVersionConflict = errors.New("blog was modified by someone else")
+	ErrInvalidPublishTime  = errors.New("scheduled publication time must be in the future")
+	ErrTagListFailed       = errors.New("failed to list tags")
 )
 
 type BlogService struct {
-	repo *repository.BlogRepository
+	repo repository.BlogRepository
 }
 
-func NewBlogService(db *gorm.DB) *BlogService {
+func NewBlogService(repo repository.BlogRepository) *BlogService {
 	return &BlogService{
-		repo: repository.NewBlogRepository(db),
+		repo: repo,
 	}
 }
 
-// CreateBlog creates a new blog post
+// CreateBlog creates a new blog post, published unless another status is requested
 func (s *BlogService) CreateBlog(ctx context.Context, req dto.CreateBlogRequest, userID uint) (*model.Blog, error) {
 	blog := &model.Blog{
 		Title:   req.Title,
 		Content: req.Content,
 		UserID:  userID,
+		Status:  model.BlogStatusPublished,
 	}
 
-	if err := s.repo.CreateBlog(ctx, blog); err != nil {
+	now := time.Now()
+	switch model.BlogStatus(req.Status) {
+	case model.BlogStatusDraft:
+		blog.Status = model.BlogStatusDraft
+	case model.BlogStatusScheduled:
+		if req.PublishAt == nil || !req.PublishAt.After(now) {
+			return nil, ErrInvalidPublishTime
+		}
+		blog.Status = model.BlogStatusScheduled
+		blog.PublishedAt = req.PublishAt
+	default:
+		blog.PublishedAt = &now
+	}
+
+	err := s.repo.Transaction(ctx, func(repo repository.BlogRepository) error {
+		if err := repo.CreateBlog(ctx, blog); err != nil {
+			return err
+		}
+		tags, err := repo.SetTags(ctx, blog.ID, util.NormalizeTags(req.Tags))
+		blog.Tags = tags
+		return err
+	})
+	if err != nil {
 		return nil, ErrBlogCreation
 	}
 
 	return blog, nil
 }
 
-// GetBlog retrieves a blog by its ID
-func (s *BlogService) GetBlog(ctx context.Context, id string) (*model.Blog, error) {
+// GetBlog retrieves a blog by its ID. Blogs that are not published are only visible
+// to their owner (viewerID), or to everyone if viewAll is set.
+func (s *BlogService) GetBlog(ctx context.Context, id string, viewerID uint, viewAll bool) (*model.Blog, error) {
 	blog, err := s.repo.GetBlog(ctx, id)
 	if err != nil {
 		return nil, ErrBlogNotFound
 	}
+	if !blog.VisibleTo(viewerID, viewAll) {
+		return nil, ErrBlogNotFound
+	}
 	return blog, nil
 }
 
-// DeleteBlog deletes a blog by its ID (only if owned by user)
-func (s *BlogService) DeleteBlog(ctx context.Context, id string, userID uint) error {
-	if err := s.repo.DeleteBlog(ctx, id, userID); err != nil {
+// UpdateBlog replaces the title and content of a blog, and its tags if given (only if owned by user,
+// unless manageAny is set). The zero IfMatch skips the version check. On a version conflict the current blog is returned
+// together with ErrBlogVersionConflict.
+func (s *BlogService) UpdateBlog(ctx context.Context, id string, req dto.UpdateBlogRequest, ifMatch util.IfMatch, userID uint, manageAny bool) (*model.Blog, error) {
+	blog, err := s.editableBlog(ctx, id, ifMatch, userID, manageAny)
+	if err != nil {
+		return blog, err
+	}
+	previous := *blog
+
+	blog.Title = req.Title
+	blog.Content = req.Content
+	return s.saveBlog(ctx, &previous, blog, userID, req.Tags)
+}
+
+// PatchBlog applies a JSON Merge Patch to the title, content and tags of a blog.
+// Ownership and versioning follow UpdateBlog.
+func (s *BlogService) PatchBlog(ctx context.Context, id string, patch []byte, ifMatch util.IfMatch, userID uint, manageAny bool) (*model.Blog, error) {
+	blog, err := s.editableBlog(ctx, id, ifMatch, userID, manageAny)
+	if err != nil {
+		return blog, err
+	}
+	previous := *blog
+
+	tags := model.TagNames(blog.Tags)
+	current, err := json.Marshal(dto.UpdateBlogRequest{Title: blog.Title, Content: blog.Content, Tags: &tags})
+	if err != nil {
+		return nil, ErrBlogUpdate
+	}
+	patched, err := util.MergePatch(current, patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBlogUpdate, err)
+	}
+
+	var req dto.UpdateBlogRequest
+	if err := json.Unmarshal(patched, &req); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBlogUpdate, err)
+	}
+	if err := util.Validate(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBlogUpdate, err)
+	}
+	if req.Tags == nil {
+		// The patch removed the tags member
+		req.Tags = &[]string{}
+	}
+
+	blog.Title = req.Title
+	blog.Content = req.Content
+	return s.saveBlog(ctx, &previous, blog, userID, req.Tags)
+}
+
+// PublishBlog publishes a blog now, or schedules it if the requested time is in the future.
+// Ownership and versioning follow UpdateBlog.
+func (s *BlogService) PublishBlog(ctx context.Context, id string, req dto.PublishBlogRequest, ifMatch util.IfMatch, userID uint, manageAny bool) (*model.Blog, error) {
+	blog, err := s.editableBlog(ctx, id, ifMatch, userID, manageAny)
+	if err != nil {
+		return blog, err
+	}
+	previous := *blog
+
+	now := time.Now()
+	switch {
+	case req.PublishAt != nil && req.PublishAt.After(now):
+		blog.Status = model.BlogStatusScheduled
+		blog.PublishedAt = req.PublishAt
+	case blog.Status == model.BlogStatusPublished:
+		// Already published, keep the original publication time
+		return blog, nil
+	case blog.Status == model.BlogStatusArchived && blog.PublishedAt != nil:
+		// Restoring an archived blog keeps its original publication time
+		blog.Status = model.BlogStatusPublished
+	default:
+		blog.Status = model.BlogStatusPublished
+		blog.PublishedAt = &now
+	}
+	return s.saveBlog(ctx, &previous, blog, userID, nil)
+}
+
+// UnpublishBlog turns a published or scheduled blog back into a draft.
+// Ownership and versioning follow UpdateBlog.
+func (s *BlogService) UnpublishBlog(ctx context.Context, id string, ifMatch util.IfMatch, userID uint, manageAny bool) (*model.Blog, error) {
+	blog, err := s.editableBlog(ctx, id, ifMatch, userID, manageAny)
+	if err != nil {
+		return blog, err
+	}
+	previous := *blog
+
+	blog.Status = model.BlogStatusDraft
+	blog.PublishedAt = nil
+	return s.saveBlog(ctx, &previous, blog, userID, nil)
+}
+
+// ArchiveBlog hides a blog from other users while keeping its publication time.
+// Ownership and versioning follow UpdateBlog.
+func (s *BlogService) ArchiveBlog(ctx context.Context, id string, ifMatch util.IfMatch, userID uint, manageAny bool) (*model.Blog, error) {
+	blog, err := s.editableBlog(ctx, id, ifMatch, userID, manageAny)
+	if err != nil {
+		return blog, err
+	}
+	previous := *blog
+
+	blog.Status = model.BlogStatusArchived
+	return s.saveBlog(ctx, &previous, blog, userID, nil)
+}
+
+// PublishDueBlogs publishes every scheduled blog whose publication time has passed,
+// storing their scheduled versions as revisions in the same transaction
+func (s *BlogService) PublishDueBlogs(ctx context.Context) (int64, error) {
+	var published int64
+	err := s.repo.Transaction(ctx, func(repo repository.BlogRepository) error {
+		now := time.Now()
+		blogs, err := repo.LockDueBlogs(ctx, now)
+		if err != nil || len(blogs) == 0 {
+			return err
+		}
+
+		ids := make([]uint, 0, len(blogs))
+		for i := range blogs {
+			if err := repo.CreateRevision(ctx, model.NewBlogRevision(&blogs[i], nil)); err != nil {
+				return err
+			}
+			ids = append(ids, blogs[i].ID)
+		}
+		if err := repo.MarkPublished(ctx, ids, now); err != nil {
+			return err
+		}
+
+		published = int64(len(ids))
+		return nil
+	})
+	return published, err
+}
+
+// editableBlog loads a blog and checks that the user may edit it at the expected version
+func (s *BlogService) editableBlog(ctx context.Context, id string, ifMatch util.IfMatch, userID uint, manageAny bool) (*model.Blog, error) {
+	// The version check needs the latest write, replicas may lag behind
+	blog, err := s.repo.GetBlog(storage.ReadFromPrimary(ctx), id)
+	if err != nil {
+		return nil, ErrBlogNotFound
+	}
+	if !manageAny && blog.UserID != userID {
+		// Blogs hidden from the user are reported as missing, not forbidden
+		if !blog.IsPublic() {
+			return nil, ErrBlogNotFound
+		}
+		return nil, ErrBlogForbidden
+	}
+	if !ifMatch.Matches(blog.Version) {
+		return blog, ErrBlogVersionConflict
+	}
+	return blog, nil
+}
+
+// saveBlog writes the update and stores the previous version as a revision in the same transaction,
+// reporting a conflict if the blog changed since it was loaded. The tags are replaced unless tags is nil.
+func (s *BlogService) saveBlog(ctx context.Context, previous *model.Blog, blog *model.Blog, userID uint, tags *[]string) (*model.Blog, error) {
+	err := s.repo.Transaction(ctx, func(repo repository.BlogRepository) error {
+		// The update only succeeds at the loaded version, so previous is exactly what it replaces
+		if err := repo.UpdateBlog(ctx, blog); err != nil {
+			return err
+		}
+		if tags != nil {
+			updated, err := repo.SetTags(ctx, blog.ID, util.NormalizeTags(*tags))
+			if err != nil {
+				return err
+			}
+			blog.Tags = updated
+		}
+		return repo.CreateRevision(ctx, model.NewBlogRevision(previous, &userID))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			current, err := s.repo.GetBlog(storage.ReadFromPrimary(ctx), fmt.Sprint(blog.ID))
+			if err != nil {
+				return nil, ErrBlogNotFound
+			}
+			return current, ErrBlogVersionConflict
+		}
+		return nil, ErrBlogUpdate
+	}
+	return blog, nil
+}
+
+// DeleteBlog deletes a blog by its ID (only if owned by user, unless manageAny is set)
+func (s *BlogService) DeleteBlog(ctx context.Context, id string, userID uint, manageAny bool) error {
+	var err error
+	if manageAny {
+		err = s.repo.DeleteAnyBlog(ctx, id)
+	} else {
+		err = s.repo.DeleteBlog(ctx, id, userID)
+	}
+	if err != nil {
 		return ErrBlogDeletion
 	}
 	return nil
 }
 
-// ListBlogs retrieves all blogs
-func (s *BlogService) ListBlogs(ctx context.Context) ([]model.Blog, error) {
-	blogs, err := s.repo.ListBlogs(ctx)
+// ListBlogs retrieves one page of blogs and the cursor of the next page
+func (s *BlogService) ListBlogs(ctx context.Context, filter dto.BlogFilter, page dto.PageQuery) ([]model.Blog, string, error) {
+	blogs, nextCursor, err := s.repo.ListBlogs(ctx, filter, page)
+	if err != nil {
+		return nil, "", listError(err, ErrBlogListFailed)
+	}
+	return blogs, nextCursor, nil
+}
+
+// SearchBlogs runs a full-text search over blog titles and content and returns one page of
+// ranked results and the cursor of the next page
+func (s *BlogService) SearchBlogs(ctx context.Context, q string, filter dto.BlogFilter, page dto.PageQuery) ([]model.BlogSearchResult, string, error) {
+	tsQuery := util.ParseSearchQuery(q)
+	if tsQuery == "" {
+		return nil, "", ErrInvalidSearchQuery
+	}
+
+	results, nextCursor, err := s.repo.SearchBlogs(ctx, tsQuery, filter, page)
+	if err != nil {
+		return nil, "", listError(err, ErrBlogSearchFailed)
+	}
+	return results, nextCursor, nil
+}
+
+// ListTags returns the tags used by published blogs with their number of blogs, most used first
+func (s *BlogService) ListTags(ctx context.Context, limit int) ([]model.TagCount, error) {
+	counts, err := s.repo.ListTagCounts(ctx, limit)
 	if err != nil {
-		return nil, ErrBlogListFailed
+		return nil, ErrTagListFailed
 	}
-	return blogs, nil
+	return counts, nil
 }
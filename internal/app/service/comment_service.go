@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go_api/internal/app/dto"
+	"go_api/internal/app/model"
+	"go_api/internal/app/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCommentNotFound      = errors.New("comment not found")
+	ErrCommentCreation      = errors.New("failed to create comment")
+	ErrCommentUpdate        = errors.New("failed to update comment")
+	ErrCommentDeletion      = errors.New("failed to delete comment")
+	ErrCommentListFailed    = errors.New("failed to list comments")
+	ErrCommentForbidden     = errors.New("not allowed to modify comment")
+	ErrInvalidParentComment = errors.New("parent comment not found or deleted")
+	ErrCommentTooDeep       = errors.New("replies are nested too deeply")
+)
+
+type CommentService struct {
+	repo  repository.CommentRepository
+	blogs repository.BlogRepository // Finds the blogs commented on
+}
+
+func NewCommentService(repo repository.CommentRepository, blogs repository.BlogRepository) *CommentService {
+	return &CommentService{
+		repo:  repo,
+		blogs: blogs,
+	}
+}
+
+// CreateComment adds a comment to a blog, as a reply if req.ParentID is set
+func (s *CommentService) CreateComment(ctx context.Context, blogID string, req dto.CreateCommentRequest, userID uint, viewAll bool) (*model.Comment, error) {
+	blog, err := s.visibleBlog(ctx, blogID, userID, viewAll)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &model.Comment{
+		BlogID:  blog.ID,
+		UserID:  userID,
+		Content: req.Content,
+	}
+
+	if req.ParentID != nil {
+		parent, err := s.repo.GetComment(ctx, blog.ID, fmt.Sprint(*req.ParentID))
+		if err != nil || parent.IsDeleted() {
+			return nil, ErrInvalidParentComment
+		}
+		if parent.Depth+1 > model.MaxCommentDepth {
+			return nil, ErrCommentTooDeep
+		}
+
+		rootID := parent.ID
+		if parent.RootID != nil {
+			rootID = *parent.RootID
+		}
+		comment.ParentID = &parent.ID
+		comment.RootID = &rootID
+		comment.Depth = parent.Depth + 1
+	}
+
+	if err := s.repo.CreateComment(ctx, comment); err != nil {
+		return nil, ErrCommentCreation
+	}
+	return comment, nil
+}
+
+// ListComments retrieves one page of a blog's top-level comments, each with the first
+// model.MaxThreadReplies replies of its thread nested, and the cursor of the next page
+func (s *CommentService) ListComments(ctx context.Context, blogID string, page dto.PageQuery, viewerID uint, viewAll bool) ([]*model.CommentNode, string, error) {
+	blog, err := s.visibleBlog(ctx, blogID, viewerID, viewAll)
+	if err != nil {
+		return nil, "", err
+	}
+
+	roots, nextCursor, err := s.repo.ListRootComments(ctx, blog.ID, page)
+	if err != nil {
+		return nil, "", listError(err, ErrCommentListFailed)
+	}
+
+	rootIDs := make([]uint, 0, len(roots))
+	for _, root := range roots {
+		rootIDs = append(rootIDs, root.ID)
+	}
+	// One extra reply per thread tells whether replies are left out
+	replies, err := s.repo.ListReplies(ctx, rootIDs, model.MaxThreadReplies+1)
+	if err != nil {
+		return nil, "", ErrCommentListFailed
+	}
+	replies, truncated := model.LimitThreadReplies(replies, model.MaxThreadReplies)
+
+	return model.BuildCommentTree(roots, replies, truncated), nextCursor, nil
+}
+
+// ListReplies retrieves one page of the replies in the thread of a top-level comment, unnested,
+// and the cursor of the next page
+func (s *CommentService) ListReplies(ctx context.Context, blogID string, id string, page dto.PageQuery, viewerID uint, viewAll bool) ([]model.Comment, string, error) {
+	blog, err := s.visibleBlog(ctx, blogID, viewerID, viewAll)
+	if err != nil {
+		return nil, "", err
+	}
+
+	root, err := s.repo.GetComment(ctx, blog.ID, id)
+	if err != nil || root.RootID != nil {
+		return nil, "", ErrCommentNotFound
+	}
+
+	replies, nextCursor, err := s.repo.ListThreadReplies(ctx, root.ID, page)
+	if err != nil {
+		return nil, "", listError(err, ErrCommentListFailed)
+	}
+	return replies, nextCursor, nil
+}
+
+// UpdateComment changes the content of a comment (only by its author)
+func (s *CommentService) UpdateComment(ctx context.Context, blogID string, id string, req dto.UpdateCommentRequest, userID uint, viewAll bool) (*model.Comment, error) {
+	blog, err := s.visibleBlog(ctx, blogID, userID, viewAll)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.repo.GetComment(ctx, blog.ID, id)
+	if err != nil || comment.IsDeleted() {
+		return nil, ErrCommentNotFound
+	}
+	if comment.UserID != userID {
+		return nil, ErrCommentForbidden
+	}
+
+	comment.Content = req.Content
+	if err := s.repo.UpdateContent(ctx, comment); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, ErrCommentUpdate
+	}
+	return comment, nil
+}
+
+// DeleteComment soft-deletes a comment (by its author, the blog owner, or anyone if manageAny is set).
+// The comment stays as a placeholder so its replies keep their place in the thread.
+func (s *CommentService) DeleteComment(ctx context.Context, blogID string, id string, userID uint, viewAll bool, manageAny bool) error {
+	blog, err := s.visibleBlog(ctx, blogID, userID, viewAll)
+	if err != nil {
+		return err
+	}
+
+	comment, err := s.repo.GetComment(ctx, blog.ID, id)
+	if err != nil || comment.IsDeleted() {
+		return ErrCommentNotFound
+	}
+	if comment.UserID != userID && blog.UserID != userID && !manageAny {
+		return ErrCommentForbidden
+	}
+
+	if err := s.repo.SoftDelete(ctx, comment.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCommentNotFound
+		}
+		return ErrCommentDeletion
+	}
+	return nil
+}
+
+// visibleBlog loads a blog the viewer is allowed to see
+func (s *CommentService) visibleBlog(ctx context.Context, id string, viewerID uint, viewAll bool) (*model.Blog, error) {
+	blog, err := s.blogs.GetBlog(ctx, id)
+	if err != nil || !blog.VisibleTo(viewerID, viewAll) {
+		return nil, ErrBlogNotFound
+	}
+	return blog, nil
+}
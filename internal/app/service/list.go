@@ -0,0 +1,18 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"go_api/internal/app/repository"
+)
+
+var ErrInvalidListQuery = errors.New("invalid list query")
+
+// listError maps a repository list error to a service error, keeping the reason for invalid queries
+func listError(err error, fallback error) error {
+	if errors.Is(err, repository.ErrInvalidPageQuery) {
+		return fmt.Errorf("%w: %v", ErrInvalidListQuery, err)
+	}
+	return fallback
+}
@@ -3,17 +3,17 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
 	"time"
 
 	"go_api/internal/app/cache"
 	"go_api/internal/app/dto"
 	"go_api/internal/app/model"
 	"go_api/internal/app/repository"
-	"go_api/internal/config"
+	"go_api/internal/storage"
 	"go_api/internal/util"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
@@ -26,17 +26,26 @@ var (
 	ErrCacheOperation  = errors.New("cache operation failed")
 	ErrTokenBlacklist  = errors.New("failed to blacklist token")
 	ErrSessionCleanup  = errors.New("failed to clean user session")
+
+	ErrInvalidRole         = errors.New("invalid role")
+	ErrInvalidPermission   = errors.New("invalid permission")
+	ErrUserUpdate          = errors.New("failed to update user")
+	ErrUserList            = errors.New("failed to list users")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReuse   = errors.New("refresh token reuse detected")
 )
 
 type UserService struct {
-	repo  *repository.UserRepository
-	cache *cache.UserCache
+	repo      repository.UserRepository
+	cache     cache.UserCache
+	jwtSecret []byte // Signs the access tokens
 }
 
-func NewUserService(db *gorm.DB, redis *redis.Client) *UserService {
+func NewUserService(repo repository.UserRepository, cache cache.UserCache, jwtSecret []byte) *UserService {
 	return &UserService{
-		repo:  repository.NewUserRepository(db),
-		cache: cache.NewUserCache(redis),
+		repo:      repo,
+		cache:     cache,
+		jwtSecret: jwtSecret,
 	}
 }
 
@@ -73,6 +82,7 @@ func (s *UserService) CreateUser(ctx context.Context, req dto.CreateUserRequest)
 		Username: req.Username,
 		Email:    req.Email,
 		Password: hashedPassword,
+		Role:     model.RoleUser,
 	}
 
 	if err := s.repo.Create(ctx, user); err != nil {
@@ -82,27 +92,118 @@ func (s *UserService) CreateUser(ctx context.Context, req dto.CreateUserRequest)
 	return user, nil
 }
 
-// LoginUser authenticates user and returns a JWT token
-func (s *UserService) LoginUser(ctx context.Context, req dto.LoginUserRequest) (string, error) {
+// LoginUser authenticates user and returns an access token and a refresh token
+func (s *UserService) LoginUser(ctx context.Context, req dto.LoginUserRequest) (*dto.TokenResponse, error) {
 	user, err := s.repo.FindByEmail(ctx, req.Email)
 	if err != nil {
-		return "", ErrUserNotFound
+		return nil, ErrUserNotFound
 	}
 
 	if !util.ComparePassword(req.Password, user.Password) {
-		return "", ErrInvalidPassword
+		return nil, ErrInvalidPassword
+	}
+
+	// Every login starts a new refresh token family
+	familyID, err := util.GenerateOpaqueToken(16)
+	if err != nil {
+		return nil, ErrTokenGeneration
+	}
+
+	refreshToken, err := util.GenerateRefreshToken()
+	if err != nil {
+		return nil, ErrTokenGeneration
+	}
+
+	session := cache.RefreshSession{UserID: user.ID, FamilyID: familyID}
+	if err := s.cache.SaveRefreshToken(ctx, refreshToken, session, util.RefreshTokenTTL); err != nil {
+		return nil, ErrCacheOperation
+	}
+
+	return s.issueTokens(user, refreshToken)
+}
+
+// RefreshToken rotates the refresh token and issues a new access token.
+// Replaying an already rotated refresh token revokes its whole family.
+func (s *UserService) RefreshToken(ctx context.Context, req dto.RefreshTokenRequest) (*dto.TokenResponse, error) {
+	newRefreshToken, err := util.GenerateRefreshToken()
+	if err != nil {
+		return nil, ErrTokenGeneration
+	}
+
+	session, err := s.cache.RotateRefreshToken(ctx, req.RefreshToken, newRefreshToken, util.RefreshTokenTTL)
+	if err != nil {
+		switch {
+		case errors.Is(err, cache.ErrRefreshTokenNotFound):
+			return nil, ErrInvalidRefreshToken
+		case errors.Is(err, cache.ErrRefreshTokenReused):
+			return nil, ErrRefreshTokenReuse
+		default:
+			return nil, ErrCacheOperation
+		}
 	}
 
-	token, err := util.GenerateToken(user.ID, user.Username, []byte(config.GlobalConfig.JWTSecretKey))
+	user, err := s.repo.FindByID(ctx, session.UserID)
 	if err != nil {
-		return "", ErrTokenGeneration
+		s.cache.RevokeRefreshFamily(ctx, session.UserID, session.FamilyID)
+		return nil, ErrUserNotFound
+	}
+
+	return s.issueTokens(user, newRefreshToken)
+}
+
+// issueTokens signs a new access token and pairs it with the given refresh token
+func (s *UserService) issueTokens(user *model.User, refreshToken string) (*dto.TokenResponse, error) {
+	var permissions []string
+	for _, permission := range user.EffectivePermissions() {
+		permissions = append(permissions, string(permission))
+	}
+
+	accessToken, err := util.GenerateToken(user.ID, user.Username, string(user.Role), permissions, s.jwtSecret)
+	if err != nil {
+		return nil, ErrTokenGeneration
+	}
+
+	return &dto.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(util.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// LogoutUser blacklists the access token and revokes the refresh tokens issued from the same login
+// as refreshToken. The other sessions of the user stay logged in.
+func (s *UserService) LogoutUser(ctx context.Context, userID uint, token string, expiresAt *jwt.NumericDate, req dto.LogoutRequest) error {
+	session, err := s.cache.GetRefreshSession(ctx, req.RefreshToken)
+	switch {
+	case errors.Is(err, cache.ErrRefreshTokenNotFound):
+		// Expired or revoked already, only the access token is left
+	case err != nil:
+		return ErrSessionCleanup
+	case session.UserID != userID:
+		return ErrInvalidRefreshToken
+	default:
+		if err := s.cache.RevokeRefreshFamily(ctx, userID, session.FamilyID); err != nil {
+			return ErrSessionCleanup
+		}
+	}
+
+	return s.blacklistToken(ctx, token, expiresAt)
+}
+
+// LogoutAllSessions blacklists the access token and revokes every refresh token of the user. Access tokens
+// of the other sessions stay valid until they expire, within util.AccessTokenTTL.
+func (s *UserService) LogoutAllSessions(ctx context.Context, userID uint, token string, expiresAt *jwt.NumericDate) error {
+	// Clean user session from cache
+	if err := s.cache.CleanUserSession(ctx, userID); err != nil {
+		return ErrSessionCleanup
 	}
 
-	return token, nil
+	return s.blacklistToken(ctx, token, expiresAt)
 }
 
-// LogoutUser blacklists the token and cleans user session
-func (s *UserService) LogoutUser(ctx context.Context, userID uint, token string, expiresAt *jwt.NumericDate) error {
+// blacklistToken revokes an access token until it expires
+func (s *UserService) blacklistToken(ctx context.Context, token string, expiresAt *jwt.NumericDate) error {
 	// Calculate TTL - default to 5 minutes if expiration is not set
 	ttl := time.Minute * 5
 	if expiresAt != nil {
@@ -113,24 +214,78 @@ func (s *UserService) LogoutUser(ctx context.Context, userID uint, token string,
 		}
 	}
 
-	// Blacklist token
-	if err := s.cache.BlacklistToken(ctx, token); err != nil {
+	if err := s.cache.BlacklistToken(ctx, token, ttl); err != nil {
 		return ErrTokenBlacklist
 	}
+	return nil
+}
 
-	// Clean user session from cache
-	if err := s.cache.CleanUserSession(ctx, userID); err != nil {
-		return ErrSessionCleanup
+// ListAllUsers retrieves one page of users from the database and the cursor of the next page
+func (s *UserService) ListAllUsers(ctx context.Context, filter dto.UserFilter, page dto.PageQuery) ([]model.User, string, error) {
+	users, nextCursor, err := s.repo.ListAllUsers(ctx, filter, page)
+	if err != nil {
+		return nil, "", listError(err, ErrUserList)
 	}
+	return users, nextCursor, nil
+}
 
-	return nil
+// UpdateUserRole changes the role of a user. The new role applies from the user's next token refresh.
+func (s *UserService) UpdateUserRole(ctx context.Context, userID uint, req dto.UpdateUserRoleRequest) (*model.User, error) {
+	role := model.Role(req.Role)
+	if !role.IsValid() {
+		return nil, ErrInvalidRole
+	}
+
+	if err := s.repo.UpdateRole(ctx, userID, role); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, ErrUserUpdate
+	}
+
+	user, err := s.repo.FindByID(storage.ReadFromPrimary(ctx), userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	// Refresh the cached profile
+	if err := s.cache.SetUser(ctx, userID, user); err != nil {
+		return nil, ErrCacheOperation
+	}
+
+	return user, nil
 }
 
-// ListAllUsers retrieves all users from the database
-func (s *UserService) ListAllUsers(ctx context.Context) ([]model.User, error) {
-	users, err := s.repo.ListAllUsers(ctx)
+// UpdateUserPermissions replaces the permissions granted to a user on top of the role. They apply
+// from the user's next token refresh.
+func (s *UserService) UpdateUserPermissions(ctx context.Context, userID uint, req dto.UpdateUserPermissionsRequest) (*model.User, error) {
+	var permissions []model.Permission
+	for _, name := range req.Permissions {
+		permission := model.Permission(name)
+		if !permission.IsValid() {
+			return nil, ErrInvalidPermission
+		}
+		if !slices.Contains(permissions, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+
+	if err := s.repo.UpdatePermissions(ctx, userID, permissions); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, ErrUserUpdate
+	}
+
+	user, err := s.repo.FindByID(storage.ReadFromPrimary(ctx), userID)
 	if err != nil {
-		return nil, err
+		return nil, ErrUserNotFound
+	}
+
+	// Refresh the cached profile
+	if err := s.cache.SetUser(ctx, userID, user); err != nil {
+		return nil, ErrCacheOperation
 	}
-	return users, nil
+
+	return user, nil
 }
@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go_api/internal/app/service"
+)
+
+// BlogScheduler periodically publishes scheduled blogs whose publication time has passed
+type BlogScheduler struct {
+	service  *service.BlogService
+	interval time.Duration
+}
+
+func NewBlogScheduler(service *service.BlogService, interval time.Duration) *BlogScheduler {
+	return &BlogScheduler{
+		service:  service,
+		interval: interval,
+	}
+}
+
+// Run publishes due blogs every interval until the context is cancelled.
+// Publishing is a single idempotent update, so several replicas can run it at once.
+func (s *BlogScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.publishDueBlogs(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *BlogScheduler) publishDueBlogs(ctx context.Context) {
+	published, err := s.service.PublishDueBlogs(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Failed to publish scheduled blogs", "error", err)
+		}
+		return
+	}
+	if published > 0 {
+		slog.InfoContext(ctx, "Published scheduled blogs", "count", published)
+	}
+}
@@ -1,72 +1,185 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"strconv"
-
-	"github.com/joho/godotenv"
+	"log/slog"
+	"maps"
+	"net/netip"
+	"slices"
+	"time"
 )
 
+// Config is the configuration of the API server and of the migrate command. See Loader for where it comes from.
 type Config struct {
-	ServerPort        string
-	DatabaseURL       string
-	DatabaseURLPooler string
-	Environment       string
-	LogLevel          string
-	JWTSecretKey      string
-	RedisAddr         string
-	RedisPassword     string
-	RedisDB           string
-	RateLimit         int
+	Environment string // development returns internal error details to clients and logs as text
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Redis       RedisConfig
+	Auth        AuthConfig
+	RateLimit   RateLimitConfig
+	Log         LogConfig
+	Tracing     TracingConfig
+	Scheduler   SchedulerConfig
+	CORS        CORSConfig
+	Health      HealthConfig
 }
 
-var GlobalConfig *Config
+// ServerConfig configures the HTTP server and its graceful shutdown
+type ServerConfig struct {
+	Port            int
+	ShutdownDelay   time.Duration // Time between failing readiness and draining, for load balancers to notice
+	ShutdownTimeout time.Duration // Time allowed for in-flight requests to finish
+}
 
-func LoadConfig() (*Config, error) {
-	if err := godotenv.Load(); err != nil {
-		return nil, fmt.Errorf("error loading file: %v", err)
-	}
+// DatabaseConfig configures the primary database and its read connections
+type DatabaseConfig struct {
+	URL                  string
+	PoolerURL            string        // PgBouncer-compatible read connection, prepared statements are disabled
+	ReplicaURLs          []string      // Extra read connections, used in turn with the pooler
+	ReadYourWritesWindow time.Duration // How long reads of a user go to the primary after they write
+	MigrationMode        string        // apply, check or off, for pending migrations on startup
+}
 
-	databaseURL := getEnv("DATABASE_URL", "")
-	if databaseURL == "" {
-		return nil, fmt.Errorf("DATABASE_URL is required")
-	}
+// RedisConfig configures the Redis connection
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+}
 
-	databaseURLPooler := getEnv("DATABASE_URL_POOLER", "")
-	if databaseURLPooler == "" {
-		return nil, fmt.Errorf("DATABASE_URL_POOLER is required")
-	}
+// AuthConfig configures authentication
+type AuthConfig struct {
+	JWTSecretKey string
+}
+
+// GlobalRateLimitPolicy names the limit of every route, counted apart from the named policies
+const GlobalRateLimitPolicy = "global"
+
+// RateLimitConfig configures the request rate limits
+type RateLimitConfig struct {
+	PerMinute int               // Requests per minute per user, or per IP address for anonymous requests, on every route
+	Policies  map[string]int    // Requests per minute per IP address by policy name
+	Routes    map[string]string // Policy applied by route pattern, like "POST /users/login", on top of PerMinute
 
-	jwtSecretKey := getEnv("JWT_SECRET_KEY", "")
-	if jwtSecretKey == "" {
-		return nil, fmt.Errorf("JWT_SECRET_KEY is required")
+	// TrustedProxies are the proxies whose X-Forwarded-For header gives the client address
+	TrustedProxies []netip.Prefix
+}
+
+// LogConfig configures logging
+type LogConfig struct {
+	Level slog.Level
+}
+
+// TracingConfig configures OpenTelemetry tracing
+type TracingConfig struct {
+	Exporter   string  // none, otlp or stdout
+	SampleRate float64 // Fraction of new traces that are sampled
+}
+
+// SchedulerConfig configures the worker publishing scheduled blogs
+type SchedulerConfig struct {
+	Interval time.Duration
+}
+
+// CORSConfig is the cross-origin policy of the API
+type CORSConfig struct {
+	AllowedOrigins   []string            // Exact origins, "*" or wildcard subdomains like https://*.example.com
+	AllowCredentials bool                // Allows cookies and Authorization headers from the allowed origins
+	ExposedHeaders   []string            // Response headers readable by clients
+	MaxAge           time.Duration       // How long browsers cache preflight responses
+	RouteOrigins     map[string][]string // Allowed origins by path prefix, replacing AllowedOrigins on those paths
+}
+
+// HealthConfig configures the readiness checks
+type HealthConfig struct {
+	Timeout         time.Duration // Time allowed for each dependency check
+	CacheTTL        time.Duration // How long a readiness report is reused, 0 to check on every probe
+	CheckMigrations bool          // Fails readiness while database migrations are pending
+	DiskPath        string        // Path whose filesystem must keep DiskMinFree bytes available, empty to skip
+	DiskMinFree     uint64
+}
+
+// Default returns the configuration used for the settings that are not set. The database URLs
+// and the JWT secret have no default.
+func Default() *Config {
+	return &Config{
+		Environment: "development",
+		Server: ServerConfig{
+			Port:            8080,
+			ShutdownDelay:   5 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			ReadYourWritesWindow: 5 * time.Second,
+			MigrationMode:        "apply",
+		},
+		Redis: RedisConfig{Addr: "localhost:6379"},
+		RateLimit: RateLimitConfig{
+			PerMinute: 100,
+			Policies:  map[string]int{"strict": 10},
+			Routes: map[string]string{
+				"POST /users/register": "strict",
+				"POST /users/login":    "strict",
+				"POST /users/refresh":  "strict",
+			},
+		},
+		Log:       LogConfig{Level: slog.LevelInfo},
+		Tracing:   TracingConfig{Exporter: "none", SampleRate: 1},
+		Scheduler: SchedulerConfig{Interval: 30 * time.Second},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+			ExposedHeaders: []string{"ETag", "X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
+			MaxAge:         10 * time.Minute,
+			RouteOrigins:   map[string][]string{},
+		},
+		Health: HealthConfig{
+			Timeout:     2 * time.Second,
+			CacheTTL:    2 * time.Second,
+			DiskMinFree: 100 << 20,
+		},
 	}
+}
 
-	rateLimit, err := strconv.Atoi(getEnv("RATE_LIMIT", "100"))
-	if err != nil {
-		return nil, fmt.Errorf("RATE_LIMIT is not a valid integer: %v", err)
+// Validate checks the values that parse but are out of range, and reports every problem at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, problem string) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s %s", describe(key), problem))
+		}
 	}
 
-	GlobalConfig = &Config{
-		ServerPort:        getEnv("SERVER_PORT", "8080"),
-		DatabaseURL:       databaseURL,
-		DatabaseURLPooler: databaseURLPooler,
-		Environment:       getEnv("ENVIRONMENT", "development"),
-		LogLevel:          getEnv("LOG_LEVEL", "info"),
-		JWTSecretKey:      jwtSecretKey,
-		RedisAddr:         getEnv("REDIS_ADDR", "localhost:6379"),
-		RedisPassword:     getEnv("REDIS_PASSWORD", ""),
-		RedisDB:           getEnv("REDIS_DB", "0"),
-		RateLimit:         rateLimit,
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port", "must be between 1 and 65535")
+	check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay", "must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout", "must be positive")
+	check(c.Database.URL != "", "database.url", "is required")
+	check(c.Database.PoolerURL != "", "database.pooler_url", "is required")
+	check(c.Database.ReadYourWritesWindow >= 0, "database.read_your_writes_window", "must not be negative")
+	check(slices.Contains([]string{"apply", "check", "off"}, c.Database.MigrationMode), "database.migration_mode", "must be apply, check or off")
+	check(c.Redis.DB >= 0, "redis.db", "must not be negative")
+	check(c.Auth.JWTSecretKey != "", "auth.jwt_secret_key", "is required")
+	check(c.RateLimit.PerMinute > 0, "rate_limit.per_minute", "must be positive")
+	for _, name := range slices.Sorted(maps.Keys(c.RateLimit.Policies)) {
+		check(c.RateLimit.Policies[name] > 0, "rate_limit.policies", "must be positive for "+name)
+		check(name != GlobalRateLimitPolicy, "rate_limit.policies", "must not redefine the "+GlobalRateLimitPolicy+" policy")
+	}
+	for _, pattern := range slices.Sorted(maps.Keys(c.RateLimit.Routes)) {
+		_, ok := c.RateLimit.Policies[c.RateLimit.Routes[pattern]]
+		check(ok, "rate_limit.routes", "must name a policy of rate_limit.policies for "+pattern)
 	}
+	check(slices.Contains([]string{"none", "otlp", "stdout"}, c.Tracing.Exporter), "tracing.exporter", "must be none, otlp or stdout")
+	check(c.Tracing.SampleRate >= 0 && c.Tracing.SampleRate <= 1, "tracing.sample_rate", "must be between 0 and 1")
+	check(c.Scheduler.Interval > 0, "scheduler.interval", "must be positive")
+	check(!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowedOrigins, "*"), "cors.allowed_origins", "must list origins when cors.allow_credentials is true")
+	check(c.CORS.MaxAge >= 0, "cors.max_age", "must not be negative")
+	check(c.Health.Timeout > 0, "health.timeout", "must be positive")
+	check(c.Health.CacheTTL >= 0, "health.cache_ttl", "must not be negative")
 
-	return GlobalConfig, nil
+	return errors.Join(errs...)
 }
 
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
-	}
-	return defaultValue
+// IsDevelopment reports whether the app runs in development, where internal error details are returned to clients
+func (c *Config) IsDevelopment() bool {
+	return c != nil && c.Environment == "development"
 }
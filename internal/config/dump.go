@@ -0,0 +1,78 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Dump writes the configuration as a YAML config file. Secrets are redacted, URLs only lose their password.
+func (c *Config) Dump(w io.Writer) error {
+	root := map[string]any{}
+	for _, s := range settings {
+		value := s.get(c)
+		if s.secret {
+			value = redact(value)
+		}
+
+		section := root
+		path := strings.Split(s.key, ".")
+		for _, name := range path[:len(path)-1] {
+			next, ok := section[name].(map[string]any)
+			if !ok {
+				next = map[string]any{}
+				section[name] = next
+			}
+			section = next
+		}
+		section[path[len(path)-1]] = value
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// RestartRequired returns the keys of the settings that differ in next but are only applied on startup
+func (c *Config) RestartRequired(next *Config) []string {
+	var keys []string
+	for _, s := range settings {
+		if !s.reloadable && !reflect.DeepEqual(s.get(c), s.get(next)) {
+			keys = append(keys, s.key)
+		}
+	}
+	return keys
+}
+
+// redact hides the secrets of a string or list setting
+func redact(value any) any {
+	switch value := value.(type) {
+	case string:
+		return redactString(value)
+	case []string:
+		redacted := make([]string, len(value))
+		for i, item := range value {
+			redacted[i] = redactString(item)
+		}
+		return redacted
+	}
+	return "[redacted]"
+}
+
+// redactString keeps URLs without their password so the dump still shows where they point.
+// URLs with a query string may hold a password there and are hidden like other secrets.
+func redactString(value string) string {
+	if value == "" {
+		return ""
+	}
+	if u, err := url.Parse(value); err == nil && u.Scheme != "" && u.Host != "" && u.RawQuery == "" {
+		return u.Redacted()
+	}
+	return "[redacted]"
+}
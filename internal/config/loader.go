@@ -0,0 +1,202 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Loader loads the configuration in layers, each overriding the previous one: the defaults, an optional
+// YAML or TOML file, the environment, then the command-line flags
+type Loader struct {
+	file  string            // Config file from --config, CONFIG_FILE otherwise
+	flags map[string]string // Values of the setting flags by key
+}
+
+// NewLoader registers --config and a flag for every setting on flags. Secrets have no flag,
+// command lines are visible to every user of the machine.
+func NewLoader(flags *flag.FlagSet) *Loader {
+	l := &Loader{flags: map[string]string{}}
+	flags.StringVar(&l.file, "config", "", "YAML or TOML config file, instead of CONFIG_FILE")
+	for _, s := range settings {
+		if s.secret {
+			continue
+		}
+		flags.Func(flagName(s.key), s.usage+" ("+s.env+")", func(value string) error {
+			l.flags[s.key] = value
+			return nil
+		})
+	}
+	return l
+}
+
+// Load reads every layer and validates the result, reporting every problem at once. The .env file is optional,
+// containers usually set the environment directly. Loading again picks up changes to the config, .env and secret files.
+func (l *Loader) Load() (*Config, error) {
+	dotenv, err := godotenv.Read()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error loading .env file: %w", err)
+	}
+	env := environment(dotenv)
+
+	cfg := Default()
+	errs := l.loadFile(cfg, env)
+	errs = append(errs, loadEnv(cfg, env)...)
+	for _, s := range settings {
+		if value, ok := l.flags[s.key]; ok {
+			if err := s.set(cfg, value); err != nil {
+				errs = append(errs, fmt.Errorf("--%s: %w", flagName(s.key), err))
+			}
+		}
+	}
+	errs = append(errs, cfg.Validate())
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// environment holds the variables of the .env file. It is read on every load rather than copied into
+// the process environment, which would keep the values of the first load.
+type environment map[string]string
+
+// lookup returns a variable of the process environment, which overrides the .env file
+func (e environment) lookup(name string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := e[name]
+	return value, ok
+}
+
+// loadFile sets the settings of the config file, if there is one
+func (l *Loader) loadFile(cfg *Config, env environment) []error {
+	path := l.file
+	if path == "" {
+		path, _ = env.lookup("CONFIG_FILE")
+	}
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("error reading config file: %w", err)}
+	}
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return []error{fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)}
+	}
+	if err != nil {
+		return []error{fmt.Errorf("error parsing config file %s: %w", path, err)}
+	}
+	return setFileSection(cfg, path, "", values)
+}
+
+// setFileSection sets the settings of a config file section, whose keys are nested under prefix
+func setFileSection(cfg *Config, path, prefix string, values map[string]any) []error {
+	var errs []error
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		key := prefix + name
+		if s, ok := lookupSetting(key); ok {
+			value, err := fileValue(values[name])
+			if err == nil {
+				err = s.set(cfg, value)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s in %s: %w", key, path, err))
+			}
+			continue
+		}
+
+		section, ok := values[name].(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s in %s is not a known setting", key, path))
+			continue
+		}
+		errs = append(errs, setFileSection(cfg, path, key+".", section)...)
+	}
+	return errs
+}
+
+// fileValue converts a config file value to the text the environment would hold: lists are
+// separated by commas, and maps are written like CORS_ROUTE_ORIGINS
+func fileValue(value any) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case []any:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			switch item.(type) {
+			case []any, map[string]any:
+				return "", errors.New("list items must be plain values")
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		routes := make([]string, 0, len(value))
+		for prefix, origins := range value {
+			list, err := fileValue(origins)
+			if err != nil {
+				return "", err
+			}
+			routes = append(routes, prefix+"="+list)
+		}
+		slices.Sort(routes)
+		return strings.Join(routes, ";"), nil
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
+
+// loadEnv sets the settings found in the environment or the .env file. Secrets are also read from the file
+// named by their variable with a _FILE suffix, like the secrets mounted by Docker and Kubernetes.
+func loadEnv(cfg *Config, env environment) []error {
+	var errs []error
+	for _, s := range settings {
+		value, ok := env.lookup(s.env)
+		if path, found := env.lookup(s.env + "_FILE"); s.secret && found {
+			if ok {
+				errs = append(errs, fmt.Errorf("%s and %s_FILE are both set", s.env, s.env))
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s_FILE: %w", s.env, err))
+				continue
+			}
+			value, ok = strings.TrimRight(string(data), "\r\n"), true
+		}
+
+		if !ok {
+			continue
+		}
+		if err := s.set(cfg, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+		}
+	}
+	return errs
+}
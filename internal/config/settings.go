@@ -0,0 +1,348 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_api/internal/util"
+)
+
+// setting is one configuration value. Every layer sets it from text: the config file under key,
+// the environment under env and the command line under the flag named after key.
+type setting struct {
+	key        string // Dotted path in config files, like "redis.db"
+	env        string
+	usage      string
+	secret     bool // Redacted from dumps, also read from the file named by env+"_FILE", and never a flag
+	reloadable bool // Applied on SIGHUP, the others need a restart
+	set        func(c *Config, value string) error
+	get        func(c *Config) any // The value as written in config files
+}
+
+// settings are every setting of Config
+var settings = []setting{
+	stringSetting("environment", "ENVIRONMENT", "Environment, development returns error details and logs as text", func(c *Config) *string { return &c.Environment }),
+
+	intSetting("server.port", "SERVER_PORT", "Port the server listens on", func(c *Config) *int { return &c.Server.Port }),
+	durationSetting("server.shutdown_delay", "SHUTDOWN_DELAY", "Time between failing readiness and draining on shutdown", func(c *Config) *time.Duration { return &c.Server.ShutdownDelay }),
+	durationSetting("server.shutdown_timeout", "SHUTDOWN_TIMEOUT", "Time allowed for in-flight requests to finish on shutdown", func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout }),
+
+	stringSetting("database.url", "DATABASE_URL", "Primary database URL", func(c *Config) *string { return &c.Database.URL }).asSecret(),
+	stringSetting("database.pooler_url", "DATABASE_URL_POOLER", "PgBouncer URL for reads", func(c *Config) *string { return &c.Database.PoolerURL }).asSecret(),
+	listSetting("database.replica_urls", "DATABASE_REPLICA_URLS", "Comma-separated read replica URLs", func(c *Config) *[]string { return &c.Database.ReplicaURLs }).asSecret(),
+	durationSetting("database.read_your_writes_window", "READ_YOUR_WRITES_WINDOW", "How long reads of a user go to the primary after they write", func(c *Config) *time.Duration { return &c.Database.ReadYourWritesWindow }),
+	stringSetting("database.migration_mode", "MIGRATION_MODE", "apply, check or off, for pending migrations on startup", func(c *Config) *string { return &c.Database.MigrationMode }),
+
+	stringSetting("redis.addr", "REDIS_ADDR", "Redis address", func(c *Config) *string { return &c.Redis.Addr }),
+	stringSetting("redis.password", "REDIS_PASSWORD", "Redis password", func(c *Config) *string { return &c.Redis.Password }).asSecret(),
+	intSetting("redis.db", "REDIS_DB", "Redis database number", func(c *Config) *int { return &c.Redis.DB }),
+
+	stringSetting("auth.jwt_secret_key", "JWT_SECRET_KEY", "Key signing the access tokens", func(c *Config) *string { return &c.Auth.JWTSecretKey }).asSecret(),
+
+	intSetting("rate_limit.per_minute", "RATE_LIMIT", "Requests per minute per user or anonymous IP address", func(c *Config) *int { return &c.RateLimit.PerMinute }).asReloadable(),
+	{
+		key:        "rate_limit.policies",
+		env:        "RATE_LIMIT_POLICIES",
+		usage:      "Requests per minute per IP address by policy name, like strict=10;uploads=30",
+		reloadable: true,
+		set: func(c *Config, value string) error {
+			policies, err := parseRateLimitPolicies(value)
+			if err != nil {
+				return err
+			}
+			c.RateLimit.Policies = policies
+			return nil
+		},
+		get: func(c *Config) any { return c.RateLimit.Policies },
+	},
+	{
+		key:        "rate_limit.routes",
+		env:        "RATE_LIMIT_ROUTES",
+		usage:      "Rate limit policy by route pattern, like POST /users/login=strict;POST /users/refresh=strict",
+		reloadable: true,
+		set: func(c *Config, value string) error {
+			routes, err := parseRateLimitRoutes(value)
+			if err != nil {
+				return err
+			}
+			c.RateLimit.Routes = routes
+			return nil
+		},
+		get: func(c *Config) any { return c.RateLimit.Routes },
+	},
+	{
+		key:        "rate_limit.trusted_proxies",
+		env:        "TRUSTED_PROXIES",
+		usage:      "Comma-separated addresses or CIDR ranges of the proxies whose X-Forwarded-For header is trusted",
+		reloadable: true,
+		set: func(c *Config, value string) error {
+			proxies, err := parsePrefixes(value)
+			if err != nil {
+				return err
+			}
+			c.RateLimit.TrustedProxies = proxies
+			return nil
+		},
+		get: func(c *Config) any {
+			proxies := make([]string, 0, len(c.RateLimit.TrustedProxies))
+			for _, prefix := range c.RateLimit.TrustedProxies {
+				proxies = append(proxies, prefix.String())
+			}
+			return proxies
+		},
+	},
+
+	{
+		key:        "log.level",
+		env:        "LOG_LEVEL",
+		usage:      "Log level: debug, info, warn or error",
+		reloadable: true,
+		set: func(c *Config, value string) error {
+			level, err := util.ParseLogLevel(value)
+			if err != nil {
+				return err
+			}
+			c.Log.Level = level
+			return nil
+		},
+		get: func(c *Config) any { return strings.ToLower(c.Log.Level.String()) },
+	},
+
+	stringSetting("tracing.exporter", "TRACING_EXPORTER", "Trace exporter: none, otlp or stdout", func(c *Config) *string { return &c.Tracing.Exporter }),
+	floatSetting("tracing.sample_rate", "TRACING_SAMPLE_RATE", "Fraction of new traces that are sampled", func(c *Config) *float64 { return &c.Tracing.SampleRate }),
+
+	durationSetting("scheduler.interval", "SCHEDULER_INTERVAL", "How often scheduled blogs are published", func(c *Config) *time.Duration { return &c.Scheduler.Interval }),
+
+	listSetting("cors.allowed_origins", "CORS_ALLOWED_ORIGINS", "Comma-separated allowed origins", func(c *Config) *[]string { return &c.CORS.AllowedOrigins }).asReloadable(),
+	boolSetting("cors.allow_credentials", "CORS_ALLOW_CREDENTIALS", "Allow credentials from the allowed origins", func(c *Config) *bool { return &c.CORS.AllowCredentials }).asReloadable(),
+	listSetting("cors.exposed_headers", "CORS_EXPOSED_HEADERS", "Comma-separated response headers readable by clients", func(c *Config) *[]string { return &c.CORS.ExposedHeaders }).asReloadable(),
+	durationSetting("cors.max_age", "CORS_MAX_AGE", "How long browsers cache preflight responses", func(c *Config) *time.Duration { return &c.CORS.MaxAge }).asReloadable(),
+	{
+		key:        "cors.route_origins",
+		env:        "CORS_ROUTE_ORIGINS",
+		usage:      "Allowed origins by path prefix, like /docs=*;/openapi.json=*",
+		reloadable: true,
+		set: func(c *Config, value string) error {
+			routeOrigins, err := parseRouteOrigins(value)
+			if err != nil {
+				return err
+			}
+			c.CORS.RouteOrigins = routeOrigins
+			return nil
+		},
+		get: func(c *Config) any { return c.CORS.RouteOrigins },
+	},
+
+	durationSetting("health.timeout", "HEALTH_CHECK_TIMEOUT", "Time allowed for each readiness check", func(c *Config) *time.Duration { return &c.Health.Timeout }),
+	durationSetting("health.cache_ttl", "HEALTH_CACHE_TTL", "How long a readiness report is reused", func(c *Config) *time.Duration { return &c.Health.CacheTTL }),
+	boolSetting("health.check_migrations", "HEALTH_CHECK_MIGRATIONS", "Fail readiness while migrations are pending", func(c *Config) *bool { return &c.Health.CheckMigrations }),
+	stringSetting("health.disk_path", "HEALTH_DISK_PATH", "Path whose filesystem must keep free space, empty to skip", func(c *Config) *string { return &c.Health.DiskPath }),
+	{
+		key:   "health.disk_min_free_mb",
+		env:   "HEALTH_DISK_MIN_FREE_MB",
+		usage: "Free space required on the disk path, in megabytes",
+		set: func(c *Config, value string) error {
+			megabytes, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%q is not a valid non-negative integer", value)
+			}
+			c.Health.DiskMinFree = megabytes << 20
+			return nil
+		},
+		get: func(c *Config) any { return c.Health.DiskMinFree >> 20 },
+	},
+}
+
+// lookupSetting finds the setting of a config file key
+func lookupSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// describe names a setting in errors by its config file key and environment variable
+func describe(key string) string {
+	if s, ok := lookupSetting(key); ok {
+		return key + " (" + s.env + ")"
+	}
+	return key
+}
+
+// flagName is the command-line flag of a setting, like redis-db for redis.db
+func flagName(key string) string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(key)
+}
+
+func (s setting) asSecret() setting {
+	s.secret = true
+	return s
+}
+
+func (s setting) asReloadable() setting {
+	s.reloadable = true
+	return s
+}
+
+func stringSetting(key, env, usage string, field func(*Config) *string) setting {
+	return setting{
+		key: key, env: env, usage: usage,
+		set: func(c *Config, value string) error {
+			*field(c) = value
+			return nil
+		},
+		get: func(c *Config) any { return *field(c) },
+	}
+}
+
+func intSetting(key, env, usage string, field func(*Config) *int) setting {
+	return setting{
+		key: key, env: env, usage: usage,
+		set: func(c *Config, value string) error {
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%q is not a valid integer", value)
+			}
+			*field(c) = number
+			return nil
+		},
+		get: func(c *Config) any { return *field(c) },
+	}
+}
+
+func floatSetting(key, env, usage string, field func(*Config) *float64) setting {
+	return setting{
+		key: key, env: env, usage: usage,
+		set: func(c *Config, value string) error {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("%q is not a valid number", value)
+			}
+			*field(c) = number
+			return nil
+		},
+		get: func(c *Config) any { return *field(c) },
+	}
+}
+
+func boolSetting(key, env, usage string, field func(*Config) *bool) setting {
+	return setting{
+		key: key, env: env, usage: usage,
+		set: func(c *Config, value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%q is not a valid boolean", value)
+			}
+			*field(c) = enabled
+			return nil
+		},
+		get: func(c *Config) any { return *field(c) },
+	}
+}
+
+func durationSetting(key, env, usage string, field func(*Config) *time.Duration) setting {
+	return setting{
+		key: key, env: env, usage: usage,
+		set: func(c *Config, value string) error {
+			duration, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("%q is not a valid duration", value)
+			}
+			*field(c) = duration
+			return nil
+		},
+		get: func(c *Config) any { return field(c).String() },
+	}
+}
+
+func listSetting(key, env, usage string, field func(*Config) *[]string) setting {
+	return setting{
+		key: key, env: env, usage: usage,
+		set: func(c *Config, value string) error {
+			*field(c) = splitList(value, ",")
+			return nil
+		},
+		get: func(c *Config) any {
+			if *field(c) == nil {
+				return []string{}
+			}
+			return *field(c)
+		},
+	}
+}
+
+// parseRouteOrigins parses origins by path prefix. Routes are separated by semicolons, like "/docs=*;/openapi.json=*".
+func parseRouteOrigins(value string) (map[string][]string, error) {
+	routeOrigins := map[string][]string{}
+	for _, route := range splitList(value, ";") {
+		prefix, origins, found := strings.Cut(route, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !found || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("entry must look like /path=origin,origin: %s", route)
+		}
+		routeOrigins[prefix] = splitList(origins, ",")
+	}
+	return routeOrigins, nil
+}
+
+// parseRateLimitPolicies parses the limits of RATE_LIMIT_POLICIES, like strict=10;uploads=30
+func parseRateLimitPolicies(value string) (map[string]int, error) {
+	policies := map[string]int{}
+	for _, entry := range splitList(value, ";") {
+		name, limit, found := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		perMinute, err := strconv.Atoi(strings.TrimSpace(limit))
+		if !found || name == "" || err != nil {
+			return nil, fmt.Errorf("entry must look like name=requests per minute: %s", entry)
+		}
+		policies[name] = perMinute
+	}
+	return policies, nil
+}
+
+// parseRateLimitRoutes parses the policies of RATE_LIMIT_ROUTES, like POST /users/login=strict
+func parseRateLimitRoutes(value string) (map[string]string, error) {
+	routes := map[string]string{}
+	for _, entry := range splitList(value, ";") {
+		pattern, name, found := strings.Cut(entry, "=")
+		pattern, name = strings.TrimSpace(pattern), strings.TrimSpace(name)
+		if !found || !strings.Contains(pattern, "/") || name == "" {
+			return nil, fmt.Errorf("entry must look like METHOD /path=policy: %s", entry)
+		}
+		routes[pattern] = name
+	}
+	return routes, nil
+}
+
+// parsePrefixes parses a comma-separated list of CIDR ranges, where a plain address is a range of one
+func parsePrefixes(value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range splitList(value, ",") {
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(item)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%q is not a valid address or CIDR range", item)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// splitList splits a separated list, trimming spaces and dropping empty items
+func splitList(value, separator string) []string {
+	var items []string
+	for _, item := range strings.Split(value, separator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
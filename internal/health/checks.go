@@ -0,0 +1,66 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+var errNotConnected = errors.New("not connected")
+
+// Database pings the database and reports the server version
+func Database(db *gorm.DB) CheckFunc {
+	return func(ctx context.Context) (Component, error) {
+		if db == nil {
+			return Component{}, errNotConnected
+		}
+		// Query the pool directly, probes should not add to the query logs and traces
+		sqlDB, err := db.DB()
+		if err != nil {
+			return Component{}, fmt.Errorf("failed to get database instance: %w", err)
+		}
+		if err := sqlDB.PingContext(ctx); err != nil {
+			return Component{}, fmt.Errorf("ping failed: %w", err)
+		}
+
+		var version string
+		if err := sqlDB.QueryRowContext(ctx, "SHOW server_version").Scan(&version); err != nil {
+			return Component{}, fmt.Errorf("failed to get the server version: %w", err)
+		}
+		return Component{Version: version}, nil
+	}
+}
+
+// Redis pings Redis and reports the server version
+func Redis(client *redis.Client) CheckFunc {
+	return func(ctx context.Context) (Component, error) {
+		if client == nil {
+			return Component{}, errNotConnected
+		}
+		if err := client.Ping(ctx).Err(); err != nil {
+			return Component{}, fmt.Errorf("ping failed: %w", err)
+		}
+
+		info, err := client.Info(ctx, "server").Result()
+		if err != nil {
+			return Component{}, fmt.Errorf("failed to get the server info: %w", err)
+		}
+		return Component{Version: redisVersion(info)}, nil
+	}
+}
+
+// redisVersion finds the redis_version field of an INFO reply
+func redisVersion(info string) string {
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		if version, found := strings.CutPrefix(scanner.Text(), "redis_version:"); found {
+			return strings.TrimSpace(version)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,30 @@
+//go:build linux || darwin
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+)
+
+// Disk checks that the filesystem holding path has at least minFree bytes available
+func Disk(path string, minFree uint64) CheckFunc {
+	return func(ctx context.Context) (Component, error) {
+		var stat syscall.Statfs_t
+		if err := syscall.Statfs(path, &stat); err != nil {
+			return Component{}, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+
+		free := stat.Bavail * uint64(stat.Bsize)
+		component := Component{Details: map[string]any{
+			"path":           path,
+			"free_bytes":     free,
+			"min_free_bytes": minFree,
+		}}
+		if free < minFree {
+			return component, fmt.Errorf("%d bytes available, below the minimum of %d", free, minFree)
+		}
+		return component, nil
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package health
+
+import (
+	"context"
+	"errors"
+)
+
+// Disk is not supported on this platform, the check always fails
+func Disk(path string, minFree uint64) CheckFunc {
+	return func(ctx context.Context) (Component, error) {
+		return Component{Details: map[string]any{"path": path}}, errors.New("disk checks are not supported on this platform")
+	}
+}
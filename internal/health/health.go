@@ -0,0 +1,142 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds each check when the checker is created without a timeout
+const DefaultTimeout = 2 * time.Second
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// Component is the result of one check
+type Component struct {
+	Status    Status         `json:"status"`
+	LatencyMS float64        `json:"latency_ms"`
+	Version   string         `json:"version,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Optional  bool           `json:"optional,omitempty"` // Reported, but does not fail the report
+}
+
+// Report is the result of every check. It is up only when every component that is not optional is up.
+type Report struct {
+	Status     Status               `json:"status"`
+	CheckedAt  time.Time            `json:"checked_at"`
+	Components map[string]Component `json:"components"`
+}
+
+// WithoutErrors returns a copy of the report without error messages, which can leak
+// addresses and credentials of the dependencies to clients
+func (r Report) WithoutErrors() Report {
+	components := make(map[string]Component, len(r.Components))
+	for name, component := range r.Components {
+		component.Error = ""
+		components[name] = component
+	}
+	r.Components = components
+	return r
+}
+
+// CheckFunc checks a dependency. It returns the version and details of the dependency,
+// which are reported even when it fails.
+type CheckFunc func(ctx context.Context) (Component, error)
+
+type namedCheck struct {
+	name     string
+	check    CheckFunc
+	optional bool
+}
+
+// Checker runs the registered checks and caches their report, so frequent probes
+// do not overload the dependencies
+type Checker struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+	checks   []namedCheck
+
+	mu     sync.Mutex
+	report *Report
+}
+
+// NewChecker creates a checker that bounds each check by timeout and reuses a report for cacheTTL.
+// A zero cacheTTL runs the checks on every call.
+func NewChecker(timeout, cacheTTL time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Checker{timeout: timeout, cacheTTL: cacheTTL}
+}
+
+// Register adds a check reported under name
+func (c *Checker) Register(name string, check CheckFunc) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// RegisterOptional adds a check reported under name that does not fail the report, for dependencies
+// the service can do without
+func (c *Checker) RegisterOptional(name string, check CheckFunc) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check, optional: true})
+}
+
+// Check runs every check concurrently, or returns the cached report while it is fresh.
+// Concurrent calls wait for the running checks instead of starting their own.
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.report != nil && time.Since(c.report.CheckedAt) < c.cacheTTL {
+		return *c.report
+	}
+
+	// The report is shared with other callers, do not fail it when this caller goes away
+	ctx = context.WithoutCancel(ctx)
+
+	components := make([]Component, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Go(func() { components[i] = c.run(ctx, check) })
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:     StatusUp,
+		CheckedAt:  time.Now(),
+		Components: make(map[string]Component, len(c.checks)),
+	}
+	for i, check := range c.checks {
+		components[i].Optional = check.optional
+		report.Components[check.name] = components[i]
+		if components[i].Status != StatusUp && !check.optional {
+			report.Status = StatusDown
+		}
+	}
+
+	c.report = &report
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check namedCheck) Component {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	component, err := check.check(ctx)
+	component.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		slog.WarnContext(ctx, "Health check failed", "component", check.name, "error", err)
+		component.Status = StatusDown
+		component.Error = err.Error()
+		return component
+	}
+	component.Status = StatusUp
+	return component
+}
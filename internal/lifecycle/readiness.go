@@ -0,0 +1,19 @@
+package lifecycle
+
+import "sync/atomic"
+
+// Readiness records whether a server accepts new traffic. It is set once the server listens
+// and cleared when shutdown starts, before connections are drained. The zero value is not ready.
+type Readiness struct {
+	ready atomic.Bool
+}
+
+// SetReady marks whether the server accepts new traffic
+func (r *Readiness) SetReady(value bool) {
+	r.ready.Store(value)
+}
+
+// IsReady reports whether the server accepts new traffic
+func (r *Readiness) IsReady() bool {
+	return r.ready.Load()
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+)
+
+const namespace = "go_api"
+
+// Metrics are the collectors of one app and the registry exposing them on /metrics.
+// Every app has its own, so several apps in one process do not mix their series.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	// HTTPRequests counts handled requests by route pattern and status code
+	HTTPRequests *prometheus.CounterVec
+	// HTTPRequestDuration observes request latencies by route pattern
+	HTTPRequestDuration *prometheus.HistogramVec
+	// HTTPRequestsInFlight is the number of requests being served
+	HTTPRequestsInFlight prometheus.Gauge
+	// CacheRequests counts cache lookups by cache and result (hit or miss)
+	CacheRequests *prometheus.CounterVec
+	// RateLimitRejections counts requests rejected by the rate limiter by policy
+	RateLimitRejections *prometheus.CounterVec
+}
+
+// New creates the collectors in a new registry, along with the Go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		HTTPRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latencies by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		HTTPRequestsInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "Number of HTTP requests being served.",
+		}),
+		CacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_requests_total",
+			Help:      "Number of cache lookups by cache and result.",
+		}, []string{"cache", "result"}),
+		RateLimitRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_rejections_total",
+			Help:      "Number of requests rejected by the rate limiter by policy.",
+		}, []string{"policy"}),
+	}
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.HTTPRequests,
+		m.HTTPRequestDuration,
+		m.HTTPRequestsInFlight,
+		m.CacheRequests,
+		m.RateLimitRejections,
+	)
+	return m
+}
+
+// CacheHit records a cache lookup that found its entry
+func (m *Metrics) CacheHit(cache string) {
+	m.CacheRequests.WithLabelValues(cache, "hit").Inc()
+}
+
+// CacheMiss records a cache lookup that did not find its entry
+func (m *Metrics) CacheMiss(cache string) {
+	m.CacheRequests.WithLabelValues(cache, "miss").Inc()
+}
+
+// RegisterDB exposes the connection pool stats of the database
+func (m *Metrics) RegisterDB(db *sql.DB) error {
+	return m.Registry.Register(collectors.NewDBStatsCollector(db, "postgres"))
+}
+
+// RegisterRedis exposes the connection pool stats of a Redis client
+func (m *Metrics) RegisterRedis(client *redis.Client) error {
+	return m.Registry.Register(newRedisPoolCollector(client))
+}
+
+// Handler serves the registered metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
@@ -7,8 +7,9 @@ import (
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
-	"go_api/internal/config"
 	"go_api/internal/storage"
 	"go_api/internal/util"
 )
@@ -17,53 +18,71 @@ type contextKey string
 
 const UserClaimsKey contextKey = "claims"
 
-func AuthMiddleware(next http.Handler) http.Handler {
+// TokenBlacklist reports whether an access token was revoked before it expired
+type TokenBlacklist interface {
+	IsTokenBlacklisted(ctx context.Context, token string) (bool, error)
+}
+
+// Authenticator authenticates requests with their access token
+type Authenticator struct {
+	secret    []byte
+	blacklist TokenBlacklist
+}
+
+// NewAuthenticator creates an authenticator of the access tokens signed with secret
+func NewAuthenticator(secret []byte, blacklist TokenBlacklist) *Authenticator {
+	return &Authenticator{secret: secret, blacklist: blacklist}
+}
+
+// Required rejects requests without a valid access token and puts the token claims in the request context
+func (a *Authenticator) Required(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Get Authorization header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			util.ResponseWithError(w, http.StatusUnauthorized, "Missing Authorization header", "Authorization header is required")
+			util.ResponseWithError(w, r, http.StatusUnauthorized, util.CodeUnauthorized, "Authorization header is required")
 			return
 		}
 
 		// Bearer token
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		claims := &util.UserClaims{}
 
 		// Parse token
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(config.GlobalConfig.JWTSecretKey), nil
-		})
+		claims, err := util.ParseToken(tokenString, a.secret)
 		if err != nil {
-			if errors.Is(err, jwt.ErrTokenSignatureInvalid) {
-				util.ResponseWithError(w, http.StatusUnauthorized, "Signature invalid", "Signature invalid")
+			detail := "Token is invalid"
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				detail = "Token has expired"
 			}
-			util.ResponseWithError(w, http.StatusUnauthorized, "Invalid token", err.Error())
-			return
-		}
-
-		// Validate token
-		if !token.Valid {
-			util.ResponseWithError(w, http.StatusUnauthorized, "Invalid token", "Token is invalid")
+			util.ResponseWithError(w, r, http.StatusUnauthorized, util.CodeInvalidToken, detail)
 			return
 		}
 
-		// Check if token is blacklisted from Redis
-		redisClient := storage.GetRedisClient()
-		if redisClient == nil {
-			util.ResponseWithError(w, http.StatusInternalServerError, "Failed to get Redis client", "Failed to get Redis client")
-			return
-		}
-
-		isBlacklisted, err := redisClient.Get(r.Context(), tokenString).Result()
-		if err == nil && isBlacklisted == "blacklisted" {
-			util.ResponseWithError(w, http.StatusUnauthorized, "Token is blacklisted", "Token is blacklisted")
+		// Check if token is blacklisted
+		blacklisted, err := a.blacklist.IsTokenBlacklisted(r.Context(), tokenString)
+		if err == nil && blacklisted {
+			util.ResponseWithError(w, r, http.StatusUnauthorized, util.CodeTokenRevoked, "Token has been revoked")
 			return
 		}
 
 		// Set context
-		ctx := context.WithValue(r.Context(), UserClaimsKey, claims)
+		setAccessLogUser(r.Context(), claims.UserID)
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.Int64("user.id", int64(claims.UserID)))
+		ctx := context.WithValue(storage.WithUser(r.Context(), claims.UserID), UserClaimsKey, claims)
 		r = r.WithContext(ctx)
 		next.ServeHTTP(w, r)
 	})
 }
+
+// Optional authenticates requests that carry an Authorization header
+// and lets anonymous requests through without claims
+func (a *Authenticator) Optional(next http.Handler) http.Handler {
+	authenticated := a.Required(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		authenticated.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go_api/internal/app/model"
+	"go_api/internal/util"
+)
+
+// RequirePermission only lets requests through whose claims grant the permission.
+// It must be wrapped by AuthMiddleware so the claims are in the context.
+func RequirePermission(permission model.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(UserClaimsKey).(*util.UserClaims)
+			if !ok {
+				util.ResponseWithError(w, r, http.StatusUnauthorized, util.CodeUnauthorized, "Authentication is required")
+				return
+			}
+
+			if !claims.HasPermission(string(permission)) {
+				util.ResponseWithError(w, r, http.StatusForbidden, util.CodeForbidden, "Missing permission: "+string(permission))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
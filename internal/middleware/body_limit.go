@@ -0,0 +1,16 @@
+package middleware
+
+import "net/http"
+
+// BodyLimitMiddleware stops reading request bodies after maxBytes. Handlers then fail to read the
+// body with an *http.MaxBytesError, and the server closes the connection after the response.
+func BodyLimitMiddleware(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
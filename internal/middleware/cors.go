@@ -2,19 +2,197 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"go_api/internal/config"
+	"go_api/internal/util"
 )
 
-func CorsMiddleware(next http.Handler) http.Handler {
+// corsAllowedHeaders are the request headers clients may send cross-origin
+var corsAllowedHeaders = []string{
+	"Accept", "Content-Type", "Authorization", "If-Match", "X-Request-ID", "traceparent",
+}
+
+// corsMethods are the methods probed on the router to answer preflights
+var corsMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// RouteMatcher finds the route of a request. *http.ServeMux implements it.
+type RouteMatcher interface {
+	Handler(r *http.Request) (http.Handler, string)
+}
+
+// originPattern is an allowed origin: an exact origin, "*", or a wildcard subdomain pattern
+type originPattern struct {
+	any    bool
+	exact  string
+	scheme string // Of wildcard patterns, like "https://"
+	suffix string // Of wildcard patterns, like ".example.com"
+}
+
+func parseOrigins(origins []string) []originPattern {
+	patterns := make([]originPattern, 0, len(origins))
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		switch {
+		case origin == "*":
+			patterns = append(patterns, originPattern{any: true})
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "*")
+			patterns = append(patterns, originPattern{scheme: scheme, suffix: host})
+		default:
+			patterns = append(patterns, originPattern{exact: origin})
+		}
+	}
+	return patterns
+}
+
+func (p originPattern) matches(origin string) bool {
+	switch {
+	case p.any:
+		return true
+	case p.exact != "":
+		return origin == p.exact
+	}
+	host, found := strings.CutPrefix(origin, p.scheme)
+	return found && strings.HasSuffix(host, p.suffix) && len(host) > len(p.suffix)
+}
+
+// corsPolicy is the parsed CORS configuration
+type corsPolicy struct {
+	config       config.CORSConfig
+	origins      []originPattern
+	routeOrigins map[string][]originPattern
+	routes       RouteMatcher
+}
+
+// CORS applies a cross-origin policy that can be replaced while the server runs
+type CORS struct {
+	policy atomic.Pointer[corsPolicy]
+	routes RouteMatcher
+}
+
+// NewCORS creates the middleware applying the CORS configuration. Requests from origins that are not allowed
+// are rejected, and preflights are answered with the methods registered on routes for the path.
+func NewCORS(cfg config.CORSConfig, routes RouteMatcher) *CORS {
+	c := &CORS{routes: routes}
+	c.SetConfig(cfg)
+	return c
+}
+
+// SetConfig replaces the CORS configuration for the requests received from then on
+func (c *CORS) SetConfig(cfg config.CORSConfig) {
+	policy := &corsPolicy{
+		config:       cfg,
+		origins:      parseOrigins(cfg.AllowedOrigins),
+		routeOrigins: make(map[string][]originPattern, len(cfg.RouteOrigins)),
+		routes:       c.routes,
+	}
+	for prefix, origins := range cfg.RouteOrigins {
+		policy.routeOrigins[prefix] = parseOrigins(origins)
+	}
+	c.policy.Store(policy)
+}
+
+// CorsMiddleware applies a CORS configuration that never changes
+func CorsMiddleware(cfg config.CORSConfig, routes RouteMatcher) func(http.Handler) http.Handler {
+	return NewCORS(cfg, routes).Middleware
+}
+
+// Middleware applies the current CORS configuration
+func (c *CORS) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		policy := c.policy.Load()
+		w.Header().Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			// Not a cross-origin request
+			next.ServeHTTP(w, r)
+			return
+		}
 
-		// Handle the "Preflight" request (OPTIONS before real request)
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusNoContent)
+		allowAny, allowed := policy.allows(r.URL.Path, strings.ToLower(origin))
+		if !allowed {
+			util.ResponseWithError(w, r, http.StatusForbidden, util.CodeForbidden, "Origin "+origin+" is not allowed")
 			return
 		}
+
+		// Credentials are never allowed for "*", browsers reject them anyway
+		if allowAny {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if policy.config.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			policy.preflight(w, r)
+			return
+		}
+
+		if len(policy.config.ExposedHeaders) > 0 {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(policy.config.ExposedHeaders, ", "))
+		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// allows reports whether an origin is allowed on a path, and whether it is allowed as any origin.
+// The origins of the longest matching route prefix replace the default ones.
+func (p *corsPolicy) allows(path, origin string) (bool, bool) {
+	origins := p.origins
+	longest := -1
+	for prefix, routeOrigins := range p.routeOrigins {
+		if strings.HasPrefix(path, prefix) && len(prefix) > longest {
+			origins, longest = routeOrigins, len(prefix)
+		}
+	}
+
+	for _, pattern := range origins {
+		if pattern.matches(origin) {
+			return pattern.any, true
+		}
+	}
+	return false, false
+}
+
+// preflight answers a preflight request with the methods registered for its path
+func (p *corsPolicy) preflight(w http.ResponseWriter, r *http.Request) {
+	methods := p.registeredMethods(r)
+	if len(methods) == 0 {
+		util.ResponseWithError(w, r, http.StatusNotFound, util.CodeNotFound, "No route matches "+r.URL.Path)
+		return
+	}
+	if !slices.Contains(methods, r.Header.Get("Access-Control-Request-Method")) {
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		util.ResponseWithError(w, r, http.StatusMethodNotAllowed, util.CodeMethodNotAllowed, "Method "+r.Header.Get("Access-Control-Request-Method")+" is not allowed on "+r.URL.Path)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(corsAllowedHeaders, ", "))
+	if p.config.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(p.config.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// registeredMethods returns the methods with a route matching the path of the request
+func (p *corsPolicy) registeredMethods(r *http.Request) []string {
+	var methods []string
+	for _, method := range corsMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		if _, pattern := p.routes.Handler(probe); pattern != "" {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
@@ -1,15 +1,83 @@
 package middleware
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
 	"time"
 )
 
+const accessLogKey contextKey = "access_log"
+
+// accessLog collects request details set by inner handlers, such as the authenticated user
+type accessLog struct {
+	userID uint
+}
+
+// statusRecorder records the status code and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// LoggerMiddleware writes an access log entry for every request
 func LoggerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Printf("Request: %s %s %s [%s]", r.Method, r.URL.Path, r.RemoteAddr, time.Since(start).String())
+		entry := &accessLog{}
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), accessLogKey, entry)))
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", recorder.bytes),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("remote_addr", r.RemoteAddr),
+		}
+		if entry.userID != 0 {
+			attrs = append(attrs, slog.Uint64("user_id", uint64(entry.userID)))
+		}
+		slog.LogAttrs(r.Context(), level, "Request", attrs...)
 	})
 }
+
+// setAccessLogUser records the authenticated user in the access log of the request
+func setAccessLogUser(ctx context.Context, userID uint) {
+	if entry, ok := ctx.Value(accessLogKey).(*accessLog); ok {
+		entry.userID = userID
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_api/internal/metrics"
+)
+
+// unmatchedRoute labels requests that matched no route, so unknown paths do not create new series
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware records request counts, latencies and in-flight requests by route pattern in m.
+// It must wrap the http.ServeMux directly, which sets the pattern on the request it receives.
+func MetricsMiddleware(m *metrics.Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			m.HTTPRequestsInFlight.Inc()
+			defer m.HTTPRequestsInFlight.Dec()
+
+			recorder := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+
+			route := r.Pattern
+			if route == "" {
+				route = unmatchedRoute
+			}
+			status := recorder.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			m.HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+			m.HTTPRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+		})
+	}
+}
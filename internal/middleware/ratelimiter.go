@@ -1,14 +1,153 @@
 package middleware
 
 import (
+	"math"
+	"net"
 	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/go-chi/httprate"
-
 	"go_api/internal/config"
+	"go_api/internal/metrics"
+	"go_api/internal/ratelimit"
+	"go_api/internal/util"
 )
 
-func RateLimiterMiddleware(next http.Handler) http.Handler {
-	return httprate.LimitByIP(config.GlobalConfig.RateLimit, time.Minute)(next)
+// RateLimiter limits the request rate of clients
+type RateLimiter struct {
+	limiter ratelimit.Limiter
+	config  atomic.Pointer[config.RateLimitConfig]
+	secret  []byte
+	metrics *metrics.Metrics
+}
+
+// NewRateLimiter creates a rate limiter applying cfg and counting with limiter. secret verifies the
+// access tokens that identify users, and rejections are counted in m.
+func NewRateLimiter(limiter ratelimit.Limiter, cfg config.RateLimitConfig, secret []byte, m *metrics.Metrics) *RateLimiter {
+	l := &RateLimiter{limiter: limiter, secret: secret, metrics: m}
+	l.SetConfig(cfg)
+	return l
+}
+
+// SetConfig replaces the limits and the policies of the routes while the server runs. Requests already
+// counted in the window still count.
+func (l *RateLimiter) SetConfig(cfg config.RateLimitConfig) {
+	l.config.Store(&cfg)
+}
+
+// Middleware applies the global limit to every route, counted per user for authenticated requests
+// and per IP address otherwise
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy := ratelimit.Policy{Name: config.GlobalRateLimitPolicy, Limit: l.config.Load().PerMinute, Window: time.Minute}
+		if l.limitRequest(w, r, policy, l.rateLimitKey(r)) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// RouteMiddleware applies the policy configured for the route pattern on top of the global limit.
+// Policies count per IP address, so the tokens of other accounts buy no more login attempts, and
+// the routes sharing a policy share its count. Routes without a policy are only limited globally.
+func (l *RateLimiter) RouteMiddleware(pattern string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := l.config.Load()
+		name, ok := cfg.Routes[pattern]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		policy := ratelimit.Policy{Name: name, Limit: cfg.Policies[name], Window: time.Minute}
+		if l.limitRequest(w, r, policy, "ip:"+clientIP(r, cfg.TrustedProxies)) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// limitRequest counts the request against a policy and sets the RateLimit headers. It writes
+// a problem and returns false when the limit is exceeded.
+func (l *RateLimiter) limitRequest(w http.ResponseWriter, r *http.Request, policy ratelimit.Policy, key string) bool {
+	result, err := l.limiter.Allow(r.Context(), key, policy)
+	if err != nil {
+		// Both limiters failed, do not turn it into an outage
+		return true
+	}
+
+	resetSeconds := strconv.Itoa(int(math.Ceil(result.ResetAfter.Seconds())))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", resetSeconds)
+	w.Header().Set("RateLimit-Policy", strconv.Itoa(policy.Limit)+";w="+strconv.Itoa(int(policy.Window.Seconds())))
+
+	if !result.Allowed {
+		l.metrics.RateLimitRejections.WithLabelValues(policy.Name).Inc()
+		w.Header().Set("Retry-After", resetSeconds)
+		util.ResponseWithError(w, r, http.StatusTooManyRequests, util.CodeRateLimited, "Too many requests, retry in "+resetSeconds+" seconds")
+		return false
+	}
+	return true
+}
+
+// rateLimitKey identifies the client of a request: the user of a valid access token, or the IP address.
+// The blacklist is not checked here, a revoked token is rejected later by the auth middleware.
+func (l *RateLimiter) rateLimitKey(r *http.Request) string {
+	if token, err := util.ExtractTokenFromHeader(r.Header.Get("Authorization")); err == nil {
+		if claims, err := util.ParseToken(token, l.secret); err == nil {
+			return "user:" + strconv.FormatUint(uint64(claims.UserID), 10)
+		}
+	}
+	return "ip:" + clientIP(r, l.config.Load().TrustedProxies)
+}
+
+// clientIP returns the IP address of the client, without the port. IPv6 clients are limited by
+// their /64 prefix, since a single host usually gets a whole /64 to pick addresses from.
+func clientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+
+	addr = forwardedFor(r, addr.Unmap().WithZone(""), trustedProxies)
+	if addr.Is4() {
+		return addr.String()
+	}
+	prefix, _ := addr.Prefix(64)
+	return prefix.String()
+}
+
+// forwardedFor returns the client address of a request received from peer. Behind trusted proxies it is
+// the right-most X-Forwarded-For address that is not a trusted proxy: clients can send the header with
+// any address, but each trusted proxy appends the address it received the request from.
+func forwardedFor(r *http.Request, peer netip.Addr, trustedProxies []netip.Prefix) netip.Addr {
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	addr := peer
+	for i := len(hops) - 1; i >= 0 && trusted(addr, trustedProxies); i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Not written by a trusted proxy, count the request against the last one
+			break
+		}
+		addr = hop.Unmap().WithZone("")
+	}
+	return addr
+}
+
+func trusted(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
 }
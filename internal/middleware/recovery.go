@@ -1,21 +1,25 @@
 package middleware
 
 import (
-	"go_api/internal/util"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"runtime/debug"
+
+	"go_api/internal/util"
 )
 
-func RecoveryMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				msg := "Caught panic: %v, Stack trace: %s"
-				log.Printf(msg, err, string(debug.Stack()))
-				util.ResponseWithError(w, http.StatusInternalServerError, "Internal server error", err.(error).Error())
-			}
-		}()
-		next.ServeHTTP(w, r)
-	})
+// RecoveryMiddleware turns panics into internal error problems. exposeErrors returns the panic value to clients.
+func RecoveryMiddleware(exposeErrors bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if err := recover(); err != nil {
+					slog.ErrorContext(r.Context(), "Caught panic", "panic", err, "stack", string(debug.Stack()))
+					util.ResponseWithInternalError(w, r, fmt.Errorf("panic: %v", err), exposeErrors)
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go_api/internal/util"
+)
+
+// RequestIDMiddleware assigns every request an ID, stores it in the context and echoes it in the
+// X-Request-ID response header. A valid X-Request-ID from the client is kept; otherwise the trace ID
+// of a W3C traceparent header is used, or a new ID is generated.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		requestID := r.Header.Get(util.RequestIDHeader)
+		if !util.IsValidRequestID(requestID) {
+			requestID = ""
+		}
+
+		if trace, err := util.ParseTraceparent(r.Header.Get(util.TraceparentHeader)); err == nil {
+			ctx = util.WithTraceContext(ctx, trace)
+			if requestID == "" {
+				requestID = trace.TraceID
+			}
+		}
+
+		if requestID == "" {
+			requestID = util.NewRequestID()
+		}
+
+		w.Header().Set(util.RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(util.WithRequestID(ctx, requestID)))
+	})
+}
@@ -0,0 +1,49 @@
+package migration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var nameSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Create writes empty up and down files for a new migration in dir, numbered after the
+// latest one, and returns their paths
+func Create(dir, name string) ([]string, error) {
+	name = strings.Trim(nameSeparators.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, fmt.Errorf("migration name must contain letters or digits")
+	}
+
+	migrations, err := Load(os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+	var version int64 = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	var paths []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%04d_%s.%s.sql", version, name, direction))
+		content := fmt.Sprintf("-- %s migration %04d: %s\n", strings.ToUpper(direction[:1])+direction[1:], version, strings.ReplaceAll(name, "_", " "))
+		// O_EXCL so an existing migration is never overwritten
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return paths, fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		_, err = file.WriteString(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return paths, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,265 @@
+package migration
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Table records the applied migrations
+const Table = "schema_migrations"
+
+// lockKey is the Postgres advisory lock held while migrating, so replicas starting
+// at the same time do not apply the same migration twice
+const lockKey int64 = 7_316_220_514
+
+// State is a migration with whether and when it was applied
+type State struct {
+	Migration
+	AppliedAt *time.Time
+	Modified  bool // The up file changed after the migration was applied
+	Missing   bool // Applied, but unknown to this binary, like after a rollback of the deployment
+}
+
+// Migrator applies and reverts migrations on a Postgres database
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func New(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Status returns every known and applied migration, sorted by version
+func (m *Migrator) Status(ctx context.Context) ([]State, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	return m.states(applied), nil
+}
+
+// Pending returns the migrations that are not applied yet
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	states, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, state := range states {
+		if state.AppliedAt == nil {
+			pending = append(pending, state.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Version returns the latest applied version, 0 when none is applied
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	for v := range applied {
+		version = max(version, v)
+	}
+	return version, nil
+}
+
+// Up applies at most limit pending migrations in order, all of them when limit is 0,
+// and returns the applied ones
+func (m *Migrator) Up(ctx context.Context, limit int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, state := range m.states(applied) {
+			if state.Modified {
+				return fmt.Errorf("migration %d_%s was modified after it was applied", state.Version, state.Name)
+			}
+		}
+
+		for _, migration := range m.migrations {
+			if limit > 0 && len(done) == limit {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			err := m.run(ctx, conn, migration.Up,
+				"INSERT INTO "+Table+" (version, name, checksum) VALUES ($1, $2, $3)",
+				migration.Version, migration.Name, migration.Checksum())
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			slog.InfoContext(ctx, "Applied migration", "version", migration.Version, "name", migration.Name)
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the latest steps applied migrations and returns the reverted ones
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		states := m.states(applied)
+		for i := len(states) - 1; i >= 0 && len(done) < steps; i-- {
+			state := states[i]
+			if state.AppliedAt == nil {
+				continue
+			}
+			if state.Missing {
+				return fmt.Errorf("migration %d_%s is applied but unknown to this binary, it cannot be reverted", state.Version, state.Name)
+			}
+			err := m.run(ctx, conn, state.Down, "DELETE FROM "+Table+" WHERE version = $1", state.Version)
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", state.Version, state.Name, err)
+			}
+			slog.InfoContext(ctx, "Reverted migration", "version", state.Version, "name", state.Name)
+			done = append(done, state.Migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// locked runs fn on a connection holding the migration lock, after creating the migration table
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a database connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Advisory locks belong to the session, they must be taken and released on the same connection
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("failed to acquire the migration lock: %w", err)
+	}
+	defer func() {
+		if _, unlockErr := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockKey); unlockErr != nil {
+			// Do not return a connection still holding the lock to the pool
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+			err = errors.Join(err, fmt.Errorf("failed to release the migration lock: %w", unlockErr))
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+Table+` (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		checksum text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create the %s table: %w", Table, err)
+	}
+	return fn(conn)
+}
+
+// run executes the SQL of a migration and records it, in a single transaction unless
+// the migration opts out
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, statements, record string, args ...any) error {
+	if !InTransaction(statements) {
+		if _, err := conn.ExecContext(ctx, statements); err != nil {
+			return err
+		}
+		_, err := conn.ExecContext(ctx, record, args...)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, statements); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// applied returns the applied migrations by version, none when the migration table does not exist
+func (m *Migrator) applied(ctx context.Context, db queryer) (map[int64]appliedMigration, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", Table).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up the %s table: %w", Table, err)
+	}
+	applied := make(map[int64]appliedMigration)
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM "+Table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int64
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+// states merges the known migrations with the applied ones
+func (m *Migrator) states(applied map[int64]appliedMigration) []State {
+	states := make([]State, 0, len(m.migrations))
+	known := make(map[int64]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		state := State{Migration: migration}
+		if a, ok := applied[migration.Version]; ok {
+			state.AppliedAt = &a.appliedAt
+			state.Modified = a.checksum != migration.Checksum()
+		}
+		states = append(states, state)
+	}
+	for version, a := range applied {
+		if !known[version] {
+			states = append(states, State{
+				Migration: Migration{Version: version, Name: a.name},
+				AppliedAt: &a.appliedAt,
+				Missing:   true,
+			})
+		}
+	}
+	slices.SortFunc(states, func(a, b State) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return states
+}
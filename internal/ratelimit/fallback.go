@@ -0,0 +1,34 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// FallbackLimiter uses the primary limiter, and the fallback limiter while the primary fails
+type FallbackLimiter struct {
+	primary  Limiter
+	fallback Limiter
+	degraded atomic.Bool
+}
+
+func NewFallbackLimiter(primary, fallback Limiter) *FallbackLimiter {
+	return &FallbackLimiter{primary: primary, fallback: fallback}
+}
+
+func (l *FallbackLimiter) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	result, err := l.primary.Allow(ctx, key, policy)
+	if err == nil {
+		if l.degraded.CompareAndSwap(true, false) {
+			slog.InfoContext(ctx, "Rate limiter recovered")
+		}
+		return result, nil
+	}
+
+	// Log once per outage, not on every request
+	if l.degraded.CompareAndSwap(false, true) {
+		slog.WarnContext(ctx, "Rate limiter failed, falling back to in-memory limits", "error", err)
+	}
+	return l.fallback.Allow(ctx, key, policy)
+}
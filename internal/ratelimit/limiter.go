@@ -0,0 +1,26 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Policy is a number of requests allowed per sliding window
+type Policy struct {
+	Name   string // Part of the storage key, so policies count separately
+	Limit  int
+	Window time.Duration
+}
+
+// Result is the outcome of a rate limit check
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // Until the oldest counted request leaves the window
+}
+
+// Limiter counts requests per key in a sliding window
+type Limiter interface {
+	Allow(ctx context.Context, key string, policy Policy) (Result, error)
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often keys without requests in their window are dropped
+const sweepInterval = time.Minute
+
+type window struct {
+	requests []time.Time // Oldest first
+	length   time.Duration
+}
+
+// MemoryLimiter is a sliding window limiter local to the process
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*window
+	lastSweep time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		windows: make(map[string]*window),
+	}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+
+	key = policy.Name + ":" + key
+	w, ok := l.windows[key]
+	if !ok {
+		w = &window{}
+		l.windows[key] = w
+	}
+	w.length = policy.Window
+	w.expire(now)
+
+	allowed := len(w.requests) < policy.Limit
+	if allowed {
+		w.requests = append(w.requests, now)
+	}
+
+	resetAfter := policy.Window
+	if len(w.requests) > 0 {
+		resetAfter = w.requests[0].Add(policy.Window).Sub(now)
+	}
+	return Result{
+		Allowed:    allowed,
+		Limit:      policy.Limit,
+		Remaining:  policy.Limit - len(w.requests),
+		ResetAfter: resetAfter,
+	}, nil
+}
+
+// expire drops the requests that left the window
+func (w *window) expire(now time.Time) {
+	start := now.Add(-w.length)
+	i := 0
+	for i < len(w.requests) && !w.requests[i].After(start) {
+		i++
+	}
+	w.requests = w.requests[i:]
+}
+
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, w := range l.windows {
+		w.expire(now)
+		if len(w.requests) == 0 {
+			delete(l.windows, key)
+		}
+	}
+	l.lastSweep = now
+}
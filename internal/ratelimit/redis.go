@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript keeps the timestamps of the requests of a key in a sorted set.
+// It uses the Redis clock, so replicas with skewed clocks share the same window.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local member = ARGV[3]
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, member)
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local reset = window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, count, reset}
+`)
+
+// RedisLimiter is a sliding window limiter shared by every replica through Redis
+type RedisLimiter struct {
+	redis *redis.Client
+}
+
+func NewRedisLimiter(redis *redis.Client) *RedisLimiter {
+	return &RedisLimiter{redis: redis}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	member := make([]byte, 8)
+	rand.Read(member)
+
+	values, err := slidingWindowScript.Run(ctx, l.redis,
+		[]string{"ratelimit:" + policy.Name + ":" + key},
+		policy.Window.Milliseconds(), policy.Limit, hex.EncodeToString(member),
+	).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if len(values) != 3 {
+		return Result{}, fmt.Errorf("failed to check rate limit: unexpected reply %v", values)
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      policy.Limit,
+		Remaining:  policy.Limit - int(values[1]),
+		ResetAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
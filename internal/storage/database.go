@@ -1,62 +1,145 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 
-	"go_api/internal/app/model"
 	"go_api/internal/config"
+	"go_api/internal/migration"
+	"go_api/migrations"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-var DB *gorm.DB
+// Database is the primary connection, which sends reads to the read connections
+type Database struct {
+	Primary  *gorm.DB
+	Replicas []*gorm.DB // The pooler, then the replicas
 
-// Connect makes connection to database
-func Connect() error {
-	var gormLogger logger.Interface
-	if config.GlobalConfig.Environment == "development" {
-		gormLogger = logger.Default.LogMode(logger.Info)
-	} else {
-		gormLogger = logger.Default.LogMode(logger.Error)
-	}
+	routing *ReadReplicas // Set by Connect
+}
 
-	db, err := gorm.Open(postgres.Open(config.GlobalConfig.DatabaseURL), &gorm.Config{
-		Logger: gormLogger,
+// Connect connects to the primary database and to the read connections. writes tracks the users
+// whose reads stay on the primary after they write.
+func Connect(cfg *config.Config, writes WriteTracker) (*Database, error) {
+	db, err := gorm.Open(postgres.Open(cfg.Database.URL), &gorm.Config{
+		Logger: newGormLogger(slog.Default()),
+		// Report unique violations as gorm.ErrDuplicatedKey
+		TranslateError: true,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	database := &Database{Primary: db}
+	if err := registerTracingCallbacks(db); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("failed to register database tracing: %w", err)
 	}
 
-	DB = db
-	log.Println("Database connection established successfully")
-	return nil
+	// PgBouncer in transaction mode cannot keep prepared statements between transactions
+	pooler, err := connectReplica(cfg.Database.PoolerURL, true)
+	if err != nil {
+		database.Close()
+		return nil, fmt.Errorf("failed to connect to database pooler: %w", err)
+	}
+	database.Replicas = append(database.Replicas, pooler)
+	for i, url := range cfg.Database.ReplicaURLs {
+		replica, err := connectReplica(url, false)
+		if err != nil {
+			database.Close()
+			return nil, fmt.Errorf("failed to connect to database replica %d: %w", i+1, err)
+		}
+		database.Replicas = append(database.Replicas, replica)
+	}
+
+	database.routing = NewReadReplicas(database.Replicas, cfg.Database.ReadYourWritesWindow, writes)
+	if err := db.Use(database.routing); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("failed to register read replicas: %w", err)
+	}
+	slog.Info("Database connection established successfully", "replicas", len(database.Replicas))
+	return database, nil
 }
 
-// Close closes the database connection
-func Close() error {
-	sqlDB, err := DB.DB()
-	if err != nil {
-		return fmt.Errorf("failed to get database instance: %w", err)
+// connectReplica opens a read connection. Its queries run through the primary's session,
+// which logs and traces them, so it needs no callbacks of its own.
+func connectReplica(url string, simpleProtocol bool) (*gorm.DB, error) {
+	return gorm.Open(postgres.New(postgres.Config{
+		DSN:                  url,
+		PreferSimpleProtocol: simpleProtocol,
+	}), &gorm.Config{
+		Logger: newGormLogger(slog.Default()),
+	})
+}
+
+// SetReplicaUp marks the read connection at index i of Replicas up or down, down ones get no reads
+func (d *Database) SetReplicaUp(i int, up bool) {
+	if d.routing != nil {
+		d.routing.SetReplicaUp(i, up)
 	}
-	return sqlDB.Close()
 }
 
-// GetDB returns the database instance
-func GetDB() *gorm.DB {
-	return DB
+// Close closes the primary and read connections
+func (d *Database) Close() error {
+	var errs []error
+	for _, db := range append([]*gorm.DB{d.Primary}, d.Replicas...) {
+		sqlDB, err := db.DB()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get database instance: %w", err))
+			continue
+		}
+		errs = append(errs, sqlDB.Close())
+	}
+	return errors.Join(errs...)
 }
 
-// Migrate runs migrations for the database
-func Migrate() error {
-	err := DB.AutoMigrate(
-		&model.User{},
-		&model.Blog{},
-	)
+// NewMigrator returns the migrator of the embedded SQL migrations on db
+func NewMigrator(db *gorm.DB) (*migration.Migrator, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database is not connected")
+	}
+	sqlDB, err := db.DB()
 	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
+	}
+	migrations, err := migration.Load(migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+	return migration.New(sqlDB, migrations), nil
+}
+
+// Migrate applies the pending migrations
+func Migrate(ctx context.Context, db *gorm.DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(ctx, 0); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 	return nil
 }
+
+// CheckSchema returns the applied schema version, and an error when migrations are pending
+func CheckSchema(ctx context.Context, db *gorm.DB) (int64, error) {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return 0, err
+	}
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return version, err
+	}
+	if len(pending) > 0 {
+		return version, fmt.Errorf("%d migrations are pending, the latest is %d_%s", len(pending), pending[len(pending)-1].Version, pending[len(pending)-1].Name)
+	}
+	return version, nil
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold is the duration above which queries are logged as warnings
+const slowQueryThreshold = 200 * time.Millisecond
+
+// gormLogger routes GORM logs through slog. Queries are logged at debug level,
+// slow queries as warnings and failed queries as errors.
+type gormLogger struct {
+	logger *slog.Logger
+	level  logger.LogLevel
+}
+
+func newGormLogger(l *slog.Logger) *gormLogger {
+	return &gormLogger{logger: l, level: logger.Info}
+}
+
+func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		l.logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		l.logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		l.logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		sql, rows := fc()
+		l.logger.ErrorContext(ctx, "Query failed", "sql", sql, "rows", rows, "latency", elapsed, "error", err)
+	case elapsed > slowQueryThreshold && l.level >= logger.Warn:
+		sql, rows := fc()
+		l.logger.WarnContext(ctx, "Slow query", "sql", sql, "rows", rows, "latency", elapsed)
+	case l.level >= logger.Info && l.logger.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		l.logger.DebugContext(ctx, "Query", "sql", sql, "rows", rows, "latency", elapsed)
+	}
+}
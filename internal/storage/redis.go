@@ -2,8 +2,8 @@ package storage
 
 import (
 	"context"
-	"log"
-	"strconv"
+	"fmt"
+	"log/slog"
 
 	"go_api/internal/config"
 
@@ -11,23 +11,13 @@ import (
 	"github.com/redis/go-redis/v9/maintnotifications"
 )
 
-var RedisClient *redis.Client
-
-func ConnectRedis() *redis.Client {
-	// Get Redis config from config
-	addr := config.GlobalConfig.RedisAddr
-	password := config.GlobalConfig.RedisPassword
-	db := config.GlobalConfig.RedisDB
-	dbInt, err := strconv.Atoi(db)
-	if err != nil {
-		log.Fatalf("failed to convert Redis DB to int: %v", err)
-	}
-
+// ConnectRedis connects to Redis and checks the connection
+func ConnectRedis(cfg *config.Config) (*redis.Client, error) {
 	// Create Redis client
-	RedisClient = redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: password,
-		DB:       int(dbInt),
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
 		// Explicitly disable maintenance notifications
 		// This prevents the client from sending CLIENT MAINT_NOTIFICATIONS ON
 		MaintNotificationsConfig: &maintnotifications.Config{
@@ -35,16 +25,16 @@ func ConnectRedis() *redis.Client {
 		},
 	})
 
+	client.AddHook(redisLogger{logger: slog.Default()})
+	client.AddHook(redisTracer{})
+
 	// Test connection
-	if err := RedisClient.Ping(context.Background()).Err(); err != nil {
-		log.Fatalf("failed to ping Redis: %v", err)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
 	}
 
-	log.Println("Redis connection established successfully")
-
-	return RedisClient
-}
+	slog.Info("Redis connection established successfully")
 
-func GetRedisClient() *redis.Client {
-	return RedisClient
+	return client, nil
 }
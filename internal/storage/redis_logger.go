@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisLogger logs Redis commands through slog with the caller's context, so they carry its
+// request ID. Commands are logged at debug level and failed commands as errors.
+type redisLogger struct {
+	logger *slog.Logger
+}
+
+func (l redisLogger) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (l redisLogger) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		l.log(ctx, cmd.Name(), time.Since(start), err)
+		return err
+	}
+}
+
+func (l redisLogger) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		l.log(ctx, "pipeline", time.Since(start), err)
+		return err
+	}
+}
+
+func (l redisLogger) log(ctx context.Context, command string, elapsed time.Duration, err error) {
+	if err != nil && !errors.Is(err, redis.Nil) && !errors.Is(err, redis.TxFailedErr) {
+		l.logger.ErrorContext(ctx, "Redis command failed", "command", command, "latency", elapsed, "error", err)
+		return
+	}
+	l.logger.DebugContext(ctx, "Redis command", "command", command, "latency", elapsed)
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type readPreference int
+
+const (
+	readFromReplica readPreference = iota + 1
+	readFromPrimary
+)
+
+type readPreferenceKey struct{}
+
+type userKey struct{}
+
+// ReadFromReplica lets the queries of ctx read from a replica, unless ReadFromPrimary was set first
+func ReadFromReplica(ctx context.Context) context.Context {
+	if ctx.Value(readPreferenceKey{}) == readFromPrimary {
+		return ctx
+	}
+	return context.WithValue(ctx, readPreferenceKey{}, readFromReplica)
+}
+
+// ReadFromPrimary sends the queries of ctx to the primary, for reads that a write depends on
+func ReadFromPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readPreferenceKey{}, readFromPrimary)
+}
+
+// WithUser identifies the user of ctx, whose reads stick to the primary for a while after they write
+func WithUser(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+func userFrom(ctx context.Context) (uint, bool) {
+	userID, ok := ctx.Value(userKey{}).(uint)
+	return userID, ok && userID != 0
+}
+
+// ReadReplicas is a GORM plugin that routes the queries of ReadFromReplica contexts to the replicas,
+// in turn. Queries in transactions, and those of users who wrote within the window, use the primary
+// so users read their own writes despite replication lag. Replicas marked down are skipped, and reads
+// use the primary while every replica is down.
+type ReadReplicas struct {
+	replicas []*gorm.DB
+	down     []atomic.Bool
+	window   time.Duration
+	writes   WriteTracker
+	next     atomic.Uint64
+	degraded atomic.Bool // The write tracker fails
+}
+
+// NewReadReplicas returns the plugin routing reads to replicas, with the read-your-writes window.
+// writes must be shared by every instance of the API for users to read their writes on all of them.
+func NewReadReplicas(replicas []*gorm.DB, window time.Duration, writes WriteTracker) *ReadReplicas {
+	return &ReadReplicas{replicas: replicas, down: make([]atomic.Bool, len(replicas)), window: window, writes: writes}
+}
+
+func (p *ReadReplicas) Name() string {
+	return "read_replicas"
+}
+
+func (p *ReadReplicas) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Query().Before("gorm:query").Register("replicas:route_query", p.route),
+		callback.Row().Before("gorm:row").Register("replicas:route_row", p.route),
+		callback.Create().After("gorm:create").Register("replicas:record_create", p.recordWrite),
+		callback.Update().After("gorm:update").Register("replicas:record_update", p.recordWrite),
+		callback.Delete().After("gorm:delete").Register("replicas:record_delete", p.recordWrite),
+		callback.Raw().After("gorm:raw").Register("replicas:record_raw", p.recordWrite),
+	)
+}
+
+// SetReplicaUp marks the replica at index i up or down, as reported by its health check
+func (p *ReadReplicas) SetReplicaUp(i int, up bool) {
+	if i >= 0 && i < len(p.down) {
+		p.down[i].Store(!up)
+	}
+}
+
+func (p *ReadReplicas) route(db *gorm.DB) {
+	ctx := db.Statement.Context
+	if len(p.replicas) == 0 || ctx == nil || ctx.Value(readPreferenceKey{}) != readFromReplica {
+		return
+	}
+	if _, inTransaction := db.Statement.ConnPool.(gorm.TxCommitter); inTransaction {
+		return
+	}
+	if userID, ok := userFrom(ctx); ok && p.wroteRecently(ctx, userID) {
+		return
+	}
+
+	start := p.next.Add(1)
+	for i := range uint64(len(p.replicas)) {
+		index := (start + i) % uint64(len(p.replicas))
+		if !p.down[index].Load() {
+			db.Statement.ConnPool = p.replicas[index].Statement.ConnPool
+			return
+		}
+	}
+}
+
+func (p *ReadReplicas) recordWrite(db *gorm.DB) {
+	ctx := db.Statement.Context
+	if db.Error != nil || ctx == nil || p.window <= 0 {
+		return
+	}
+	userID, ok := userFrom(ctx)
+	if !ok {
+		return
+	}
+	p.trackerResult(ctx, p.writes.RecordWrite(ctx, userID, p.window))
+}
+
+// wroteRecently reports whether the user wrote within the window. When the tracker fails, the user
+// is taken to have written, reading from the primary is always consistent.
+func (p *ReadReplicas) wroteRecently(ctx context.Context, userID uint) bool {
+	wrote, err := p.writes.WroteRecently(ctx, userID)
+	p.trackerResult(ctx, err)
+	return wrote || err != nil
+}
+
+// trackerResult logs the failures of the write tracker once per outage, not on every query
+func (p *ReadReplicas) trackerResult(ctx context.Context, err error) {
+	if err == nil {
+		if p.degraded.CompareAndSwap(true, false) {
+			slog.InfoContext(ctx, "Read-your-writes tracking recovered")
+		}
+		return
+	}
+	if p.degraded.CompareAndSwap(false, true) {
+		slog.WarnContext(ctx, "Read-your-writes tracking failed, reading from the primary", "error", err)
+	}
+}
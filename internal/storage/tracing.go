@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"go_api/internal/tracing"
+)
+
+// gormSpanKey stores the span of a query in the GORM statement
+const gormSpanKey = "tracing:span"
+
+// registerTracingCallbacks creates a child span of the request for every GORM query
+func registerTracingCallbacks(db *gorm.DB) error {
+	callback := db.Callback()
+	registrations := []error{
+		callback.Create().Before("gorm:create").Register("tracing:before_create", startGormSpan("create")),
+		callback.Create().After("gorm:create").Register("tracing:after_create", endGormSpan),
+		callback.Query().Before("gorm:query").Register("tracing:before_query", startGormSpan("query")),
+		callback.Query().After("gorm:query").Register("tracing:after_query", endGormSpan),
+		callback.Update().Before("gorm:update").Register("tracing:before_update", startGormSpan("update")),
+		callback.Update().After("gorm:update").Register("tracing:after_update", endGormSpan),
+		callback.Delete().Before("gorm:delete").Register("tracing:before_delete", startGormSpan("delete")),
+		callback.Delete().After("gorm:delete").Register("tracing:after_delete", endGormSpan),
+		callback.Row().Before("gorm:row").Register("tracing:before_row", startGormSpan("row")),
+		callback.Row().After("gorm:row").Register("tracing:after_row", endGormSpan),
+		callback.Raw().Before("gorm:raw").Register("tracing:before_raw", startGormSpan("raw")),
+		callback.Raw().After("gorm:raw").Register("tracing:after_raw", endGormSpan),
+	}
+	return errors.Join(registrations...)
+}
+
+func startGormSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		_, span := tracing.Tracer().Start(ctx, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system.name", "postgresql"),
+				attribute.String("db.operation.name", operation),
+			),
+		)
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func endGormSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.query.text", db.Statement.SQL.String()),
+		attribute.Int64("db.response.returned_rows", db.Statement.RowsAffected),
+	)
+	if db.Statement.Table != "" {
+		span.SetAttributes(attribute.String("db.collection.name", db.Statement.Table))
+	}
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// redisTracer creates a child span of the request for every Redis command.
+// Arguments are left out, since they hold tokens.
+type redisTracer struct{}
+
+func (redisTracer) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (redisTracer) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := startRedisSpan(ctx, cmd.Name())
+		defer span.End()
+
+		err := next(ctx, cmd)
+		endRedisSpan(span, err)
+		return err
+	}
+}
+
+func (redisTracer) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := startRedisSpan(ctx, "pipeline")
+		defer span.End()
+		span.SetAttributes(attribute.Int("db.operation.batch.size", len(cmds)))
+
+		err := next(ctx, cmds)
+		endRedisSpan(span, err)
+		return err
+	}
+}
+
+func startRedisSpan(ctx context.Context, command string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "redis."+command,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "redis"),
+			attribute.String("db.operation.name", command),
+		),
+	)
+}
+
+func endRedisSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, redis.Nil) && !errors.Is(err, redis.TxFailedErr) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// WriteTracker remembers which users wrote within the read-your-writes window
+type WriteTracker interface {
+	RecordWrite(ctx context.Context, userID uint, window time.Duration) error
+	WroteRecently(ctx context.Context, userID uint) (bool, error)
+}
+
+// RedisWriteTracker keeps a key per user that expires with the window, so every instance
+// sends the reads of a user to the primary after any of them handled the user's write
+type RedisWriteTracker struct {
+	redis *redis.Client
+}
+
+func NewRedisWriteTracker(redis *redis.Client) *RedisWriteTracker {
+	return &RedisWriteTracker{redis: redis}
+}
+
+func writeKey(userID uint) string {
+	return "read_your_writes:user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+func (t *RedisWriteTracker) RecordWrite(ctx context.Context, userID uint, window time.Duration) error {
+	if err := t.redis.Set(ctx, writeKey(userID), 1, window).Err(); err != nil {
+		return fmt.Errorf("failed to record write: %w", err)
+	}
+	return nil
+}
+
+func (t *RedisWriteTracker) WroteRecently(ctx context.Context, userID uint) (bool, error) {
+	count, err := t.redis.Exists(ctx, writeKey(userID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to look up writes: %w", err)
+	}
+	return count > 0, nil
+}
+
+// MemoryWriteTracker keeps the writes in the process, for a single instance and for tests
+type MemoryWriteTracker struct {
+	mu          sync.Mutex
+	until       map[uint]time.Time // End of the window of each user
+	lastCleanup time.Time
+}
+
+func NewMemoryWriteTracker() *MemoryWriteTracker {
+	return &MemoryWriteTracker{until: make(map[uint]time.Time)}
+}
+
+func (t *MemoryWriteTracker) RecordWrite(ctx context.Context, userID uint, window time.Duration) error {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.until[userID] = now.Add(window)
+
+	// Forget users whose window is over, at most once per window
+	if now.Sub(t.lastCleanup) > window {
+		for id, until := range t.until {
+			if now.After(until) {
+				delete(t.until, id)
+			}
+		}
+		t.lastCleanup = now
+	}
+	return nil
+}
+
+func (t *MemoryWriteTracker) WroteRecently(ctx context.Context, userID uint) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	until, ok := t.until[userID]
+	return ok && !time.Now().After(until), nil
+}
@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName is the default service name of the traces, OTEL_SERVICE_NAME overrides it
+const ServiceName = "go_api"
+
+// instrumentationName names the tracer of the spans created by the API
+const instrumentationName = "go_api"
+
+// Exporters
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// Setup installs the global tracer provider and the W3C Trace Context propagator.
+// The OTLP exporter is configured by the standard OTEL_EXPORTER_OTLP_* variables and
+// sends traces over HTTP, to localhost:4318 by default. With ExporterNone, spans are not recorded.
+// The returned function flushes and stops the exporter.
+func Setup(ctx context.Context, exporter string, sampleRate float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", exporter, err)
+	}
+
+	// Later options override earlier ones, so OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", ServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRate))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of the API. It reads the global provider on every call,
+// so spans follow the provider installed by Setup.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
@@ -0,0 +1,35 @@
+package util
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Cursor marks the position after the last item of a page in a keyset-paginated list
+type Cursor struct {
+	Sort  string `json:"s"`  // Sort field the cursor was issued for
+	Desc  bool   `json:"d"`  // Sort direction the cursor was issued for
+	Value string `json:"v"`  // Sort value of the last item
+	ID    uint   `json:"id"` // ID of the last item, breaking ties between equal sort values
+}
+
+// EncodeCursor encodes a cursor as an opaque URL-safe string
+func EncodeCursor(cursor Cursor) string {
+	cursorJSON, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(cursorJSON)
+}
+
+// DecodeCursor decodes a cursor produced by EncodeCursor
+func DecodeCursor(encoded string) (*Cursor, error) {
+	cursorJSON, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(cursorJSON, &cursor); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,189 @@
+package util
+
+import (
+	"errors"
+	"strings"
+)
+
+// MaxDiffLines is the most lines both texts of a diff may have together. The diff takes time
+// proportional to the line count times the number of changes.
+const MaxDiffLines = 10000
+
+var ErrDiffTooLarge = errors.New("texts are too large to diff")
+
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffDelete DiffOp = "delete"
+	DiffInsert DiffOp = "insert"
+)
+
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffLines returns the line diff that turns text a into text b, using the linear space variant of
+// Myers' algorithm. Within each change, deleted lines come before inserted ones.
+func DiffLines(a, b string) ([]DiffLine, error) {
+	from, to := splitLines(a), splitLines(b)
+	if len(from)+len(to) > MaxDiffLines {
+		return nil, ErrDiffTooLarge
+	}
+	return groupChanges(diffRange(nil, from, to)), nil
+}
+
+// diffRange appends the diff of from and to to lines. Common ends are kept, the rest is split at
+// the middle snake of a shortest edit script and both halves are diffed in turn.
+func diffRange(lines []DiffLine, from, to []string) []DiffLine {
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	for _, text := range from[:prefix] {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: text})
+	}
+	from, to = from[prefix:], to[prefix:]
+
+	suffix := 0
+	for suffix < len(from) && suffix < len(to) && from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+	common := from[len(from)-suffix:]
+	from, to = from[:len(from)-suffix], to[:len(to)-suffix]
+
+	if x, y, ok := middleSnake(from, to); ok {
+		lines = diffRange(lines, from[:x], to[:y])
+		lines = diffRange(lines, from[x:], to[y:])
+	} else {
+		// One side is empty, or both are entirely different
+		for _, text := range from {
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: text})
+		}
+		for _, text := range to {
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: text})
+		}
+	}
+
+	for _, text := range common {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: text})
+	}
+	return lines
+}
+
+// middleSnake searches a shortest edit script from both ends at once and returns where the two
+// searches meet. It keeps only the furthest point of each diagonal, so it needs O(n+m) space.
+// ok is false when from and to have no line in common.
+func middleSnake(from, to []string) (x, y int, ok bool) {
+	n, m := len(from), len(to)
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	size := 2*maxD + 2
+	// forward[k+offset] is the furthest x reached on diagonal k from the start, backward the same from the end
+	forward, backward := make([]int, size), make([]int, size)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	// With an odd delta the forward search meets the backward one, otherwise the reverse
+	odd := delta%2 != 0
+	// Diagonals that ran off the edit graph are skipped
+	forwardStart, forwardEnd, backwardStart, backwardEnd := 0, 0, 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + forwardStart; k <= d-forwardEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -d || (k != d && forward[i-1] < forward[i+1]) {
+				x = forward[i+1] // Move down: insert
+			} else {
+				x = forward[i-1] + 1 // Move right: delete
+			}
+			y := x - k
+			for x < n && y < m && from[x] == to[y] {
+				x, y = x+1, y+1
+			}
+			forward[i] = x
+
+			switch {
+			case x > n:
+				forwardEnd += 2
+			case y > m:
+				forwardStart += 2
+			case odd:
+				j := offset + delta - k
+				if j >= 0 && j < size && backward[j] != -1 && x >= n-backward[j] {
+					return x, y, true
+				}
+			}
+		}
+
+		for k := -d + backwardStart; k <= d-backwardEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -d || (k != d && backward[i-1] < backward[i+1]) {
+				x = backward[i+1]
+			} else {
+				x = backward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && from[n-x-1] == to[m-y-1] {
+				x, y = x+1, y+1
+			}
+			backward[i] = x
+
+			switch {
+			case x > n:
+				backwardEnd += 2
+			case y > m:
+				backwardStart += 2
+			case !odd:
+				j := offset + delta - k
+				if j >= 0 && j < size && forward[j] != -1 {
+					forwardX := forward[j]
+					if forwardX >= n-x {
+						return forwardX, offset + forwardX - j, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// groupChanges moves the deleted lines of each run of changes before the inserted ones
+func groupChanges(lines []DiffLine) []DiffLine {
+	for start := 0; start < len(lines); {
+		if lines[start].Op == DiffEqual {
+			start++
+			continue
+		}
+		end := start
+		var inserted []DiffLine
+		for i := start; i < len(lines) && lines[i].Op != DiffEqual; i++ {
+			if lines[i].Op == DiffDelete {
+				lines[end] = lines[i]
+				end++
+			} else {
+				inserted = append(inserted, lines[i])
+			}
+		}
+		end += copy(lines[end:], inserted)
+		start = end
+	}
+	return lines
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// VersionETag formats a resource version as a strong ETag
+func VersionETag(version uint) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// IfMatch is a parsed If-Match header. The zero value, like a missing header or "*", matches any version.
+type IfMatch struct {
+	versions []uint
+	checked  bool // Only versions match, so nothing does when every listed tag was weak or unknown
+}
+
+// MatchVersions returns the precondition of an If-Match header listing the given versions
+func MatchVersions(versions ...uint) IfMatch {
+	return IfMatch{versions: versions, checked: true}
+}
+
+// Matches reports whether a resource at version passes the precondition
+func (m IfMatch) Matches(version uint) bool {
+	return !m.checked || slices.Contains(m.versions, version)
+}
+
+// ParseIfMatch parses an If-Match header following RFC 9110: "*" or a comma-separated list of entity tags.
+// Tags are compared strongly, so weak tags like W/"3" and tags that are not versions never match.
+// Only malformed headers, like unquoted tags, are errors.
+func ParseIfMatch(header string) (IfMatch, error) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return IfMatch{}, nil
+	}
+
+	match := IfMatch{checked: true}
+	listed := false
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			// Empty list elements are allowed
+			continue
+		}
+		listed = true
+
+		weak := strings.HasPrefix(tag, "W/")
+		opaque := strings.TrimPrefix(tag, "W/")
+		if len(opaque) < 2 || opaque[0] != '"' || opaque[len(opaque)-1] != '"' || strings.Contains(opaque[1:len(opaque)-1], `"`) {
+			return IfMatch{}, fmt.Errorf("entity tag must be quoted: %s", tag)
+		}
+		if weak {
+			continue
+		}
+		if version, err := strconv.ParseUint(opaque[1:len(opaque)-1], 10, 64); err == nil && version > 0 {
+			match.versions = append(match.versions, uint(version))
+		}
+	}
+	if !listed {
+		return IfMatch{}, errors.New("no entity tag")
+	}
+	return match, nil
+}
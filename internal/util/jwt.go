@@ -2,25 +2,38 @@ package util
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// AccessTokenTTL is the lifetime of an access token
+const AccessTokenTTL = time.Minute * 15
+
 type UserClaims struct {
-	Username string `json:"username"`
-	UserID   uint   `json:"userId"`
+	Username    string   `json:"username"`
+	UserID      uint     `json:"userId"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a new JWT token
-func GenerateToken(userID uint, username string, secret []byte) (string, error) {
+// HasPermission reports whether the claims grant the given permission
+func (c *UserClaims) HasPermission(permission string) bool {
+	return slices.Contains(c.Permissions, permission)
+}
+
+// GenerateToken generates a new short-lived JWT access token
+func GenerateToken(userID uint, username string, role string, permissions []string, secret []byte) (string, error) {
 	claims := UserClaims{
-		Username: username,
-		UserID:   userID,
+		Username:    username,
+		UserID:      userID,
+		Role:        role,
+		Permissions: permissions,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -29,6 +42,21 @@ func GenerateToken(userID uint, username string, secret []byte) (string, error)
 	return token.SignedString(secret)
 }
 
+// ParseToken parses and verifies a JWT access token
+func ParseToken(tokenString string, secret []byte) (*UserClaims, error) {
+	claims := &UserClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return secret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
 // ExtractTokenFromHeader extracts the token from the Authorization header
 func ExtractTokenFromHeader(authHeader string) (string, error) {
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ParseLogLevel parses a LOG_LEVEL value: debug, info, warn or error
+func ParseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", level)
+}
+
+// NewLogger creates the application logger. It writes text in development and JSON elsewhere.
+// A *slog.LevelVar level can be changed while the logger runs.
+func NewLogger(w io.Writer, environment string, level slog.Leveler) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	if environment == "development" {
+		return slog.New(NewContextHandler(slog.NewTextHandler(w, options)))
+	}
+	return slog.New(NewContextHandler(slog.NewJSONHandler(w, options)))
+}
+
+// contextHandler adds the request ID and trace ID of the context to every record. The trace ID comes
+// from the current span when tracing is on, and from the traceparent header of the request otherwise.
+type contextHandler struct {
+	slog.Handler
+}
+
+// NewContextHandler wraps h so records logged with a request context carry its request and trace IDs
+func NewContextHandler(h slog.Handler) slog.Handler {
+	return contextHandler{h}
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	} else if traceContext, ok := TraceContextFrom(ctx); ok {
+		record.AddAttrs(slog.String("trace_id", traceContext.TraceID))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,37 @@
+package util
+
+import "encoding/json"
+
+// MergePatch applies a JSON Merge Patch (RFC 7396) to a JSON document
+func MergePatch(document, patch []byte) ([]byte, error) {
+	var target, patchValue interface{}
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(target, patchValue))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		// A non-object patch replaces the target entirely
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
@@ -1,7 +1,7 @@
 package util
 
 import (
-	"log"
+	"log/slog"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -10,7 +10,7 @@ import (
 func HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		log.Printf("Error hashing password: %v", err)
+		slog.Error("Error hashing password", "error", err)
 		return "", err
 	}
 	return string(hashedPassword), nil
@@ -0,0 +1,82 @@
+package util
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Codes of problems raised outside the services. Codes are stable, so clients can match on them.
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeInvalidBody          = "invalid_body"
+	CodeBodyTooLarge         = "body_too_large"
+	CodeValidationFailed     = "validation_failed"
+	CodeInvalidQuery         = "invalid_query"
+	CodeUnauthorized         = "unauthorized"
+	CodeInvalidToken         = "invalid_token"
+	CodeTokenRevoked         = "token_revoked"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodePreconditionRequired = "precondition_required"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeRateLimited          = "rate_limited"
+	CodeInternal             = "internal_error"
+)
+
+// ProblemTypeBase is prefixed to problem codes to form their type URI
+const ProblemTypeBase = "/problems/"
+
+// Problem is an RFC 7807 problem details response, extended with a stable code and the request ID
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"` // Fields that failed validation
+}
+
+// NewProblem builds the problem for a request. detail must be safe to show to clients.
+func NewProblem(r *http.Request, status int, code string, detail string) Problem {
+	requestID := RequestIDFromRequest(r)
+
+	return Problem{
+		Type:      ProblemTypeBase + code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.RequestURI(),
+		Code:      code,
+		RequestID: requestID,
+	}
+}
+
+// ResponseWithProblem writes a problem details response to the client
+func ResponseWithProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// ResponseWithError writes a problem with a stable code to the client. detail must be safe to show to clients.
+func ResponseWithError(w http.ResponseWriter, r *http.Request, statusCode int, code string, detail string) {
+	ResponseWithProblem(w, NewProblem(r, statusCode, code, detail))
+}
+
+// ResponseWithInternalError logs err and writes an internal error problem. The error text can hold
+// database or library internals, so it is only returned when expose is set.
+func ResponseWithInternalError(w http.ResponseWriter, r *http.Request, err error, expose bool) {
+	problem := NewProblem(r, http.StatusInternalServerError, CodeInternal, "")
+	slog.ErrorContext(r.Context(), "Internal error", "method", r.Method, "path", r.URL.Path, "error", err)
+
+	if expose {
+		problem.Detail = err.Error()
+	}
+	ResponseWithProblem(w, problem)
+}
@@ -0,0 +1,123 @@
+package util
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	RequestIDHeader   = "X-Request-ID"
+	TraceparentHeader = "traceparent"
+)
+
+// maxRequestIDLength bounds client-supplied request IDs, which end up in every log line
+const maxRequestIDLength = 128
+
+var errInvalidTraceparent = errors.New("invalid traceparent")
+
+// TraceContext is a W3C Trace Context traceparent header
+type TraceContext struct {
+	Version  string
+	TraceID  string
+	ParentID string
+	Flags    string
+}
+
+// String formats the trace context as a traceparent header value
+func (t TraceContext) String() string {
+	return t.Version + "-" + t.TraceID + "-" + t.ParentID + "-" + t.Flags
+}
+
+type requestIDKey struct{}
+
+type traceContextKey struct{}
+
+// WithRequestID returns a copy of ctx holding the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, or an empty string
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDFromRequest returns the request ID stored in the request context, or the X-Request-ID header
+func RequestIDFromRequest(r *http.Request) string {
+	if id := RequestID(r.Context()); id != "" {
+		return id
+	}
+	return r.Header.Get(RequestIDHeader)
+}
+
+// WithTraceContext returns a copy of ctx holding the trace context of the request
+func WithTraceContext(ctx context.Context, trace TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, trace)
+}
+
+// TraceContextFrom returns the trace context stored in ctx
+func TraceContextFrom(ctx context.Context) (TraceContext, bool) {
+	trace, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return trace, ok
+}
+
+// NewRequestID generates a random request ID, formatted like a trace ID
+func NewRequestID() string {
+	return randomHex(16)
+}
+
+// IsValidRequestID reports whether a client-supplied request ID is safe to log and echo back
+func IsValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTraceparent parses a traceparent header as defined by W3C Trace Context
+func ParseTraceparent(header string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 {
+		return TraceContext{}, errInvalidTraceparent
+	}
+
+	trace := TraceContext{Version: parts[0], TraceID: parts[1], ParentID: parts[2], Flags: parts[3]}
+	switch {
+	case !isLowerHex(trace.Version, 2) || trace.Version == "ff",
+		trace.Version == "00" && len(parts) != 4,
+		!isLowerHex(trace.TraceID, 32) || trace.TraceID == strings.Repeat("0", 32),
+		!isLowerHex(trace.ParentID, 16) || trace.ParentID == strings.Repeat("0", 16),
+		!isLowerHex(trace.Flags, 2):
+		return TraceContext{}, errInvalidTraceparent
+	}
+	return trace, nil
+}
+
+// isLowerHex reports whether s is made of n lowercase hexadecimal digits
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
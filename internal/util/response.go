@@ -6,13 +6,9 @@ import (
 )
 
 type SuccessResponse struct {
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
-}
-
-type ErrorResponse struct {
-	Message string `json:"message"`
-	Error   string `json:"error,omitempty"`
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
 }
 
 // ResponseWithSuccess writes a success response to the client
@@ -25,12 +21,13 @@ func ResponseWithSuccess(w http.ResponseWriter, statusCode int, message string,
 	})
 }
 
-// ResponseWithError writes an error response to the client
-func ResponseWithError(w http.ResponseWriter, statusCode int, message string, error string) {
+// ResponseWithPage writes a success response holding one page of a list and the cursor of the next page
+func ResponseWithPage(w http.ResponseWriter, statusCode int, message string, data interface{}, nextCursor string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(ErrorResponse{
-		Message: message,
-		Error:   error,
+	json.NewEncoder(w).Encode(SuccessResponse{
+		Message:    message,
+		Data:       data,
+		NextCursor: nextCursor,
 	})
 }
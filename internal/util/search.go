@@ -0,0 +1,51 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ParseSearchQuery converts a user search string into a Postgres to_tsquery expression.
+// Words are ANDed together, "quoted phrases" must appear in order and a trailing * makes
+// a word a prefix match. It returns an empty string if the input has no searchable words.
+func ParseSearchQuery(q string) string {
+	var terms []string
+
+	parts := strings.Split(q, `"`)
+	for i, part := range parts {
+		// Odd parts sit between quotes
+		if i%2 == 1 {
+			if words := searchWords(part); len(words) > 0 {
+				terms = append(terms, phrase(words))
+			}
+			continue
+		}
+
+		for _, token := range strings.Fields(part) {
+			words := searchWords(token)
+			if len(words) == 0 {
+				continue
+			}
+			if strings.HasSuffix(token, "*") {
+				words[len(words)-1] += ":*"
+			}
+			terms = append(terms, phrase(words))
+		}
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// searchWords splits text into words made of letters and digits only
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func phrase(words []string) string {
+	if len(words) == 1 {
+		return words[0]
+	}
+	return "(" + strings.Join(words, " <-> ") + ")"
+}
@@ -0,0 +1,23 @@
+package util
+
+import "strings"
+
+// NormalizeTag lowercases a tag, trims it and joins its words with single hyphens
+func NormalizeTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+}
+
+// NormalizeTags normalizes tags, dropping empty ones and duplicates while keeping their order
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
@@ -0,0 +1,33 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// RefreshTokenTTL is how long a refresh token stays valid without being used
+const RefreshTokenTTL = time.Hour * 24 * 30
+
+// GenerateOpaqueToken generates a random URL-safe token of n random bytes
+func GenerateOpaqueToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GenerateRefreshToken generates a new opaque refresh token
+func GenerateRefreshToken() (string, error) {
+	return GenerateOpaqueToken(32)
+}
+
+// HashToken returns the SHA-256 hex digest of a token, so raw tokens are never stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
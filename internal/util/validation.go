@@ -2,22 +2,157 @@ package util
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
 	"strings"
+	"unicode"
 
 	"github.com/go-playground/validator/v10"
 )
 
-var validate = validator.New()
+// UsernamePattern is the character set allowed by the username rule
+const UsernamePattern = `^[a-zA-Z0-9_-]+$`
 
-// Validate validates the request body
+var usernameRegexp = regexp.MustCompile(UsernamePattern)
+
+var validate = newValidator()
+
+// ruleMessages holds the messages of custom rules, keyed by tag
+var ruleMessages = map[string]string{}
+
+// FieldError is a single failed rule, keyed by the JSON path of the field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors lists every field that failed validation
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Field + " " + fe.Message
+	}
+	return strings.Join(messages, ", ")
+}
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+
+	// Report fields by their JSON name
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+func init() {
+	RegisterValidation("username", "must only contain letters, numbers, underscores and hyphens", func(fl validator.FieldLevel) bool {
+		return usernameRegexp.MatchString(fl.Field().String())
+	})
+	RegisterValidation("password", "must contain an uppercase letter, a lowercase letter and a number", func(fl validator.FieldLevel) bool {
+		return IsStrongPassword(fl.Field().String())
+	})
+}
+
+// RegisterValidation adds a custom rule to the shared validator. message is reported for fields failing it.
+func RegisterValidation(tag string, message string, fn validator.Func) {
+	if err := validate.RegisterValidation(tag, fn); err != nil {
+		panic(fmt.Sprintf("validation: cannot register rule %q: %v", tag, err))
+	}
+	ruleMessages[tag] = message
+}
+
+// IsStrongPassword reports whether a password mixes upper and lower case letters and numbers
+func IsStrongPassword(password string) bool {
+	var upper, lower, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
+
+// Validate validates the request body. Failed rules are returned as ValidationErrors.
 func Validate(i interface{}) error {
 	err := validate.Struct(i)
-	if err != nil {
-		var errorMessages []string
-		for _, err := range err.(validator.ValidationErrors) {
-			errorMessages = append(errorMessages, err.Error())
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+
+	result := make(ValidationErrors, len(fieldErrors))
+	for i, fe := range fieldErrors {
+		result[i] = FieldError{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: fieldMessage(fe),
 		}
-		return errors.New(strings.Join(errorMessages, ", "))
 	}
-	return nil
+	return result
+}
+
+// fieldPath returns the JSON path of a field, like "tags[0]", without the name of the validated struct
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+// fieldMessage returns a human-readable message for a failed rule
+func fieldMessage(fe validator.FieldError) string {
+	if message, ok := ruleMessages[fe.Tag()]; ok {
+		return message
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_if":
+		field, value, _ := strings.Cut(fe.Param(), " ")
+		return fmt.Sprintf("is required when %s is %s", strings.ToLower(field), value)
+	case "email":
+		return "must be a valid email address"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min", "gte":
+		return sizeMessage(fe, "at least")
+	case "max", "lte":
+		return sizeMessage(fe, "at most")
+	case "len":
+		return sizeMessage(fe, "exactly")
+	}
+	return "is invalid"
+}
+
+// sizeMessage describes a size rule in the unit of the field: characters, items or a value
+func sizeMessage(fe validator.FieldError, bound string) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("must be %s %s characters long", bound, fe.Param())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("must have %s %s items", bound, fe.Param())
+	}
+	return fmt.Sprintf("must be %s %s", bound, fe.Param())
 }
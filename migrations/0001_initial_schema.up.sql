@@ -0,0 +1,94 @@
+-- Schema previously created by GORM AutoMigrate. Fresh databases get every table here.
+-- Databases created by AutoMigrate before migrations keep their users and blogs tables,
+-- which gain the columns added since, with the same defaults, before they are indexed.
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    username text NOT NULL,
+    email text NOT NULL,
+    password text NOT NULL,
+    role varchar(20) NOT NULL DEFAULT 'user',
+    permissions text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role varchar(20) NOT NULL DEFAULT 'user',
+    ADD COLUMN IF NOT EXISTS permissions text;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at);
+
+CREATE TABLE IF NOT EXISTS blogs (
+    id bigserial PRIMARY KEY,
+    title text NOT NULL,
+    content text,
+    user_id bigint NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'published',
+    published_at timestamptz,
+    version bigint NOT NULL DEFAULT 1,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_blogs_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+ALTER TABLE blogs
+    ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'published',
+    ADD COLUMN IF NOT EXISTS published_at timestamptz,
+    ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS idx_blogs_user_id ON blogs (user_id);
+CREATE INDEX IF NOT EXISTS idx_blogs_status ON blogs (status);
+CREATE INDEX IF NOT EXISTS idx_blogs_published_at ON blogs (published_at);
+CREATE INDEX IF NOT EXISTS idx_blogs_created_at ON blogs (created_at);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id bigserial PRIMARY KEY,
+    name varchar(50) NOT NULL,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_name ON tags (name);
+
+CREATE TABLE IF NOT EXISTS blog_tags (
+    blog_id bigint,
+    tag_id bigint,
+    PRIMARY KEY (blog_id, tag_id),
+    CONSTRAINT fk_blog_tags_blog FOREIGN KEY (blog_id) REFERENCES blogs (id) ON DELETE CASCADE,
+    CONSTRAINT fk_blog_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS blog_revisions (
+    id bigserial PRIMARY KEY,
+    blog_id bigint NOT NULL,
+    version bigint NOT NULL,
+    title text NOT NULL,
+    content text,
+    status varchar(20) NOT NULL,
+    published_at timestamptz,
+    replaced_by bigint,
+    created_at timestamptz,
+    CONSTRAINT fk_blog_revisions_blog FOREIGN KEY (blog_id) REFERENCES blogs (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_blog_revisions_blog_version ON blog_revisions (blog_id, version);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id bigserial PRIMARY KEY,
+    blog_id bigint NOT NULL,
+    parent_id bigint,
+    root_id bigint,
+    depth bigint NOT NULL DEFAULT 0,
+    user_id bigint NOT NULL,
+    content text NOT NULL,
+    edited_at timestamptz,
+    deleted_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_comments_blog FOREIGN KEY (blog_id) REFERENCES blogs (id) ON DELETE CASCADE,
+    CONSTRAINT fk_comments_parent FOREIGN KEY (parent_id) REFERENCES comments (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_comments_blog_id ON comments (blog_id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments (parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_root_id ON comments (root_id);
+CREATE INDEX IF NOT EXISTS idx_comments_user_id ON comments (user_id);
+CREATE INDEX IF NOT EXISTS idx_comments_created_at ON comments (created_at);
+
+-- Blogs created before statuses existed are published since their creation
+UPDATE blogs SET published_at = created_at WHERE status = 'published' AND published_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_blogs_search_vector;
+ALTER TABLE blogs DROP COLUMN IF EXISTS search_vector;
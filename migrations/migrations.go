@@ -0,0 +1,8 @@
+// Package migrations embeds the versioned SQL migrations of the database schema.
+// Create new ones with `make migrate-create name=...`, applied migrations must not be edited.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,129 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go_api/internal/app"
+	"go_api/internal/config"
+	"go_api/internal/storage"
+	"go_api/internal/util"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestApp creates an app on a database that records queries and a Redis server that is down
+func newTestApp(t *testing.T, rateLimit int, secret string) *app.App {
+	db := &storage.Database{Primary: openRecordingDB(t, "primary", &[]string{})}
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialerRetries: 1})
+	t.Cleanup(func() { client.Close() })
+
+	cfg := &config.Config{
+		Environment: "test",
+		Database:    config.DatabaseConfig{MigrationMode: "off"},
+		Auth:        config.AuthConfig{JWTSecretKey: secret},
+		RateLimit:   config.RateLimitConfig{PerMinute: rateLimit},
+		Scheduler:   config.SchedulerConfig{Interval: time.Hour},
+		Health:      config.HealthConfig{Timeout: time.Second},
+	}
+	application, err := app.New(cfg, app.WithDatabase(db), app.WithRedis(client))
+	require.NoError(t, err)
+	t.Cleanup(func() { application.Close() })
+	return application
+}
+
+func TestApp(t *testing.T) {
+	serve := func(a *app.App, path, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		a.Handler().ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("should apply the rate limit of its own config", func(t *testing.T) {
+		strict := newTestApp(t, 1, "strict-secret")
+		lenient := newTestApp(t, 3, "lenient-secret")
+
+		serve(strict, "/health/live", "")
+		serve(lenient, "/health/live", "")
+
+		assert.Equal(t, http.StatusTooManyRequests, serve(strict, "/health/live", "").Code)
+		assert.Equal(t, http.StatusOK, serve(lenient, "/health/live", "").Code)
+	})
+
+	t.Run("should verify tokens with its own secret", func(t *testing.T) {
+		first := newTestApp(t, 10, "first-secret")
+		second := newTestApp(t, 10, "second-secret")
+		token, err := util.GenerateToken(1, "alice", "user", nil, []byte("first-secret"))
+		require.NoError(t, err)
+
+		rejected := serve(second, "/users/profile", token)
+		accepted := serve(first, "/users/profile", token)
+
+		assert.Equal(t, http.StatusUnauthorized, rejected.Code)
+		assert.Contains(t, rejected.Body.String(), util.CodeInvalidToken)
+		assert.NotEqual(t, http.StatusUnauthorized, accepted.Code)
+	})
+
+	t.Run("should report its own readiness", func(t *testing.T) {
+		started := newTestApp(t, 10, "secret")
+		idle := newTestApp(t, 10, "secret")
+
+		started.Start()
+
+		assert.Equal(t, http.StatusOK, serve(started, "/health", "").Code)
+		assert.Equal(t, http.StatusServiceUnavailable, serve(idle, "/health", "").Code)
+
+		started.Drain()
+		assert.Equal(t, http.StatusServiceUnavailable, serve(started, "/health", "").Code)
+	})
+
+	t.Run("should apply reloaded rate limits and CORS policy", func(t *testing.T) {
+		application := newTestApp(t, 1, "secret")
+		serve(application, "/health/live", "")
+		require.Equal(t, http.StatusTooManyRequests, serve(application, "/health/live", "").Code)
+
+		cfg := config.Default()
+		cfg.RateLimit.PerMinute = 5
+		cfg.CORS.AllowedOrigins = []string{"https://app.example.com"}
+		application.Reload(cfg)
+
+		assert.Equal(t, http.StatusOK, serve(application, "/health/live", "").Code)
+		r := httptest.NewRequest(http.MethodGet, "/health/live", nil)
+		r.Header.Set("Origin", "https://other.example.com")
+		w := httptest.NewRecorder()
+		application.Handler().ServeHTTP(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("should count its requests in its own metrics", func(t *testing.T) {
+		busy := newTestApp(t, 10, "secret")
+		idle := newTestApp(t, 10, "secret")
+		serve(busy, "/health/live", "")
+		serve(busy, "/health/live", "")
+
+		series := `go_api_http_requests_total{method="GET",route="GET /health/live",status="200"}`
+		assert.Contains(t, serve(busy, "/metrics", "").Body.String(), series+" 2")
+		assert.NotContains(t, serve(idle, "/metrics", "").Body.String(), series)
+	})
+
+	t.Run("should refuse request bodies over the limit", func(t *testing.T) {
+		application := newTestApp(t, 10, "secret")
+		body := `{"username":"` + strings.Repeat("a", 2<<20) + `"}`
+		r := httptest.NewRequest(http.MethodPost, "/users/register", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		application.Handler().ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Contains(t, w.Body.String(), util.CodeBodyTooLarge)
+	})
+}
@@ -0,0 +1,73 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go_api/internal/app/model"
+	"go_api/internal/middleware"
+	"go_api/internal/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRolePermissions(t *testing.T) {
+	t.Run("should grant admin permissions to admin role", func(t *testing.T) {
+		assert.Contains(t, model.RoleAdmin.Permissions(), model.PermReadUsers)
+		assert.Contains(t, model.RoleAdmin.Permissions(), model.PermManageBlogs)
+	})
+
+	t.Run("should not grant admin permissions to user role", func(t *testing.T) {
+		assert.NotContains(t, model.RoleUser.Permissions(), model.PermReadUsers)
+		assert.NotContains(t, model.RoleUser.Permissions(), model.PermManageBlogs)
+	})
+
+	t.Run("should reject unknown role", func(t *testing.T) {
+		assert.False(t, model.Role("superuser").IsValid())
+	})
+
+	t.Run("should combine role permissions with extra grants", func(t *testing.T) {
+		user := model.User{Role: model.RoleUser, Permissions: []model.Permission{model.PermReadUsers}}
+
+		permissions := user.EffectivePermissions()
+
+		assert.ElementsMatch(t, []model.Permission{model.PermWriteBlogs, model.PermWriteComments, model.PermReadUsers}, permissions)
+	})
+}
+
+func TestRequirePermission(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := middleware.RequirePermission(model.PermReadUsers)(next)
+
+	serve := func(claims *util.UserClaims) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/users/", nil)
+		if claims != nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserClaimsKey, claims))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("should allow claims with permission", func(t *testing.T) {
+		w := serve(&util.UserClaims{Permissions: []string{string(model.PermReadUsers)}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should forbid claims without permission", func(t *testing.T) {
+		w := serve(&util.UserClaims{Permissions: []string{string(model.PermWriteBlogs)}})
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("should reject request without claims", func(t *testing.T) {
+		w := serve(nil)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,48 @@
+package unit
+
+import (
+	"testing"
+
+	"go_api/internal/app/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlogIsPublic(t *testing.T) {
+	t.Run("should be public when published", func(t *testing.T) {
+		blog := model.Blog{Status: model.BlogStatusPublished}
+
+		assert.True(t, blog.IsPublic())
+	})
+
+	t.Run("should not be public otherwise", func(t *testing.T) {
+		for _, status := range []model.BlogStatus{model.BlogStatusDraft, model.BlogStatusScheduled, model.BlogStatusArchived} {
+			blog := model.Blog{Status: status}
+
+			assert.False(t, blog.IsPublic(), status)
+		}
+	})
+}
+
+func TestBlogVisibleTo(t *testing.T) {
+	draft := model.Blog{UserID: 1, Status: model.BlogStatusDraft}
+	published := model.Blog{UserID: 1, Status: model.BlogStatusPublished}
+
+	t.Run("should show published blogs to everyone", func(t *testing.T) {
+		assert.True(t, published.VisibleTo(0, false))
+		assert.True(t, published.VisibleTo(2, false))
+	})
+
+	t.Run("should show unpublished blogs to their owner", func(t *testing.T) {
+		assert.True(t, draft.VisibleTo(1, false))
+	})
+
+	t.Run("should show unpublished blogs to viewers who can see every blog", func(t *testing.T) {
+		assert.True(t, draft.VisibleTo(2, true))
+	})
+
+	t.Run("should hide unpublished blogs from others", func(t *testing.T) {
+		assert.False(t, draft.VisibleTo(0, false))
+		assert.False(t, draft.VisibleTo(2, false))
+	})
+}
@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"go_api/internal/app/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func reply(id, parentID, rootID uint) model.Comment {
+	return model.Comment{ID: id, ParentID: &parentID, RootID: &rootID}
+}
+
+func TestBuildCommentTree(t *testing.T) {
+	t.Run("should nest replies under their parents", func(t *testing.T) {
+		roots := []model.Comment{{ID: 1}, {ID: 2}}
+		replies := []model.Comment{reply(3, 1, 1), reply(4, 3, 1), reply(5, 2, 2), reply(6, 1, 1)}
+
+		tree := model.BuildCommentTree(roots, replies, nil)
+
+		assert.Len(t, tree, 2)
+		assert.Equal(t, uint(1), tree[0].ID)
+		assert.Len(t, tree[0].Replies, 2)
+		assert.Equal(t, uint(3), tree[0].Replies[0].ID)
+		assert.Equal(t, uint(6), tree[0].Replies[1].ID)
+		assert.Equal(t, uint(4), tree[0].Replies[0].Replies[0].ID)
+		assert.Equal(t, uint(5), tree[1].Replies[0].ID)
+	})
+
+	t.Run("should keep deleted comments that have replies", func(t *testing.T) {
+		deletedAt := time.Now()
+		roots := []model.Comment{{ID: 1, DeletedAt: &deletedAt}}
+		replies := []model.Comment{reply(2, 1, 1)}
+
+		tree := model.BuildCommentTree(roots, replies, nil)
+
+		assert.Len(t, tree, 1)
+		assert.True(t, tree[0].IsDeleted())
+		assert.Len(t, tree[0].Replies, 1)
+	})
+
+	t.Run("should drop deleted comments without replies", func(t *testing.T) {
+		deletedAt := time.Now()
+		roots := []model.Comment{{ID: 1}, {ID: 2, DeletedAt: &deletedAt}}
+		deletedReply := reply(3, 1, 1)
+		deletedReply.DeletedAt = &deletedAt
+
+		tree := model.BuildCommentTree(roots, []model.Comment{deletedReply}, nil)
+
+		assert.Len(t, tree, 1)
+		assert.Equal(t, uint(1), tree[0].ID)
+		assert.Empty(t, tree[0].Replies)
+	})
+
+	t.Run("should drop deleted chains whose replies are all deleted", func(t *testing.T) {
+		deletedAt := time.Now()
+		roots := []model.Comment{{ID: 1, DeletedAt: &deletedAt}}
+		deletedReply := reply(2, 1, 1)
+		deletedReply.DeletedAt = &deletedAt
+
+		tree := model.BuildCommentTree(roots, []model.Comment{deletedReply}, nil)
+
+		assert.Empty(t, tree)
+	})
+
+	t.Run("should keep deleted comments of truncated threads", func(t *testing.T) {
+		deletedAt := time.Now()
+		roots := []model.Comment{{ID: 1}, {ID: 2}}
+		deletedReply := reply(3, 1, 1)
+		deletedReply.DeletedAt = &deletedAt
+		otherDeleted := reply(4, 2, 2)
+		otherDeleted.DeletedAt = &deletedAt
+
+		tree := model.BuildCommentTree(roots, []model.Comment{deletedReply, otherDeleted}, map[uint]bool{1: true})
+
+		assert.Len(t, tree, 2)
+		assert.True(t, tree[0].MoreReplies)
+		assert.Len(t, tree[0].Replies, 1)
+		assert.True(t, tree[0].Replies[0].IsDeleted())
+		assert.False(t, tree[1].MoreReplies)
+		assert.Empty(t, tree[1].Replies)
+	})
+}
+
+func TestLimitThreadReplies(t *testing.T) {
+	t.Run("should keep the first replies of each thread", func(t *testing.T) {
+		replies := []model.Comment{reply(3, 1, 1), reply(4, 2, 2), reply(5, 3, 1), reply(6, 1, 1)}
+
+		kept, truncated := model.LimitThreadReplies(replies, 2)
+
+		assert.Equal(t, []model.Comment{reply(3, 1, 1), reply(4, 2, 2), reply(5, 3, 1)}, kept)
+		assert.Equal(t, map[uint]bool{1: true}, truncated)
+	})
+
+	t.Run("should mark no thread when none is over the limit", func(t *testing.T) {
+		replies := []model.Comment{reply(3, 1, 1), reply(4, 1, 1)}
+
+		kept, truncated := model.LimitThreadReplies(replies, 2)
+
+		assert.Equal(t, replies, kept)
+		assert.Empty(t, truncated)
+	})
+}
@@ -0,0 +1,112 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go_api/internal/config"
+	"go_api/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorsMiddleware(t *testing.T) {
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	mux.HandleFunc("GET /blogs/{id}", ok)
+	mux.HandleFunc("PATCH /blogs/{id}", ok)
+	mux.HandleFunc("DELETE /blogs/{id}", ok)
+	mux.HandleFunc("GET /docs", ok)
+
+	cors := middleware.CorsMiddleware(config.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowCredentials: true,
+		ExposedHeaders:   []string{"ETag", "X-Request-ID"},
+		MaxAge:           10 * time.Minute,
+		RouteOrigins:     map[string][]string{"/docs": {"*"}},
+	}, mux)
+	handler := cors(mux)
+
+	serve := func(method, path, origin string, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		for key, value := range headers {
+			r.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("should allow exact origins with credentials", func(t *testing.T) {
+		w := serve(http.MethodGet, "/blogs/1", "https://app.example.com", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "ETag, X-Request-ID", w.Header().Get("Access-Control-Expose-Headers"))
+		assert.Contains(t, w.Header().Values("Vary"), "Origin")
+	})
+
+	t.Run("should allow wildcard subdomains", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/blogs/1", "https://a.example.org", nil).Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/blogs/1", "https://a.b.example.org", nil).Code)
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/blogs/1", "https://example.org", nil).Code)
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/blogs/1", "http://a.example.org", nil).Code)
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/blogs/1", "https://evil-example.org", nil).Code)
+	})
+
+	t.Run("should reject disallowed origins", func(t *testing.T) {
+		w := serve(http.MethodGet, "/blogs/1", "https://evil.com", nil)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("should pass requests without an origin", func(t *testing.T) {
+		w := serve(http.MethodGet, "/blogs/1", "", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("should answer preflights with the registered methods", func(t *testing.T) {
+		w := serve(http.MethodOptions, "/blogs/1", "https://app.example.com", map[string]string{
+			"Access-Control-Request-Method": "PATCH",
+		})
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "GET, PATCH, DELETE", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "If-Match")
+		assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("should reject preflights for unregistered methods", func(t *testing.T) {
+		w := serve(http.MethodOptions, "/blogs/1", "https://app.example.com", map[string]string{
+			"Access-Control-Request-Method": "PUT",
+		})
+
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "GET, PATCH, DELETE", w.Header().Get("Allow"))
+	})
+
+	t.Run("should reject preflights for unknown paths", func(t *testing.T) {
+		w := serve(http.MethodOptions, "/unknown", "https://app.example.com", map[string]string{
+			"Access-Control-Request-Method": "GET",
+		})
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("should apply route overrides", func(t *testing.T) {
+		w := serve(http.MethodGet, "/docs", "https://anyone.net", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	})
+}
@@ -0,0 +1,40 @@
+package unit
+
+import (
+	"testing"
+
+	"go_api/internal/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	t.Run("should round trip a cursor", func(t *testing.T) {
+		cursor := util.Cursor{Sort: "created_at", Desc: true, Value: "2025-01-02T03:04:05.123456Z", ID: 42}
+
+		decoded, err := util.DecodeCursor(util.EncodeCursor(cursor))
+
+		assert.NoError(t, err)
+		assert.Equal(t, cursor, *decoded)
+	})
+
+	t.Run("should encode a URL-safe cursor", func(t *testing.T) {
+		encoded := util.EncodeCursor(util.Cursor{Sort: "title", Value: "a/b+c?d", ID: 1})
+
+		assert.NotContains(t, encoded, "/")
+		assert.NotContains(t, encoded, "+")
+		assert.NotContains(t, encoded, "=")
+	})
+
+	t.Run("should return error for invalid base64", func(t *testing.T) {
+		_, err := util.DecodeCursor("not a cursor!")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("should return error for invalid JSON", func(t *testing.T) {
+		_, err := util.DecodeCursor("bm90IGpzb24")
+
+		assert.Error(t, err)
+	})
+}
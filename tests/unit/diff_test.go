@@ -0,0 +1,121 @@
+package unit
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"go_api/internal/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// applyDiff rebuilds both sides of a diff to check it is consistent with its inputs
+func applyDiff(lines []util.DiffLine) (string, string) {
+	var from, to []string
+	for _, line := range lines {
+		if line.Op != util.DiffInsert {
+			from = append(from, line.Text)
+		}
+		if line.Op != util.DiffDelete {
+			to = append(to, line.Text)
+		}
+	}
+	return strings.Join(from, "\n"), strings.Join(to, "\n")
+}
+
+func TestDiffLines(t *testing.T) {
+	t.Run("should return only equal lines for identical text", func(t *testing.T) {
+		lines, err := util.DiffLines("a\nb", "a\nb")
+
+		require.NoError(t, err)
+		assert.Equal(t, []util.DiffLine{
+			{Op: util.DiffEqual, Text: "a"},
+			{Op: util.DiffEqual, Text: "b"},
+		}, lines)
+	})
+
+	t.Run("should detect a changed line", func(t *testing.T) {
+		lines, err := util.DiffLines("a\nb\nc", "a\nx\nc")
+
+		require.NoError(t, err)
+		assert.Equal(t, []util.DiffLine{
+			{Op: util.DiffEqual, Text: "a"},
+			{Op: util.DiffDelete, Text: "b"},
+			{Op: util.DiffInsert, Text: "x"},
+			{Op: util.DiffEqual, Text: "c"},
+		}, lines)
+	})
+
+	t.Run("should diff against empty text", func(t *testing.T) {
+		inserted, err := util.DiffLines("", "a")
+		require.NoError(t, err)
+		deleted, err := util.DiffLines("a", "")
+		require.NoError(t, err)
+		none, err := util.DiffLines("", "")
+		require.NoError(t, err)
+
+		assert.Equal(t, []util.DiffLine{{Op: util.DiffInsert, Text: "a"}}, inserted)
+		assert.Equal(t, []util.DiffLine{{Op: util.DiffDelete, Text: "a"}}, deleted)
+		assert.Empty(t, none)
+	})
+
+	t.Run("should produce a minimal consistent diff", func(t *testing.T) {
+		a := "the\nquick\nbrown\nfox\njumps\nover\nthe\nlazy\ndog"
+		b := "a\nquick\nred\nfox\njumps\nhigh\nover\nthe\ndog"
+
+		lines, err := util.DiffLines(a, b)
+		require.NoError(t, err)
+		from, to := applyDiff(lines)
+
+		assert.Equal(t, a, from)
+		assert.Equal(t, b, to)
+
+		changes := 0
+		for _, line := range lines {
+			if line.Op != util.DiffEqual {
+				changes++
+			}
+		}
+		assert.Equal(t, 6, changes)
+	})
+
+	t.Run("should group deleted lines before inserted ones", func(t *testing.T) {
+		lines, err := util.DiffLines("a\nb\nc\nd", "x\ny\nc\nz")
+
+		require.NoError(t, err)
+		assert.Equal(t, []util.DiffLine{
+			{Op: util.DiffDelete, Text: "a"},
+			{Op: util.DiffDelete, Text: "b"},
+			{Op: util.DiffInsert, Text: "x"},
+			{Op: util.DiffInsert, Text: "y"},
+			{Op: util.DiffEqual, Text: "c"},
+			{Op: util.DiffDelete, Text: "d"},
+			{Op: util.DiffInsert, Text: "z"},
+		}, lines)
+	})
+
+	t.Run("should diff long texts with many changes", func(t *testing.T) {
+		var a, b []string
+		for i := range util.MaxDiffLines / 2 {
+			a = append(a, strconv.Itoa(i%7))
+			b = append(b, strconv.Itoa(i%5))
+		}
+
+		lines, err := util.DiffLines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+		require.NoError(t, err)
+		from, to := applyDiff(lines)
+
+		assert.Equal(t, strings.Join(a, "\n"), from)
+		assert.Equal(t, strings.Join(b, "\n"), to)
+	})
+
+	t.Run("should refuse texts over the line limit", func(t *testing.T) {
+		a := strings.Repeat("line\n", util.MaxDiffLines/2)
+
+		_, err := util.DiffLines(a, a+"more")
+
+		assert.ErrorIs(t, err, util.ErrDiffTooLarge)
+	})
+}
@@ -0,0 +1,65 @@
+package unit
+
+import (
+	"testing"
+
+	"go_api/internal/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionETag(t *testing.T) {
+	t.Run("should quote the version", func(t *testing.T) {
+		assert.Equal(t, `"3"`, util.VersionETag(3))
+	})
+}
+
+func TestParseIfMatch(t *testing.T) {
+	parse := func(t *testing.T, header string) util.IfMatch {
+		ifMatch, err := util.ParseIfMatch(header)
+		require.NoError(t, err)
+		return ifMatch
+	}
+
+	t.Run("should match quoted version", func(t *testing.T) {
+		ifMatch := parse(t, `"3"`)
+
+		assert.True(t, ifMatch.Matches(3))
+		assert.False(t, ifMatch.Matches(4))
+	})
+
+	t.Run("should round trip with VersionETag", func(t *testing.T) {
+		assert.True(t, parse(t, util.VersionETag(42)).Matches(42))
+	})
+
+	t.Run("should match any version for wildcard", func(t *testing.T) {
+		assert.True(t, parse(t, "*").Matches(7))
+	})
+
+	t.Run("should match any version of a list", func(t *testing.T) {
+		ifMatch := parse(t, `"3", "4",,"5"`)
+
+		assert.True(t, ifMatch.Matches(4))
+		assert.True(t, ifMatch.Matches(5))
+		assert.False(t, ifMatch.Matches(6))
+	})
+
+	t.Run("should never match weak tags", func(t *testing.T) {
+		assert.False(t, parse(t, `W/"3"`).Matches(3))
+		assert.True(t, parse(t, `W/"3", "3"`).Matches(3))
+	})
+
+	t.Run("should never match tags that are not versions", func(t *testing.T) {
+		assert.False(t, parse(t, `"abc"`).Matches(1))
+		assert.False(t, parse(t, `"0"`).Matches(0))
+	})
+
+	t.Run("should reject unquoted tags", func(t *testing.T) {
+		for _, header := range []string{`3`, `"3", 4`, `W/3`, `"3`, `,`} {
+			_, err := util.ParseIfMatch(header)
+
+			assert.Error(t, err, header)
+		}
+	})
+}
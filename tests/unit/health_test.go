@@ -0,0 +1,207 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go_api/internal/app/handler"
+	"go_api/internal/health"
+	"go_api/internal/lifecycle"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler(t *testing.T) {
+	readiness := &lifecycle.Readiness{}
+	health := handler.NewHandler(nil, readiness, false).HealthHandler()
+
+	t.Run("should succeed while ready", func(t *testing.T) {
+		readiness.SetReady(true)
+		w := httptest.NewRecorder()
+
+		health.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should fail while shutting down", func(t *testing.T) {
+		readiness.SetReady(false)
+		w := httptest.NewRecorder()
+
+		health.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "shutting down")
+	})
+}
+
+func upCheck(version string) health.CheckFunc {
+	return func(ctx context.Context) (health.Component, error) {
+		return health.Component{Version: version}, nil
+	}
+}
+
+func downCheck(ctx context.Context) (health.Component, error) {
+	return health.Component{}, errors.New("connection refused")
+}
+
+func TestHealthChecker(t *testing.T) {
+	t.Run("should be up when every check succeeds", func(t *testing.T) {
+		checker := health.NewChecker(time.Second, 0)
+		checker.Register("database", upCheck("16.2"))
+		checker.Register("redis", upCheck("7.2.4"))
+
+		report := checker.Check(context.Background())
+
+		assert.Equal(t, health.StatusUp, report.Status)
+		require.Len(t, report.Components, 2)
+		assert.Equal(t, health.StatusUp, report.Components["database"].Status)
+		assert.Equal(t, "16.2", report.Components["database"].Version)
+		assert.Equal(t, "7.2.4", report.Components["redis"].Version)
+	})
+
+	t.Run("should be down when a check fails", func(t *testing.T) {
+		checker := health.NewChecker(time.Second, 0)
+		checker.Register("database", upCheck("16.2"))
+		checker.Register("redis", downCheck)
+
+		report := checker.Check(context.Background())
+
+		assert.Equal(t, health.StatusDown, report.Status)
+		assert.Equal(t, health.StatusUp, report.Components["database"].Status)
+		assert.Equal(t, health.StatusDown, report.Components["redis"].Status)
+		assert.Equal(t, "connection refused", report.Components["redis"].Error)
+	})
+
+	t.Run("should stay up when an optional check fails", func(t *testing.T) {
+		checker := health.NewChecker(time.Second, 0)
+		checker.Register("database", upCheck("16.2"))
+		checker.RegisterOptional("database_replica_1", downCheck)
+
+		report := checker.Check(context.Background())
+
+		assert.Equal(t, health.StatusUp, report.Status)
+		assert.Equal(t, health.StatusDown, report.Components["database_replica_1"].Status)
+		assert.True(t, report.Components["database_replica_1"].Optional)
+		assert.False(t, report.Components["database"].Optional)
+	})
+
+	t.Run("should fail checks that exceed the timeout", func(t *testing.T) {
+		checker := health.NewChecker(10*time.Millisecond, 0)
+		checker.Register("database", func(ctx context.Context) (health.Component, error) {
+			<-ctx.Done()
+			return health.Component{}, ctx.Err()
+		})
+
+		report := checker.Check(context.Background())
+
+		assert.Equal(t, health.StatusDown, report.Components["database"].Status)
+		assert.Contains(t, report.Components["database"].Error, "deadline exceeded")
+	})
+
+	t.Run("should reuse the report while it is fresh", func(t *testing.T) {
+		var calls atomic.Int32
+		checker := health.NewChecker(time.Second, time.Minute)
+		checker.Register("database", func(ctx context.Context) (health.Component, error) {
+			calls.Add(1)
+			return health.Component{}, nil
+		})
+
+		checker.Check(context.Background())
+		checker.Check(context.Background())
+
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("should not be failed by a canceled caller", func(t *testing.T) {
+		checker := health.NewChecker(time.Second, 0)
+		checker.Register("database", func(ctx context.Context) (health.Component, error) {
+			return health.Component{}, ctx.Err()
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		report := checker.Check(ctx)
+
+		assert.Equal(t, health.StatusUp, report.Status)
+	})
+
+	t.Run("should hide errors", func(t *testing.T) {
+		checker := health.NewChecker(time.Second, 0)
+		checker.Register("redis", downCheck)
+
+		report := checker.Check(context.Background()).WithoutErrors()
+
+		assert.Equal(t, health.StatusDown, report.Components["redis"].Status)
+		assert.Empty(t, report.Components["redis"].Error)
+	})
+}
+
+func TestReadinessHandler(t *testing.T) {
+	readiness := &lifecycle.Readiness{}
+	readiness.SetReady(true)
+
+	serve := func(checker *health.Checker) (*httptest.ResponseRecorder, health.Report) {
+		w := httptest.NewRecorder()
+		handler.NewHandler(checker, readiness, false).ReadinessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+		var body struct {
+			Data health.Report `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w, body.Data
+	}
+
+	t.Run("should succeed when dependencies are up", func(t *testing.T) {
+		checker := health.NewChecker(time.Second, 0)
+		checker.Register("database", upCheck("16.2"))
+
+		w, report := serve(checker)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, health.StatusUp, report.Status)
+		assert.Equal(t, "16.2", report.Components["database"].Version)
+	})
+
+	t.Run("should fail when a dependency is down", func(t *testing.T) {
+		checker := health.NewChecker(time.Second, 0)
+		checker.Register("database", upCheck("16.2"))
+		checker.Register("redis", downCheck)
+
+		w, report := serve(checker)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, health.StatusDown, report.Components["redis"].Status)
+	})
+
+	t.Run("should fail while shutting down", func(t *testing.T) {
+		readiness.SetReady(false)
+		defer readiness.SetReady(true)
+		checker := health.NewChecker(time.Second, 0)
+		checker.Register("database", upCheck("16.2"))
+
+		w, _ := serve(checker)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "shutting down")
+	})
+}
+
+func TestLivenessHandler(t *testing.T) {
+	t.Run("should succeed without checking dependencies", func(t *testing.T) {
+		checker := health.NewChecker(time.Second, 0)
+		checker.Register("database", downCheck)
+		w := httptest.NewRecorder()
+
+		handler.NewHandler(checker, &lifecycle.Readiness{}, false).LivenessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -2,9 +2,11 @@ package unit
 
 import (
 	"testing"
+	"time"
 
 	"go_api/internal/util"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,20 +17,33 @@ func TestGenerateToken(t *testing.T) {
 		userID := uint(1)
 		username := "testuser"
 
-		token, err := util.GenerateToken(userID, username, secret)
+		token, err := util.GenerateToken(userID, username, "user", nil, secret)
 
 		assert.NoError(t, err)
 		assert.NotEmpty(t, token)
 	})
 
 	t.Run("should generate different tokens for different users", func(t *testing.T) {
-		token1, err1 := util.GenerateToken(1, "user1", secret)
-		token2, err2 := util.GenerateToken(2, "user2", secret)
+		token1, err1 := util.GenerateToken(1, "user1", "user", nil, secret)
+		token2, err2 := util.GenerateToken(2, "user2", "user", nil, secret)
 
 		assert.NoError(t, err1)
 		assert.NoError(t, err2)
 		assert.NotEqual(t, token1, token2)
 	})
+
+	t.Run("should generate short-lived token", func(t *testing.T) {
+		token, err := util.GenerateToken(1, "user1", "user", nil, secret)
+		assert.NoError(t, err)
+
+		claims := &util.UserClaims{}
+		_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+			return secret, nil
+		})
+
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(util.AccessTokenTTL), claims.ExpiresAt.Time, time.Minute)
+	})
 }
 
 func TestExtractTokenFromHeader(t *testing.T) {
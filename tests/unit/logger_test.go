@@ -0,0 +1,116 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go_api/internal/middleware"
+	"go_api/internal/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	t.Run("should parse known levels", func(t *testing.T) {
+		levels := map[string]slog.Level{
+			"debug": slog.LevelDebug,
+			"INFO":  slog.LevelInfo,
+			"":      slog.LevelInfo,
+			"warn":  slog.LevelWarn,
+			"error": slog.LevelError,
+		}
+		for value, expected := range levels {
+			level, err := util.ParseLogLevel(value)
+
+			assert.NoError(t, err, value)
+			assert.Equal(t, expected, level, value)
+		}
+	})
+
+	t.Run("should reject unknown levels", func(t *testing.T) {
+		_, err := util.ParseLogLevel("verbose")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestNewLogger(t *testing.T) {
+	t.Run("should write JSON outside development", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := util.NewLogger(&buf, "production", slog.LevelInfo)
+
+		logger.Info("hello", "key", "value")
+
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "hello", entry["msg"])
+		assert.Equal(t, "value", entry["key"])
+	})
+
+	t.Run("should write text in development", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := util.NewLogger(&buf, "development", slog.LevelInfo)
+
+		logger.Info("hello")
+
+		assert.Contains(t, buf.String(), "msg=hello")
+	})
+
+	t.Run("should honor the level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := util.NewLogger(&buf, "production", slog.LevelWarn)
+
+		logger.Info("hidden")
+
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("should follow a level variable", func(t *testing.T) {
+		var buf bytes.Buffer
+		level := &slog.LevelVar{}
+		level.Set(slog.LevelWarn)
+		logger := util.NewLogger(&buf, "production", level)
+
+		logger.Info("hidden")
+		level.Set(slog.LevelInfo)
+		logger.Info("shown")
+
+		assert.NotContains(t, buf.String(), "hidden")
+		assert.Contains(t, buf.String(), "shown")
+	})
+}
+
+func TestLoggerMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(util.NewLogger(&buf, "production", slog.LevelInfo))
+	defer slog.SetDefault(previous)
+
+	handler := middleware.RequestIDMiddleware(middleware.LoggerMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})))
+
+	t.Run("should log status, size and request ID", func(t *testing.T) {
+		buf.Reset()
+		r := httptest.NewRequest(http.MethodPost, "/blogs/", nil)
+		r.Header.Set("X-Request-ID", "req-42")
+
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "POST", entry["method"])
+		assert.Equal(t, "/blogs/", entry["path"])
+		assert.Equal(t, float64(http.StatusCreated), entry["status"])
+		assert.Equal(t, float64(len("created")), entry["bytes"])
+		assert.Equal(t, "req-42", entry["request_id"])
+		assert.Contains(t, entry, "latency")
+		assert.NotContains(t, entry, "user_id")
+	})
+}
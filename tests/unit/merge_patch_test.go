@@ -0,0 +1,45 @@
+package unit
+
+import (
+	"testing"
+
+	"go_api/internal/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	t.Run("should replace only patched fields", func(t *testing.T) {
+		result, err := util.MergePatch([]byte(`{"title":"a","content":"b"}`), []byte(`{"title":"c"}`))
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"title":"c","content":"b"}`, string(result))
+	})
+
+	t.Run("should remove fields set to null", func(t *testing.T) {
+		result, err := util.MergePatch([]byte(`{"title":"a","content":"b"}`), []byte(`{"content":null}`))
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"title":"a"}`, string(result))
+	})
+
+	t.Run("should merge nested objects", func(t *testing.T) {
+		result, err := util.MergePatch([]byte(`{"a":{"b":1,"c":2}}`), []byte(`{"a":{"c":3,"d":4}}`))
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"a":{"b":1,"c":3,"d":4}}`, string(result))
+	})
+
+	t.Run("should replace document with non-object patch", func(t *testing.T) {
+		result, err := util.MergePatch([]byte(`{"a":1}`), []byte(`["b"]`))
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `["b"]`, string(result))
+	})
+
+	t.Run("should return error for invalid patch", func(t *testing.T) {
+		_, err := util.MergePatch([]byte(`{"a":1}`), []byte(`{invalid`))
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,70 @@
+package unit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go_api/internal/metrics"
+	"go_api/internal/middleware"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics-test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	m := metrics.New()
+	handler := middleware.MetricsMiddleware(m)(mux)
+
+	t.Run("should label requests with the route pattern", func(t *testing.T) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-test/1", nil))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-test/2", nil))
+
+		assert.Equal(t, float64(2), testutil.ToFloat64(m.HTTPRequests.WithLabelValues("GET", "GET /metrics-test/{id}", "202")))
+		assert.Equal(t, float64(0), testutil.ToFloat64(m.HTTPRequestsInFlight))
+	})
+
+	t.Run("should group unmatched paths", func(t *testing.T) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown/path", nil))
+
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.HTTPRequests.WithLabelValues("GET", "unmatched", "404")))
+	})
+}
+
+func TestMetricsHandler(t *testing.T) {
+	t.Run("should serve metrics in the Prometheus text format", func(t *testing.T) {
+		m := metrics.New()
+		m.CacheHit("user")
+		m.RateLimitRejections.WithLabelValues("global").Add(0)
+
+		w := httptest.NewRecorder()
+		m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		body, err := io.ReadAll(w.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+		assert.Contains(t, string(body), `go_api_cache_requests_total{cache="user",result="hit"}`)
+		assert.Contains(t, string(body), "go_api_rate_limit_rejections_total")
+		assert.Contains(t, string(body), "go_goroutines")
+	})
+
+	t.Run("should expose Redis pool stats", func(t *testing.T) {
+		m := metrics.New()
+		client := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+		defer client.Close()
+		require.NoError(t, m.RegisterRedis(client))
+
+		count, err := testutil.GatherAndCount(m.Registry, "go_api_redis_pool_connections")
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
@@ -0,0 +1,209 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go_api/internal/migration"
+	"go_api/internal/storage"
+	"go_api/migrations"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("should load migrations sorted by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0002_add_tags.up.sql":       {Data: []byte("CREATE TABLE tags (id bigint);")},
+			"0002_add_tags.down.sql":     {Data: []byte("DROP TABLE tags;")},
+			"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id bigint);")},
+			"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+			"README.md":                  {Data: []byte("ignored")},
+		}
+
+		loaded, err := migration.Load(fsys)
+
+		require.NoError(t, err)
+		require.Len(t, loaded, 2)
+		assert.Equal(t, int64(1), loaded[0].Version)
+		assert.Equal(t, "create_users", loaded[0].Name)
+		assert.Equal(t, "DROP TABLE users;", loaded[0].Down)
+		assert.Equal(t, int64(2), loaded[1].Version)
+	})
+
+	t.Run("should fail without a down file", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0001_create_users.up.sql": {Data: []byte("CREATE TABLE users (id bigint);")},
+		}
+
+		_, err := migration.Load(fsys)
+
+		assert.ErrorContains(t, err, "no down file")
+	})
+
+	t.Run("should fail on badly named files", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"create_users.sql": {Data: []byte("CREATE TABLE users (id bigint);")},
+		}
+
+		_, err := migration.Load(fsys)
+
+		assert.ErrorContains(t, err, "must be named like")
+	})
+
+	t.Run("should fail when a version has two names", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0001_create_users.up.sql":    {Data: []byte("CREATE TABLE users (id bigint);")},
+			"0001_create_people.down.sql": {Data: []byte("DROP TABLE users;")},
+		}
+
+		_, err := migration.Load(fsys)
+
+		assert.ErrorContains(t, err, "two names")
+	})
+
+	t.Run("should load the embedded migrations", func(t *testing.T) {
+		loaded, err := migration.Load(migrations.FS)
+
+		require.NoError(t, err)
+		require.NotEmpty(t, loaded)
+		for i, m := range loaded {
+			assert.Equal(t, int64(i+1), m.Version, "migration versions must follow each other")
+		}
+	})
+}
+
+func TestMigrationChecksum(t *testing.T) {
+	t.Run("should change with the up SQL only", func(t *testing.T) {
+		m := migration.Migration{Version: 1, Name: "create_users", Up: "CREATE TABLE users (id bigint);", Down: "DROP TABLE users;"}
+		changedDown := m
+		changedDown.Down = "DROP TABLE IF EXISTS users;"
+		changedUp := m
+		changedUp.Up = "CREATE TABLE users (id bigserial);"
+
+		assert.Equal(t, m.Checksum(), changedDown.Checksum())
+		assert.NotEqual(t, m.Checksum(), changedUp.Checksum())
+	})
+}
+
+func TestMigrationInTransaction(t *testing.T) {
+	t.Run("should run in a transaction by default", func(t *testing.T) {
+		assert.True(t, migration.InTransaction("CREATE TABLE users (id bigint);"))
+	})
+
+	t.Run("should opt out with the directive", func(t *testing.T) {
+		assert.False(t, migration.InTransaction("-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY idx ON users (id);"))
+	})
+}
+
+func TestCreateMigration(t *testing.T) {
+	t.Run("should number new migrations after the latest", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "0003_create_users.up.sql"), []byte("SELECT 1;"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "0003_create_users.down.sql"), []byte("SELECT 1;"), 0o644))
+
+		paths, err := migration.Create(dir, "Add Blog Slugs")
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			filepath.Join(dir, "0004_add_blog_slugs.up.sql"),
+			filepath.Join(dir, "0004_add_blog_slugs.down.sql"),
+		}, paths)
+		loaded, err := migration.Load(os.DirFS(dir))
+		require.NoError(t, err)
+		assert.Len(t, loaded, 2)
+	})
+
+	t.Run("should start at 1 in an empty directory", func(t *testing.T) {
+		paths, err := migration.Create(t.TempDir(), "init")
+
+		require.NoError(t, err)
+		assert.Equal(t, "0001_init.up.sql", filepath.Base(paths[0]))
+	})
+
+	t.Run("should reject names without letters or digits", func(t *testing.T) {
+		_, err := migration.Create(t.TempDir(), "--")
+
+		assert.Error(t, err)
+	})
+}
+
+// baselineUser and baselineBlog are the models AutoMigrate created the schema from before migrations
+type baselineUser struct {
+	ID        uint   `gorm:"primaryKey"`
+	Username  string `gorm:"uniqueIndex;not null"`
+	Email     string `gorm:"uniqueIndex;not null"`
+	Password  string `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineBlog struct {
+	ID        uint         `gorm:"primaryKey"`
+	Title     string       `gorm:"not null"`
+	Content   string       `gorm:"type:text"`
+	UserID    uint         `gorm:"index;not null"`
+	User      baselineUser `gorm:"foreignKey:UserID"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (baselineBlog) TableName() string { return "blogs" }
+
+// TestMigrateFromAutoMigrate runs the migrations against Postgres when TEST_DATABASE_URL is set,
+// starting from the schema AutoMigrate created. The tables of that database are dropped first.
+func TestMigrateFromAutoMigrate(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(url), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, db.Exec("DROP TABLE IF EXISTS "+migration.Table+", comments, blog_revisions, blog_tags, tags, blogs, users CASCADE").Error)
+	require.NoError(t, db.AutoMigrate(&baselineUser{}, &baselineBlog{}))
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	user := baselineUser{Username: "alice", Email: "alice@example.com", Password: "hash"}
+	require.NoError(t, db.Create(&user).Error)
+	require.NoError(t, db.Create(&baselineBlog{Title: "Hello", Content: "World", UserID: user.ID, CreatedAt: created}).Error)
+
+	t.Run("should add the new columns to the existing tables", func(t *testing.T) {
+		require.NoError(t, storage.Migrate(ctx, db))
+
+		var role string
+		require.NoError(t, db.Raw("SELECT role FROM users WHERE id = ?", user.ID).Scan(&role).Error)
+		assert.Equal(t, "user", role)
+
+		var blog struct {
+			Status      string
+			PublishedAt *time.Time
+			Version     int64
+		}
+		require.NoError(t, db.Raw("SELECT status, published_at, version FROM blogs").Scan(&blog).Error)
+		assert.Equal(t, "published", blog.Status)
+		assert.Equal(t, int64(1), blog.Version)
+		require.NotNil(t, blog.PublishedAt)
+		assert.True(t, created.Equal(*blog.PublishedAt))
+	})
+
+	t.Run("should leave no migration pending", func(t *testing.T) {
+		version, err := storage.CheckSchema(ctx, db)
+
+		require.NoError(t, err)
+		loaded, err := migration.Load(migrations.FS)
+		require.NoError(t, err)
+		assert.Equal(t, loaded[len(loaded)-1].Version, version)
+	})
+}
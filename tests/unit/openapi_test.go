@@ -0,0 +1,180 @@
+package unit
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go_api/internal/app/handler"
+	"go_api/internal/app/openapi"
+	"go_api/internal/app/route"
+	"go_api/internal/config"
+	"go_api/internal/metrics"
+	"go_api/internal/middleware"
+	"go_api/internal/ratelimit"
+	"go_api/internal/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// patternRecorder records the patterns registered by the route package
+type patternRecorder struct {
+	patterns []string
+}
+
+func (r *patternRecorder) Handle(pattern string, handler http.Handler) {
+	r.patterns = append(r.patterns, pattern)
+}
+
+// docsRoutes are the handlers of the route tests, only the docs are served
+func docsRoutes() route.Handlers {
+	m := metrics.New()
+	return route.Handlers{
+		Docs:        handler.NewDocsHandler(openapi.Build()),
+		RateLimiter: middleware.NewRateLimiter(ratelimit.NewMemoryLimiter(), config.Default().RateLimit, nil, m),
+		Metrics:     m,
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	recorder := &patternRecorder{}
+	route.RegisterRoutes(recorder, docsRoutes())
+	doc := openapi.Build()
+
+	t.Run("should describe every registered route", func(t *testing.T) {
+		require.NotEmpty(t, recorder.patterns)
+		for _, pattern := range recorder.patterns {
+			method, path := openapi.SplitPattern(pattern)
+			assert.NotNil(t, doc.Operation(method, path), "route %q is missing from the OpenAPI document", pattern)
+		}
+	})
+
+	t.Run("should only describe registered routes", func(t *testing.T) {
+		registered := make(map[string]bool)
+		for _, pattern := range recorder.patterns {
+			method, path := openapi.SplitPattern(pattern)
+			registered[method+" "+path] = true
+		}
+		for path, item := range doc.Paths {
+			for method := range item {
+				assert.True(t, registered[strings.ToUpper(method)+" "+path], "%s %s is not a registered route", method, path)
+			}
+		}
+	})
+
+	t.Run("should resolve every schema reference", func(t *testing.T) {
+		data, err := json.Marshal(doc)
+		require.NoError(t, err)
+
+		var refs []string
+		collectRefs(t, data, &refs)
+		require.NotEmpty(t, refs)
+		for _, ref := range refs {
+			name := ref[len("#/components/schemas/"):]
+			assert.NotNil(t, doc.Components.Schemas[name], "unresolved reference %s", ref)
+		}
+	})
+
+	t.Run("should not filter blogs by tag query under a tag path", func(t *testing.T) {
+		op := doc.Operation(http.MethodGet, "/tags/{tag}/blogs")
+		require.NotNil(t, op)
+
+		for _, param := range op.Parameters {
+			if param.In == "query" {
+				assert.NotContains(t, []string{"tag", "tag_match"}, param.Name)
+			}
+		}
+	})
+
+	t.Run("should document validate tags", func(t *testing.T) {
+		schema := doc.Components.Schemas["CreateUserRequest"]
+		require.NotNil(t, schema)
+
+		assert.ElementsMatch(t, []string{"username", "email", "password"}, schema.Required)
+		assert.Equal(t, "email", schema.Properties["email"].Format)
+		assert.Equal(t, 3, *schema.Properties["username"].MinLength)
+		assert.Equal(t, 30, *schema.Properties["username"].MaxLength)
+		assert.Equal(t, util.UsernamePattern, schema.Properties["username"].Pattern)
+	})
+}
+
+// swaggerUIIntegrity are the hashes of the Swagger UI 5.18.2 files of github.com/swaggo/files/v2 v2.0.2.
+// They change only when that dependency is upgraded.
+var swaggerUIIntegrity = map[string]string{
+	"swagger-ui.css":       "sha384-rcbEi6xgdPk0iWkAQzT2F3FeBJXdG+ydrawGlfHAFIZG7wU6aKbQaRewysYpmrlW",
+	"swagger-ui-bundle.js": "sha384-NXtFPpN61oWCuN4D42K6Zd5Rt2+uxeIT36R7kpXBuY9tLnZorzrJ4ykpqwJfgjpZ",
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	route.RegisterRoutes(mux, docsRoutes())
+
+	t.Run("should serve the OpenAPI document", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var doc openapi.Document
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		assert.Equal(t, "3.1.0", doc.OpenAPI)
+	})
+
+	t.Run("should serve the docs page", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "/openapi.json")
+		assert.Contains(t, w.Body.String(), `<link rel="stylesheet" href="/docs/swagger-ui.css" integrity="`+swaggerUIIntegrity["swagger-ui.css"]+`">`)
+		assert.Contains(t, w.Body.String(), `<script src="/docs/swagger-ui-bundle.js" integrity="`+swaggerUIIntegrity["swagger-ui-bundle.js"]+`"></script>`)
+		assert.Contains(t, w.Header().Get("Content-Security-Policy"), "script-src 'self' 'sha256-")
+		assert.Contains(t, w.Header().Get("Content-Security-Policy"), "style-src 'self'")
+	})
+
+	t.Run("should serve the Swagger UI files matching their integrity", func(t *testing.T) {
+		for file, integrity := range swaggerUIIntegrity {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/"+file, nil))
+
+			require.Equal(t, http.StatusOK, w.Code, file)
+			sum := sha512.Sum384(w.Body.Bytes())
+			assert.Equal(t, integrity, "sha384-"+base64.StdEncoding.EncodeToString(sum[:]), file)
+		}
+	})
+
+	t.Run("should not serve other files", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/index.html", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+// collectRefs appends every $ref value found in a JSON document
+func collectRefs(t *testing.T, data []byte, refs *[]string) {
+	var value any
+	require.NoError(t, json.Unmarshal(data, &value))
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				*refs = append(*refs, ref)
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(value)
+}
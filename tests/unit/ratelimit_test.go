@@ -0,0 +1,243 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"go_api/internal/app/route"
+	"go_api/internal/config"
+	"go_api/internal/metrics"
+	"go_api/internal/middleware"
+	"go_api/internal/ratelimit"
+	"go_api/internal/util"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	ctx := context.Background()
+	policy := ratelimit.Policy{Name: "test", Limit: 2, Window: time.Minute}
+
+	t.Run("should allow requests up to the limit", func(t *testing.T) {
+		limiter := ratelimit.NewMemoryLimiter()
+
+		first, _ := limiter.Allow(ctx, "client", policy)
+		second, _ := limiter.Allow(ctx, "client", policy)
+		third, err := limiter.Allow(ctx, "client", policy)
+
+		require.NoError(t, err)
+		assert.True(t, first.Allowed)
+		assert.Equal(t, 1, first.Remaining)
+		assert.True(t, second.Allowed)
+		assert.Equal(t, 0, second.Remaining)
+		assert.False(t, third.Allowed)
+		assert.Equal(t, 0, third.Remaining)
+		assert.LessOrEqual(t, third.ResetAfter, time.Minute)
+		assert.Greater(t, third.ResetAfter, time.Duration(0))
+	})
+
+	t.Run("should count keys and policies separately", func(t *testing.T) {
+		limiter := ratelimit.NewMemoryLimiter()
+		other := ratelimit.Policy{Name: "other", Limit: 1, Window: time.Minute}
+
+		limiter.Allow(ctx, "a", other)
+		resultB, _ := limiter.Allow(ctx, "b", other)
+		resultA, _ := limiter.Allow(ctx, "a", policy)
+
+		assert.True(t, resultB.Allowed)
+		assert.True(t, resultA.Allowed)
+	})
+
+	t.Run("should slide the window", func(t *testing.T) {
+		limiter := ratelimit.NewMemoryLimiter()
+		short := ratelimit.Policy{Name: "short", Limit: 1, Window: 50 * time.Millisecond}
+
+		limiter.Allow(ctx, "client", short)
+		denied, _ := limiter.Allow(ctx, "client", short)
+		time.Sleep(60 * time.Millisecond)
+		allowed, _ := limiter.Allow(ctx, "client", short)
+
+		assert.False(t, denied.Allowed)
+		assert.True(t, allowed.Allowed)
+	})
+}
+
+func TestFallbackLimiter(t *testing.T) {
+	t.Run("should fall back when Redis is down", func(t *testing.T) {
+		client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+		defer client.Close()
+		limiter := ratelimit.NewFallbackLimiter(ratelimit.NewRedisLimiter(client), ratelimit.NewMemoryLimiter())
+		policy := ratelimit.Policy{Name: "test", Limit: 1, Window: time.Minute}
+
+		first, err := limiter.Allow(context.Background(), "client", policy)
+		require.NoError(t, err)
+		second, err := limiter.Allow(context.Background(), "client", policy)
+		require.NoError(t, err)
+
+		assert.True(t, first.Allowed)
+		assert.False(t, second.Allowed)
+	})
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	secret := []byte("rate-limit-secret")
+	limiter := middleware.NewRateLimiter(ratelimit.NewMemoryLimiter(), config.RateLimitConfig{
+		PerMinute: 2,
+		Policies:  map[string]int{"strict": 1},
+		Routes:    map[string]string{"POST /users/login": "strict", "POST /users/refresh": "strict"},
+	}, secret, metrics.New())
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := limiter.Middleware(ok)
+
+	serve := func(h http.Handler, remoteAddr, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remoteAddr
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("should set RateLimit headers and reject over the limit", func(t *testing.T) {
+		first := serve(handler, "10.0.0.1:1000", "")
+		serve(handler, "10.0.0.1:1001", "")
+		rejected := serve(handler, "10.0.0.1:1002", "")
+
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "2;w=60", first.Header().Get("RateLimit-Policy"))
+		assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+		assert.Equal(t, "0", rejected.Header().Get("RateLimit-Remaining"))
+		assert.NotEmpty(t, rejected.Header().Get("Retry-After"))
+		assert.Contains(t, rejected.Body.String(), util.CodeRateLimited)
+	})
+
+	t.Run("should count authenticated users separately from their IP address", func(t *testing.T) {
+		alice, err := util.GenerateToken(101, "alice", "user", nil, secret)
+		require.NoError(t, err)
+		bob, err := util.GenerateToken(102, "bob", "user", nil, secret)
+		require.NoError(t, err)
+
+		serve(handler, "10.0.0.2:1000", alice)
+		serve(handler, "10.0.0.2:1000", alice)
+
+		assert.Equal(t, http.StatusTooManyRequests, serve(handler, "10.0.0.2:1000", alice).Code)
+		assert.Equal(t, http.StatusOK, serve(handler, "10.0.0.2:1000", bob).Code)
+		assert.Equal(t, http.StatusOK, serve(handler, "10.0.0.2:1000", "").Code)
+	})
+
+	t.Run("should count IPv6 clients by their /64 prefix", func(t *testing.T) {
+		serve(handler, "[2001:db8:1:2::1]:1000", "")
+		serve(handler, "[2001:db8:1:2:ffff::9]:1000", "")
+
+		assert.Equal(t, http.StatusTooManyRequests, serve(handler, "[2001:db8:1:2:abcd::1]:1000", "").Code)
+		assert.Equal(t, http.StatusOK, serve(handler, "[2001:db8:1:3::1]:1000", "").Code)
+	})
+
+	t.Run("should count IPv4-mapped addresses as IPv4", func(t *testing.T) {
+		serve(handler, "[::ffff:10.0.0.4]:1000", "")
+		serve(handler, "10.0.0.4:1000", "")
+
+		assert.Equal(t, http.StatusTooManyRequests, serve(handler, "10.0.0.4:1001", "").Code)
+		assert.Equal(t, http.StatusOK, serve(handler, "10.0.0.5:1000", "").Code)
+	})
+
+	t.Run("should share the policy of a route between its routes", func(t *testing.T) {
+		login := limiter.RouteMiddleware("POST /users/login", ok)
+		refresh := limiter.RouteMiddleware("POST /users/refresh", ok)
+
+		assert.Equal(t, http.StatusOK, serve(login, "10.0.0.3:1000", "").Code)
+		rejected := serve(refresh, "10.0.0.3:1000", "")
+		assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+		assert.Equal(t, "1;w=60", rejected.Header().Get("RateLimit-Policy"))
+	})
+
+	t.Run("should only apply the global limit to routes without a policy", func(t *testing.T) {
+		profile := limiter.RouteMiddleware("GET /users/profile", ok)
+
+		for range 3 {
+			assert.Equal(t, http.StatusOK, serve(profile, "10.0.0.6:1000", "").Code)
+		}
+	})
+
+	t.Run("should apply new policies on reload", func(t *testing.T) {
+		reloaded := middleware.NewRateLimiter(ratelimit.NewMemoryLimiter(), config.RateLimitConfig{PerMinute: 2}, secret, metrics.New())
+		profile := reloaded.RouteMiddleware("GET /users/profile", ok)
+		assert.Equal(t, http.StatusOK, serve(profile, "10.0.0.7:1000", "").Code)
+
+		reloaded.SetConfig(config.RateLimitConfig{
+			PerMinute: 2,
+			Policies:  map[string]int{"profile": 1},
+			Routes:    map[string]string{"GET /users/profile": "profile"},
+		})
+
+		assert.Equal(t, http.StatusOK, serve(profile, "10.0.0.7:1000", "").Code)
+		assert.Equal(t, http.StatusTooManyRequests, serve(profile, "10.0.0.7:1000", "").Code)
+	})
+}
+
+func TestRateLimiterTrustedProxies(t *testing.T) {
+	limiter := middleware.NewRateLimiter(ratelimit.NewMemoryLimiter(), config.RateLimitConfig{
+		PerMinute:      1,
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")},
+	}, nil, metrics.New())
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(remoteAddr string, forwardedFor ...string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remoteAddr
+		for _, value := range forwardedFor {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	t.Run("should count clients behind a trusted proxy by their forwarded address", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("10.1.0.5:1000", "203.0.113.7"))
+		assert.Equal(t, http.StatusTooManyRequests, serve("10.1.0.5:1000", "203.0.113.7"))
+		assert.Equal(t, http.StatusOK, serve("10.1.0.5:1000", "203.0.113.8"))
+	})
+
+	t.Run("should ignore addresses prepended by the client", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("10.1.0.5:1000", "198.51.100.1, 203.0.113.9"))
+		assert.Equal(t, http.StatusTooManyRequests, serve("10.1.0.5:1000", "198.51.100.2, 203.0.113.9"))
+	})
+
+	t.Run("should skip every trusted hop", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("10.1.0.5:1000", "203.0.113.10", "10.1.0.9"))
+		assert.Equal(t, http.StatusTooManyRequests, serve("10.1.0.6:1000", "203.0.113.10, 10.1.2.3"))
+	})
+
+	t.Run("should ignore the header from untrusted peers", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("192.0.2.1:1000", "203.0.113.11"))
+		assert.Equal(t, http.StatusTooManyRequests, serve("192.0.2.1:1000", "203.0.113.12"))
+	})
+
+	t.Run("should count the proxy when the forwarded address is invalid", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("10.1.0.7:1000", "unknown"))
+		assert.Equal(t, http.StatusTooManyRequests, serve("10.1.0.7:1000"))
+	})
+}
+
+func TestDefaultRateLimitRoutes(t *testing.T) {
+	t.Run("should name registered routes", func(t *testing.T) {
+		recorder := &patternRecorder{}
+		route.RegisterRoutes(recorder, docsRoutes())
+
+		for pattern := range config.Default().RateLimit.Routes {
+			assert.Contains(t, recorder.patterns, pattern)
+		}
+	})
+}
@@ -0,0 +1,234 @@
+package unit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"go_api/internal/app/model"
+	"go_api/internal/storage"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var errRecorded = errors.New("recorded")
+
+// recordingPool is a connection pool that records the name of the pool queries ran on
+type recordingPool struct {
+	name string
+	used *[]string
+}
+
+func (p recordingPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errRecorded
+}
+
+func (p recordingPool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	*p.used = append(*p.used, p.name)
+	return driverResult{}, nil
+}
+
+func (p recordingPool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	*p.used = append(*p.used, p.name)
+	return nil, errRecorded
+}
+
+func (p recordingPool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	*p.used = append(*p.used, p.name)
+	return &sql.Row{}
+}
+
+type driverResult struct{}
+
+func (driverResult) LastInsertId() (int64, error) { return 0, nil }
+func (driverResult) RowsAffected() (int64, error) { return 1, nil }
+
+func openRecordingDB(t *testing.T, name string, used *[]string) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: recordingPool{name: name, used: used}}), &gorm.Config{
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Discard,
+	})
+	require.NoError(t, err)
+	return db
+}
+
+func TestReadReplicas(t *testing.T) {
+	// instance opens the database of one API instance, sharing the write tracker and the used pools
+	instance := func(t *testing.T, replicas int, writes storage.WriteTracker, used *[]string) (*gorm.DB, *storage.ReadReplicas) {
+		primary := openRecordingDB(t, "primary", used)
+		var replicaDBs []*gorm.DB
+		for _, name := range []string{"replica1", "replica2"}[:replicas] {
+			replicaDBs = append(replicaDBs, openRecordingDB(t, name, used))
+		}
+		routing := storage.NewReadReplicas(replicaDBs, time.Minute, writes)
+		require.NoError(t, primary.Use(routing))
+		return primary, routing
+	}
+	setup := func(t *testing.T, replicas int) (*gorm.DB, *[]string) {
+		used := &[]string{}
+		db, _ := instance(t, replicas, storage.NewMemoryWriteTracker(), used)
+		return db, used
+	}
+	find := func(db *gorm.DB, ctx context.Context) {
+		db.WithContext(ctx).First(&model.User{}, 1)
+	}
+
+	t.Run("should read from the primary by default", func(t *testing.T) {
+		db, used := setup(t, 1)
+
+		find(db, context.Background())
+
+		assert.Equal(t, []string{"primary"}, *used)
+	})
+
+	t.Run("should read from a replica when allowed", func(t *testing.T) {
+		db, used := setup(t, 1)
+
+		find(db, storage.ReadFromReplica(context.Background()))
+
+		assert.Equal(t, []string{"replica1"}, *used)
+	})
+
+	t.Run("should use the replicas in turn", func(t *testing.T) {
+		db, used := setup(t, 2)
+		ctx := storage.ReadFromReplica(context.Background())
+
+		find(db, ctx)
+		find(db, ctx)
+		find(db, ctx)
+
+		assert.ElementsMatch(t, []string{"replica1", "replica2"}, (*used)[:2])
+		assert.Equal(t, (*used)[0], (*used)[2])
+	})
+
+	t.Run("should keep reads for writes on the primary", func(t *testing.T) {
+		db, used := setup(t, 1)
+
+		find(db, storage.ReadFromReplica(storage.ReadFromPrimary(context.Background())))
+
+		assert.Equal(t, []string{"primary"}, *used)
+	})
+
+	t.Run("should read the user's own writes from the primary", func(t *testing.T) {
+		db, used := setup(t, 1)
+		writer := storage.WithUser(context.Background(), 1)
+		other := storage.WithUser(context.Background(), 2)
+
+		db.WithContext(writer).Exec("UPDATE users SET role = 'admin' WHERE id = 1")
+		find(db, storage.ReadFromReplica(writer))
+		find(db, storage.ReadFromReplica(other))
+
+		assert.Equal(t, []string{"primary", "primary", "replica1"}, *used)
+	})
+
+	t.Run("should read from the primary without replicas", func(t *testing.T) {
+		db, used := setup(t, 0)
+
+		find(db, storage.ReadFromReplica(context.Background()))
+
+		assert.Equal(t, []string{"primary"}, *used)
+	})
+
+	t.Run("should read the user's own writes from the primary of every instance", func(t *testing.T) {
+		used := &[]string{}
+		writes := storage.NewMemoryWriteTracker()
+		first, _ := instance(t, 1, writes, used)
+		second, _ := instance(t, 1, writes, used)
+		writer := storage.WithUser(context.Background(), 1)
+
+		first.WithContext(writer).Exec("UPDATE users SET role = 'admin' WHERE id = 1")
+		find(second, storage.ReadFromReplica(writer))
+
+		assert.Equal(t, []string{"primary", "primary"}, *used)
+	})
+
+	t.Run("should read from the primary while writes cannot be looked up", func(t *testing.T) {
+		used := &[]string{}
+		db, _ := instance(t, 1, failingWriteTracker{}, used)
+
+		find(db, storage.ReadFromReplica(storage.WithUser(context.Background(), 1)))
+		find(db, storage.ReadFromReplica(context.Background()))
+
+		assert.Equal(t, []string{"primary", "replica1"}, *used)
+	})
+
+	t.Run("should skip replicas that are down", func(t *testing.T) {
+		used := &[]string{}
+		db, routing := instance(t, 2, storage.NewMemoryWriteTracker(), used)
+		ctx := storage.ReadFromReplica(context.Background())
+
+		routing.SetReplicaUp(0, false)
+		find(db, ctx)
+		find(db, ctx)
+		routing.SetReplicaUp(1, false)
+		find(db, ctx)
+		routing.SetReplicaUp(0, true)
+		find(db, ctx)
+
+		assert.Equal(t, []string{"replica2", "replica2", "primary", "replica1"}, *used)
+	})
+}
+
+// failingWriteTracker is a write tracker whose store is down
+type failingWriteTracker struct{}
+
+func (failingWriteTracker) RecordWrite(ctx context.Context, userID uint, window time.Duration) error {
+	return errRecorded
+}
+
+func (failingWriteTracker) WroteRecently(ctx context.Context, userID uint) (bool, error) {
+	return false, errRecorded
+}
+
+func TestMemoryWriteTracker(t *testing.T) {
+	runWriteTrackerTests(t, func(t *testing.T) storage.WriteTracker {
+		return storage.NewMemoryWriteTracker()
+	})
+}
+
+// TestRedisWriteTracker runs the conformance tests against Redis when TEST_REDIS_ADDR is set.
+// The selected database is flushed before every test.
+func TestRedisWriteTracker(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+
+	runWriteTrackerTests(t, func(t *testing.T) storage.WriteTracker {
+		require.NoError(t, client.FlushDB(context.Background()).Err())
+		return storage.NewRedisWriteTracker(client)
+	})
+}
+
+func runWriteTrackerTests(t *testing.T, newTracker func(t *testing.T) storage.WriteTracker) {
+	ctx := context.Background()
+
+	t.Run("should remember writes for the window", func(t *testing.T) {
+		writes := newTracker(t)
+		require.NoError(t, writes.RecordWrite(ctx, 1, 100*time.Millisecond))
+
+		wrote, err := writes.WroteRecently(ctx, 1)
+		require.NoError(t, err)
+		assert.True(t, wrote)
+		wrote, err = writes.WroteRecently(ctx, 2)
+		require.NoError(t, err)
+		assert.False(t, wrote)
+
+		time.Sleep(150 * time.Millisecond)
+		wrote, err = writes.WroteRecently(ctx, 1)
+		require.NoError(t, err)
+		assert.False(t, wrote)
+	})
+}
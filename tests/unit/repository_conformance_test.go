@@ -0,0 +1,498 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go_api/internal/app/dto"
+	"go_api/internal/app/model"
+	"go_api/internal/app/repository"
+	"go_api/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// repositories is one storage backend for the conformance tests. The repositories share the same data.
+type repositories struct {
+	blogs    repository.BlogRepository
+	users    repository.UserRepository
+	comments repository.CommentRepository
+}
+
+func TestMemoryRepositories(t *testing.T) {
+	newRepos := func(t *testing.T) repositories {
+		return repositories{
+			blogs:    repository.NewMemoryBlogRepository(),
+			users:    repository.NewMemoryUserRepository(),
+			comments: repository.NewMemoryCommentRepository(),
+		}
+	}
+	runBlogRepositoryTests(t, newRepos)
+	runUserRepositoryTests(t, newRepos)
+	runCommentRepositoryTests(t, newRepos)
+}
+
+// TestGormRepositories runs the conformance tests against Postgres when TEST_DATABASE_URL is set.
+// The tables of that database are emptied before every test.
+func TestGormRepositories(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(url), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	require.NoError(t, err)
+	migrator, err := storage.NewMigrator(db)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background(), 0)
+	require.NoError(t, err)
+
+	newRepos := func(t *testing.T) repositories {
+		require.NoError(t, db.Exec("TRUNCATE users, blogs, tags, blog_tags, blog_revisions, comments RESTART IDENTITY CASCADE").Error)
+		return repositories{
+			blogs:    repository.NewBlogRepository(db),
+			users:    repository.NewUserRepository(db),
+			comments: repository.NewCommentRepository(db),
+		}
+	}
+	runBlogRepositoryTests(t, newRepos)
+	runUserRepositoryTests(t, newRepos)
+	runCommentRepositoryTests(t, newRepos)
+}
+
+func createTestUser(t *testing.T, repos repositories, name string) *model.User {
+	user := &model.User{Username: name, Email: name + "@example.com", Password: "hash", Role: model.RoleUser}
+	require.NoError(t, repos.users.Create(context.Background(), user))
+	return user
+}
+
+func createTestBlog(t *testing.T, repos repositories, blog *model.Blog, tags ...string) *model.Blog {
+	ctx := context.Background()
+	require.NoError(t, repos.blogs.CreateBlog(ctx, blog))
+	if len(tags) > 0 {
+		_, err := repos.blogs.SetTags(ctx, blog.ID, tags)
+		require.NoError(t, err)
+	}
+	return blog
+}
+
+func blogTitles(blogs []model.Blog) []string {
+	titles := []string{}
+	for _, blog := range blogs {
+		titles = append(titles, blog.Title)
+	}
+	return titles
+}
+
+func runBlogRepositoryTests(t *testing.T, newRepos func(t *testing.T) repositories) {
+	ctx := context.Background()
+	byID := dto.PageQuery{Limit: 10, Sort: "id"}
+
+	t.Run("should create blog with defaults", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createTestUser(t, repos, "alice")
+
+		blog := createTestBlog(t, repos, &model.Blog{Title: "Hello", Content: "World", UserID: user.ID})
+
+		assert.NotZero(t, blog.ID)
+		loaded, err := repos.blogs.GetBlog(ctx, fmt.Sprint(blog.ID))
+		require.NoError(t, err)
+		assert.Equal(t, "Hello", loaded.Title)
+		assert.Equal(t, model.BlogStatusPublished, loaded.Status)
+		assert.Equal(t, uint(1), loaded.Version)
+		assert.Empty(t, loaded.Tags)
+	})
+
+	t.Run("should report missing blog as record not found", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.blogs.GetBlog(ctx, "999")
+
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("should update blog only at its current version", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createTestUser(t, repos, "alice")
+		blog := createTestBlog(t, repos, &model.Blog{Title: "Hello", Content: "World", UserID: user.ID})
+
+		stale := *blog
+		blog.Title = "Updated"
+		require.NoError(t, repos.blogs.UpdateBlog(ctx, blog))
+		assert.Equal(t, uint(2), blog.Version)
+
+		stale.Title = "Stale"
+		assert.ErrorIs(t, repos.blogs.UpdateBlog(ctx, &stale), repository.ErrVersionConflict)
+
+		loaded, err := repos.blogs.GetBlog(ctx, fmt.Sprint(blog.ID))
+		require.NoError(t, err)
+		assert.Equal(t, "Updated", loaded.Title)
+		assert.Equal(t, uint(2), loaded.Version)
+	})
+
+	t.Run("should only delete blog of its owner", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createTestUser(t, repos, "alice")
+		bob := createTestUser(t, repos, "bob")
+		blog := createTestBlog(t, repos, &model.Blog{Title: "Hello", Content: "World", UserID: alice.ID})
+		id := fmt.Sprint(blog.ID)
+
+		assert.ErrorIs(t, repos.blogs.DeleteBlog(ctx, id, bob.ID), repository.ErrBlogNotFound)
+		require.NoError(t, repos.blogs.DeleteBlog(ctx, id, alice.ID))
+
+		_, err := repos.blogs.GetBlog(ctx, id)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, repos.blogs.DeleteAnyBlog(ctx, id), repository.ErrBlogNotFound)
+	})
+
+	t.Run("should discard changes of failed transaction", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createTestUser(t, repos, "alice")
+		failure := errors.New("failure")
+
+		var blog model.Blog
+		err := repos.blogs.Transaction(ctx, func(repo repository.BlogRepository) error {
+			blog = model.Blog{Title: "Hello", Content: "World", UserID: user.ID}
+			if err := repo.CreateBlog(ctx, &blog); err != nil {
+				return err
+			}
+			return failure
+		})
+
+		assert.ErrorIs(t, err, failure)
+		_, err = repos.blogs.GetBlog(ctx, fmt.Sprint(blog.ID))
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("should list only visible blogs", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createTestUser(t, repos, "alice")
+		bob := createTestUser(t, repos, "bob")
+		createTestBlog(t, repos, &model.Blog{Title: "Public", Content: "Text", UserID: alice.ID})
+		createTestBlog(t, repos, &model.Blog{Title: "Draft", Content: "Text", UserID: alice.ID, Status: model.BlogStatusDraft})
+
+		anonymous, _, err := repos.blogs.ListBlogs(ctx, dto.BlogFilter{}, byID)
+		require.NoError(t, err)
+		owner, _, err := repos.blogs.ListBlogs(ctx, dto.BlogFilter{ViewerID: alice.ID}, byID)
+		require.NoError(t, err)
+		other, _, err := repos.blogs.ListBlogs(ctx, dto.BlogFilter{ViewerID: bob.ID}, byID)
+		require.NoError(t, err)
+		admin, _, err := repos.blogs.ListBlogs(ctx, dto.BlogFilter{ViewAll: true, Status: "draft"}, byID)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Public"}, blogTitles(anonymous))
+		assert.Equal(t, []string{"Public", "Draft"}, blogTitles(owner))
+		assert.Equal(t, []string{"Public"}, blogTitles(other))
+		assert.Equal(t, []string{"Draft"}, blogTitles(admin))
+	})
+
+	t.Run("should filter blogs by title, author and tags", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createTestUser(t, repos, "alice")
+		bob := createTestUser(t, repos, "bob")
+		createTestBlog(t, repos, &model.Blog{Title: "Learning Go", Content: "Text", UserID: alice.ID}, "go", "web")
+		createTestBlog(t, repos, &model.Blog{Title: "Learning Rust", Content: "Text", UserID: bob.ID}, "rust")
+		createTestBlog(t, repos, &model.Blog{Title: "Go Web Servers", Content: "Text", UserID: bob.ID}, "go")
+
+		byTitle, _, err := repos.blogs.ListBlogs(ctx, dto.BlogFilter{Title: "LEARNING"}, byID)
+		require.NoError(t, err)
+		byAuthor, _, err := repos.blogs.ListBlogs(ctx, dto.BlogFilter{AuthorID: bob.ID}, byID)
+		require.NoError(t, err)
+		anyTag, _, err := repos.blogs.ListBlogs(ctx, dto.BlogFilter{Tags: []string{"web", "rust"}}, byID)
+		require.NoError(t, err)
+		allTags, _, err := repos.blogs.ListBlogs(ctx, dto.BlogFilter{Tags: []string{"go", "web"}, MatchAllTags: true}, byID)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Learning Go", "Learning Rust"}, blogTitles(byTitle))
+		assert.Equal(t, []string{"Learning Rust", "Go Web Servers"}, blogTitles(byAuthor))
+		assert.Equal(t, []string{"Learning Go", "Learning Rust"}, blogTitles(anyTag))
+		assert.Equal(t, []string{"Learning Go"}, blogTitles(allTags))
+		assert.Equal(t, []string{"go", "web"}, model.TagNames(allTags[0].Tags))
+	})
+
+	t.Run("should paginate blogs with cursor", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createTestUser(t, repos, "alice")
+		for _, title := range []string{"B", "C", "A"} {
+			createTestBlog(t, repos, &model.Blog{Title: title, Content: "Text", UserID: user.ID})
+		}
+		page := dto.PageQuery{Limit: 2, Sort: "title", Desc: true}
+
+		first, cursor, err := repos.blogs.ListBlogs(ctx, dto.BlogFilter{}, page)
+		require.NoError(t, err)
+		require.NotEmpty(t, cursor)
+		page.Cursor = cursor
+		second, cursor, err := repos.blogs.ListBlogs(ctx, dto.BlogFilter{}, page)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"C", "B"}, blogTitles(first))
+		assert.Equal(t, []string{"A"}, blogTitles(second))
+		assert.Empty(t, cursor)
+	})
+
+	t.Run("should replace tags and count them on published blogs", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createTestUser(t, repos, "alice")
+		first := createTestBlog(t, repos, &model.Blog{Title: "First", Content: "Text", UserID: user.ID}, "go", "web")
+		createTestBlog(t, repos, &model.Blog{Title: "Second", Content: "Text", UserID: user.ID}, "web")
+		createTestBlog(t, repos, &model.Blog{Title: "Draft", Content: "Text", UserID: user.ID, Status: model.BlogStatusDraft}, "go", "draft")
+
+		tags, err := repos.blogs.SetTags(ctx, first.ID, []string{"web", "api"})
+		require.NoError(t, err)
+		counts, err := repos.blogs.ListTagCounts(ctx, 10)
+		require.NoError(t, err)
+		limited, err := repos.blogs.ListTagCounts(ctx, 1)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"api", "web"}, model.TagNames(tags))
+		assert.Equal(t, []model.TagCount{{Name: "web", Count: 2}, {Name: "api", Count: 1}}, counts)
+		assert.Len(t, limited, 1)
+	})
+
+	t.Run("should store one revision per blog version", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createTestUser(t, repos, "alice")
+		blog := createTestBlog(t, repos, &model.Blog{Title: "Hello", Content: "World", UserID: user.ID})
+
+		require.NoError(t, repos.blogs.CreateRevision(ctx, model.NewBlogRevision(blog, &user.ID)))
+		err := repos.blogs.CreateRevision(ctx, model.NewBlogRevision(blog, &user.ID))
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+
+		revision, err := repos.blogs.GetRevision(ctx, blog.ID, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "Hello", revision.Title)
+		_, err = repos.blogs.GetRevision(ctx, blog.ID, 2)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		revisions, cursor, err := repos.blogs.ListRevisions(ctx, blog.ID, dto.PageQuery{Limit: 10, Sort: "version"})
+		require.NoError(t, err)
+		assert.Len(t, revisions, 1)
+		assert.Empty(t, cursor)
+	})
+
+	t.Run("should publish due scheduled blogs", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createTestUser(t, repos, "alice")
+		past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+		due := createTestBlog(t, repos, &model.Blog{Title: "Due", Content: "Text", UserID: user.ID, Status: model.BlogStatusScheduled, PublishedAt: &past})
+		createTestBlog(t, repos, &model.Blog{Title: "Later", Content: "Text", UserID: user.ID, Status: model.BlogStatusScheduled, PublishedAt: &future})
+
+		err := repos.blogs.Transaction(ctx, func(repo repository.BlogRepository) error {
+			blogs, err := repo.LockDueBlogs(ctx, time.Now())
+			if err != nil {
+				return err
+			}
+			assert.Equal(t, []string{"Due"}, blogTitles(blogs))
+			return repo.MarkPublished(ctx, []uint{due.ID}, time.Now())
+		})
+		require.NoError(t, err)
+
+		loaded, err := repos.blogs.GetBlog(ctx, fmt.Sprint(due.ID))
+		require.NoError(t, err)
+		assert.Equal(t, model.BlogStatusPublished, loaded.Status)
+		assert.Equal(t, uint(2), loaded.Version)
+	})
+
+	t.Run("should search blogs ranking title matches first", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createTestUser(t, repos, "alice")
+		createTestBlog(t, repos, &model.Blog{Title: "Cooking pasta", Content: "Notes about kubernetes clusters", UserID: user.ID})
+		createTestBlog(t, repos, &model.Blog{Title: "Kubernetes basics", Content: "Pods and services", UserID: user.ID})
+		createTestBlog(t, repos, &model.Blog{Title: "Gardening", Content: "Tomatoes", UserID: user.ID})
+
+		results, _, err := repos.blogs.SearchBlogs(ctx, "kubernetes", dto.BlogFilter{}, dto.PageQuery{Limit: 10, Sort: "rank", Desc: true})
+		require.NoError(t, err)
+
+		require.Len(t, results, 2)
+		assert.Equal(t, "Kubernetes basics", results[0].Title)
+		assert.Equal(t, "<mark>Kubernetes</mark> basics", results[0].TitleHighlight)
+		assert.Contains(t, results[1].Snippet, "<mark>kubernetes</mark>")
+		assert.Greater(t, results[0].Rank, results[1].Rank)
+	})
+}
+
+func runUserRepositoryTests(t *testing.T, newRepos func(t *testing.T) repositories) {
+	ctx := context.Background()
+
+	t.Run("should find created user by ID and email", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createTestUser(t, repos, "alice")
+
+		byID, err := repos.users.FindByID(ctx, user.ID)
+		require.NoError(t, err)
+		byEmail, err := repos.users.FindByEmail(ctx, "alice@example.com")
+		require.NoError(t, err)
+
+		assert.Equal(t, "alice", byID.Username)
+		assert.Equal(t, user.ID, byEmail.ID)
+	})
+
+	t.Run("should report missing user as record not found", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.users.FindByID(ctx, 999)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		_, err = repos.users.FindByEmail(ctx, "nobody@example.com")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("should reject duplicate username and email", func(t *testing.T) {
+		repos := newRepos(t)
+		createTestUser(t, repos, "alice")
+
+		sameEmail := &model.User{Username: "other", Email: "alice@example.com", Password: "hash", Role: model.RoleUser}
+		sameName := &model.User{Username: "alice", Email: "other@example.com", Password: "hash", Role: model.RoleUser}
+
+		assert.ErrorIs(t, repos.users.Create(ctx, sameEmail), gorm.ErrDuplicatedKey)
+		assert.ErrorIs(t, repos.users.Create(ctx, sameName), gorm.ErrDuplicatedKey)
+	})
+
+	t.Run("should filter and paginate users", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createTestUser(t, repos, "alice")
+		createTestUser(t, repos, "bob")
+		createTestUser(t, repos, "alicia")
+		require.NoError(t, repos.users.UpdateRole(ctx, alice.ID, model.RoleAdmin))
+
+		byName, _, err := repos.users.ListAllUsers(ctx, dto.UserFilter{Username: "ALI"}, dto.PageQuery{Limit: 10, Sort: "username"})
+		require.NoError(t, err)
+		byRole, _, err := repos.users.ListAllUsers(ctx, dto.UserFilter{Role: "admin"}, dto.PageQuery{Limit: 10, Sort: "id"})
+		require.NoError(t, err)
+		page, cursor, err := repos.users.ListAllUsers(ctx, dto.UserFilter{}, dto.PageQuery{Limit: 2, Sort: "id", Desc: true})
+		require.NoError(t, err)
+
+		require.Len(t, byName, 2)
+		assert.Equal(t, "alice", byName[0].Username)
+		assert.Equal(t, "alicia", byName[1].Username)
+		require.Len(t, byRole, 1)
+		assert.Equal(t, alice.ID, byRole[0].ID)
+		require.Len(t, page, 2)
+		assert.Equal(t, "alicia", page[0].Username)
+		assert.NotEmpty(t, cursor)
+	})
+
+	t.Run("should update role of existing user only", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createTestUser(t, repos, "alice")
+
+		require.NoError(t, repos.users.UpdateRole(ctx, user.ID, model.RoleAdmin))
+		assert.ErrorIs(t, repos.users.UpdateRole(ctx, 999, model.RoleAdmin), gorm.ErrRecordNotFound)
+
+		loaded, err := repos.users.FindByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.RoleAdmin, loaded.Role)
+	})
+
+	t.Run("should replace permissions of existing user only", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createTestUser(t, repos, "alice")
+
+		require.NoError(t, repos.users.UpdatePermissions(ctx, user.ID, []model.Permission{model.PermManageBlogs}))
+		assert.ErrorIs(t, repos.users.UpdatePermissions(ctx, 999, nil), gorm.ErrRecordNotFound)
+		loaded, err := repos.users.FindByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []model.Permission{model.PermManageBlogs}, loaded.Permissions)
+
+		require.NoError(t, repos.users.UpdatePermissions(ctx, user.ID, nil))
+		loaded, err = repos.users.FindByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, loaded.Permissions)
+	})
+}
+
+// createTestComment adds a comment at a given time, as a reply to parent if it is not nil
+func createTestComment(t *testing.T, repos repositories, blog *model.Blog, parent *model.Comment, at time.Time) *model.Comment {
+	comment := &model.Comment{BlogID: blog.ID, UserID: blog.UserID, Content: "Comment", CreatedAt: at}
+	if parent != nil {
+		rootID := parent.ID
+		if parent.RootID != nil {
+			rootID = *parent.RootID
+		}
+		comment.ParentID, comment.RootID, comment.Depth = &parent.ID, &rootID, parent.Depth+1
+	}
+	require.NoError(t, repos.comments.CreateComment(context.Background(), comment))
+	return comment
+}
+
+func commentIDs(comments []model.Comment) []uint {
+	ids := []uint{}
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+	return ids
+}
+
+func runCommentRepositoryTests(t *testing.T, newRepos func(t *testing.T) repositories) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should find comments of their blog only", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createTestUser(t, repos, "alice")
+		blog := createTestBlog(t, repos, &model.Blog{Title: "Hello", Content: "World", UserID: user.ID})
+		other := createTestBlog(t, repos, &model.Blog{Title: "Other", Content: "World", UserID: user.ID})
+		comment := createTestComment(t, repos, blog, nil, start)
+
+		loaded, err := repos.comments.GetComment(ctx, blog.ID, fmt.Sprint(comment.ID))
+		require.NoError(t, err)
+		assert.Equal(t, "Comment", loaded.Content)
+		_, err = repos.comments.GetComment(ctx, other.ID, fmt.Sprint(comment.ID))
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		_, err = repos.comments.GetComment(ctx, blog.ID, "999")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("should edit and delete comments until they are deleted", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createTestUser(t, repos, "alice")
+		blog := createTestBlog(t, repos, &model.Blog{Title: "Hello", Content: "World", UserID: user.ID})
+		comment := createTestComment(t, repos, blog, nil, start)
+
+		comment.Content = "Edited"
+		require.NoError(t, repos.comments.UpdateContent(ctx, comment))
+		assert.NotNil(t, comment.EditedAt)
+		require.NoError(t, repos.comments.SoftDelete(ctx, comment.ID))
+
+		loaded, err := repos.comments.GetComment(ctx, blog.ID, fmt.Sprint(comment.ID))
+		require.NoError(t, err)
+		assert.True(t, loaded.IsDeleted())
+		assert.Empty(t, loaded.Content)
+		assert.ErrorIs(t, repos.comments.UpdateContent(ctx, comment), gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, repos.comments.SoftDelete(ctx, comment.ID), gorm.ErrRecordNotFound)
+	})
+
+	t.Run("should list the first replies of each thread oldest first", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createTestUser(t, repos, "alice")
+		blog := createTestBlog(t, repos, &model.Blog{Title: "Hello", Content: "World", UserID: user.ID})
+		first := createTestComment(t, repos, blog, nil, start)
+		second := createTestComment(t, repos, blog, nil, start.Add(time.Minute))
+		a := createTestComment(t, repos, blog, first, start.Add(2*time.Minute))
+		b := createTestComment(t, repos, blog, second, start.Add(3*time.Minute))
+		c := createTestComment(t, repos, blog, a, start.Add(4*time.Minute))
+		createTestComment(t, repos, blog, first, start.Add(5*time.Minute))
+
+		roots, cursor, err := repos.comments.ListRootComments(ctx, blog.ID, dto.PageQuery{Limit: 10, Sort: "created_at"})
+		require.NoError(t, err)
+		replies, err := repos.comments.ListReplies(ctx, []uint{first.ID, second.ID}, 2)
+		require.NoError(t, err)
+		thread, _, err := repos.comments.ListThreadReplies(ctx, first.ID, dto.PageQuery{Limit: 2, Sort: "created_at"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []uint{first.ID, second.ID}, commentIDs(roots))
+		assert.Empty(t, cursor)
+		assert.Equal(t, []uint{a.ID, b.ID, c.ID}, commentIDs(replies))
+		assert.Equal(t, []uint{a.ID, c.ID}, commentIDs(thread))
+	})
+}
@@ -0,0 +1,113 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go_api/internal/middleware"
+	"go_api/internal/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	t.Run("should parse a valid traceparent", func(t *testing.T) {
+		trace, err := util.ParseTraceparent(validTraceparent)
+
+		require.NoError(t, err)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", trace.TraceID)
+		assert.Equal(t, "00f067aa0ba902b7", trace.ParentID)
+		assert.Equal(t, "01", trace.Flags)
+		assert.Equal(t, validTraceparent, trace.String())
+	})
+
+	t.Run("should reject invalid traceparents", func(t *testing.T) {
+		invalid := []string{
+			"",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		}
+		for _, header := range invalid {
+			_, err := util.ParseTraceparent(header)
+
+			assert.Error(t, err, header)
+		}
+	})
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var requestID string
+	var trace util.TraceContext
+	var hasTrace bool
+	handler := middleware.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = util.RequestID(r.Context())
+		trace, hasTrace = util.TraceContextFrom(r.Context())
+	}))
+
+	serve := func(headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for key, value := range headers {
+			r.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("should keep the client request ID", func(t *testing.T) {
+		w := serve(map[string]string{"X-Request-ID": "client-id-1"})
+
+		assert.Equal(t, "client-id-1", requestID)
+		assert.Equal(t, "client-id-1", w.Header().Get("X-Request-ID"))
+	})
+
+	t.Run("should generate a request ID when missing", func(t *testing.T) {
+		w := serve(nil)
+
+		assert.Len(t, requestID, 32)
+		assert.Equal(t, requestID, w.Header().Get("X-Request-ID"))
+		assert.False(t, hasTrace)
+	})
+
+	t.Run("should replace invalid request IDs", func(t *testing.T) {
+		serve(map[string]string{"X-Request-ID": strings.Repeat("a", 200)})
+
+		assert.Len(t, requestID, 32)
+	})
+
+	t.Run("should use the trace ID of a traceparent", func(t *testing.T) {
+		w := serve(map[string]string{"traceparent": validTraceparent})
+
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", requestID)
+		assert.Equal(t, requestID, w.Header().Get("X-Request-ID"))
+		assert.True(t, hasTrace)
+		assert.Equal(t, "00f067aa0ba902b7", trace.ParentID)
+	})
+
+	t.Run("should prefer the client request ID over the traceparent", func(t *testing.T) {
+		serve(map[string]string{"X-Request-ID": "client-id-2", "traceparent": validTraceparent})
+
+		assert.Equal(t, "client-id-2", requestID)
+		assert.True(t, hasTrace)
+	})
+
+	t.Run("should include the request ID in problems", func(t *testing.T) {
+		errHandler := middleware.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			util.ResponseWithError(w, r, http.StatusNotFound, "not_found", "")
+		}))
+		w := httptest.NewRecorder()
+
+		errHandler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Contains(t, w.Body.String(), `"request_id":"`+w.Header().Get("X-Request-ID")+`"`)
+	})
+}
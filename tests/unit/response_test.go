@@ -2,6 +2,7 @@ package unit
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -45,49 +46,96 @@ func TestResponseWithSuccess(t *testing.T) {
 	})
 }
 
-func TestResponseWithError(t *testing.T) {
-	t.Run("should return error response with error details", func(t *testing.T) {
+func TestResponseWithPage(t *testing.T) {
+	t.Run("should return page with next cursor", func(t *testing.T) {
 		w := httptest.NewRecorder()
 
-		util.ResponseWithError(w, http.StatusBadRequest, "Bad Request", "Invalid input")
+		util.ResponseWithPage(w, http.StatusOK, "List", []string{"a", "b"}, "next")
 
-		assert.Equal(t, http.StatusBadRequest, w.Code)
-		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, http.StatusOK, w.Code)
 
-		var response util.ErrorResponse
+		var response util.SuccessResponse
 		err := json.Unmarshal(w.Body.Bytes(), &response)
 
 		assert.NoError(t, err)
-		assert.Equal(t, "Bad Request", response.Message)
-		assert.Equal(t, "Invalid input", response.Error)
+		assert.Equal(t, "List", response.Message)
+		assert.Equal(t, "next", response.NextCursor)
 	})
 
-	t.Run("should return error response for not found", func(t *testing.T) {
+	t.Run("should omit next cursor on last page", func(t *testing.T) {
 		w := httptest.NewRecorder()
 
-		util.ResponseWithError(w, http.StatusNotFound, "Not Found", "Resource not found")
+		util.ResponseWithPage(w, http.StatusOK, "List", []string{"a"}, "")
+
+		assert.NotContains(t, w.Body.String(), "next_cursor")
+	})
+}
+
+func TestResponseWithError(t *testing.T) {
+	t.Run("should return problem details with code", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/blogs/7?x=1", nil)
+
+		util.ResponseWithError(w, r, http.StatusNotFound, "blog_not_found", "blog not found")
 
 		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
 
-		var response util.ErrorResponse
-		err := json.Unmarshal(w.Body.Bytes(), &response)
+		var problem util.Problem
+		err := json.Unmarshal(w.Body.Bytes(), &problem)
 
 		assert.NoError(t, err)
-		assert.Equal(t, "Not Found", response.Message)
-		assert.Equal(t, "Resource not found", response.Error)
+		assert.Equal(t, "/problems/blog_not_found", problem.Type)
+		assert.Equal(t, "Not Found", problem.Title)
+		assert.Equal(t, http.StatusNotFound, problem.Status)
+		assert.Equal(t, "blog not found", problem.Detail)
+		assert.Equal(t, "/blogs/7?x=1", problem.Instance)
+		assert.Equal(t, "blog_not_found", problem.Code)
 	})
 
-	t.Run("should return internal server error", func(t *testing.T) {
+	t.Run("should include request ID from context", func(t *testing.T) {
 		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r = r.WithContext(util.WithRequestID(r.Context(), "req-123"))
 
-		util.ResponseWithError(w, http.StatusInternalServerError, "Internal Server Error", "")
+		util.ResponseWithError(w, r, http.StatusBadRequest, util.CodeInvalidBody, "")
+
+		var problem util.Problem
+		err := json.Unmarshal(w.Body.Bytes(), &problem)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "req-123", problem.RequestID)
+		assert.NotContains(t, w.Body.String(), "detail")
+	})
+}
+
+func TestResponseWithInternalError(t *testing.T) {
+	cause := errors.New("pq: relation \"blogs\" does not exist")
+
+	t.Run("should hide error details", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		util.ResponseWithInternalError(w, httptest.NewRequest(http.MethodGet, "/", nil), cause, false)
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.NotContains(t, w.Body.String(), "relation")
 
-		var response util.ErrorResponse
-		err := json.Unmarshal(w.Body.Bytes(), &response)
+		var problem util.Problem
+		err := json.Unmarshal(w.Body.Bytes(), &problem)
+
+		assert.NoError(t, err)
+		assert.Equal(t, util.CodeInternal, problem.Code)
+	})
+
+	t.Run("should expose error details when allowed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		util.ResponseWithInternalError(w, httptest.NewRequest(http.MethodGet, "/", nil), cause, true)
+
+		var problem util.Problem
+		err := json.Unmarshal(w.Body.Bytes(), &problem)
 
 		assert.NoError(t, err)
-		assert.Equal(t, "Internal Server Error", response.Message)
+		assert.Equal(t, cause.Error(), problem.Detail)
 	})
 }
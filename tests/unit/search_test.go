@@ -0,0 +1,35 @@
+package unit
+
+import (
+	"testing"
+
+	"go_api/internal/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	t.Run("should AND words together", func(t *testing.T) {
+		assert.Equal(t, "go & api", util.ParseSearchQuery("Go API"))
+	})
+
+	t.Run("should turn quoted text into a phrase", func(t *testing.T) {
+		assert.Equal(t, "(rest <-> api) & go", util.ParseSearchQuery(`"REST api" go`))
+	})
+
+	t.Run("should turn trailing star into a prefix match", func(t *testing.T) {
+		assert.Equal(t, "prog:* & go", util.ParseSearchQuery("prog* go"))
+	})
+
+	t.Run("should strip tsquery operators", func(t *testing.T) {
+		assert.Equal(t, "go & api", util.ParseSearchQuery("go & !api | ('"))
+	})
+
+	t.Run("should treat punctuated words as a phrase", func(t *testing.T) {
+		assert.Equal(t, "(e <-> mail)", util.ParseSearchQuery("e-mail"))
+	})
+
+	t.Run("should return empty string when there are no words", func(t *testing.T) {
+		assert.Equal(t, "", util.ParseSearchQuery(` "" * & `))
+	})
+}
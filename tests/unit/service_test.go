@@ -0,0 +1,268 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go_api/internal/app/cache"
+	"go_api/internal/app/dto"
+	"go_api/internal/app/model"
+	"go_api/internal/app/repository"
+	"go_api/internal/app/service"
+	"go_api/internal/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlogService(t *testing.T) {
+	ctx := context.Background()
+	create := dto.CreateBlogRequest{Title: "Hello", Content: "World", Tags: []string{"Go"}}
+
+	t.Run("should store previous version as revision on update", func(t *testing.T) {
+		repo := repository.NewMemoryBlogRepository()
+		blogs := service.NewBlogService(repo)
+		blog, err := blogs.CreateBlog(ctx, create, 1)
+		require.NoError(t, err)
+
+		updated, err := blogs.UpdateBlog(ctx, fmt.Sprint(blog.ID), dto.UpdateBlogRequest{Title: "Updated", Content: "World"}, util.MatchVersions(1), 1, false)
+		require.NoError(t, err)
+
+		assert.Equal(t, uint(2), updated.Version)
+		revision, err := repo.GetRevision(ctx, blog.ID, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "Hello", revision.Title)
+	})
+
+	t.Run("should return current blog on version conflict", func(t *testing.T) {
+		blogs := service.NewBlogService(repository.NewMemoryBlogRepository())
+		blog, err := blogs.CreateBlog(ctx, create, 1)
+		require.NoError(t, err)
+		id := fmt.Sprint(blog.ID)
+		_, err = blogs.UpdateBlog(ctx, id, dto.UpdateBlogRequest{Title: "First", Content: "World"}, util.MatchVersions(1), 1, false)
+		require.NoError(t, err)
+
+		current, err := blogs.UpdateBlog(ctx, id, dto.UpdateBlogRequest{Title: "Second", Content: "World"}, util.MatchVersions(1), 1, false)
+
+		assert.ErrorIs(t, err, service.ErrBlogVersionConflict)
+		assert.Equal(t, "First", current.Title)
+	})
+
+	t.Run("should accept any listed version but no weak tag", func(t *testing.T) {
+		blogs := service.NewBlogService(repository.NewMemoryBlogRepository())
+		blog, err := blogs.CreateBlog(ctx, create, 1)
+		require.NoError(t, err)
+		id := fmt.Sprint(blog.ID)
+		weak, err := util.ParseIfMatch(`W/"1"`)
+		require.NoError(t, err)
+
+		_, err = blogs.UpdateBlog(ctx, id, dto.UpdateBlogRequest{Title: "Weak", Content: "World"}, weak, 1, false)
+		assert.ErrorIs(t, err, service.ErrBlogVersionConflict)
+
+		updated, err := blogs.UpdateBlog(ctx, id, dto.UpdateBlogRequest{Title: "Listed", Content: "World"}, util.MatchVersions(5, 1), 1, false)
+		require.NoError(t, err)
+		assert.Equal(t, "Listed", updated.Title)
+	})
+
+	t.Run("should forbid other users from editing published blog", func(t *testing.T) {
+		blogs := service.NewBlogService(repository.NewMemoryBlogRepository())
+		blog, err := blogs.CreateBlog(ctx, create, 1)
+		require.NoError(t, err)
+		id := fmt.Sprint(blog.ID)
+
+		_, err = blogs.UpdateBlog(ctx, id, dto.UpdateBlogRequest{Title: "Mine", Content: "World"}, util.IfMatch{}, 2, false)
+		assert.ErrorIs(t, err, service.ErrBlogForbidden)
+		assert.ErrorIs(t, blogs.DeleteBlog(ctx, id, 2, false), service.ErrBlogDeletion)
+		assert.NoError(t, blogs.DeleteBlog(ctx, id, 2, true))
+	})
+
+	t.Run("should hide drafts of other users", func(t *testing.T) {
+		blogs := service.NewBlogService(repository.NewMemoryBlogRepository())
+		draft := create
+		draft.Status = "draft"
+		blog, err := blogs.CreateBlog(ctx, draft, 1)
+		require.NoError(t, err)
+		id := fmt.Sprint(blog.ID)
+
+		_, err = blogs.GetBlog(ctx, id, 2, false)
+		assert.ErrorIs(t, err, service.ErrBlogNotFound)
+		_, err = blogs.UpdateBlog(ctx, id, dto.UpdateBlogRequest{Title: "Mine", Content: "World"}, util.IfMatch{}, 2, false)
+		assert.ErrorIs(t, err, service.ErrBlogNotFound)
+		_, err = blogs.GetBlog(ctx, id, 1, false)
+		assert.NoError(t, err)
+	})
+}
+
+func TestUserService(t *testing.T) {
+	ctx := context.Background()
+	register := dto.CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "password123"}
+	login := dto.LoginUserRequest{Email: "alice@example.com", Password: "password123"}
+
+	t.Run("should reject duplicate email", func(t *testing.T) {
+		users := service.NewUserService(repository.NewMemoryUserRepository(), cache.NewMemoryUserCache(), []byte("service-secret"))
+		_, err := users.CreateUser(ctx, register)
+		require.NoError(t, err)
+
+		duplicate := register
+		duplicate.Username = "other"
+		_, err = users.CreateUser(ctx, duplicate)
+
+		assert.ErrorIs(t, err, service.ErrUserCreation)
+	})
+
+	t.Run("should log in with correct password only", func(t *testing.T) {
+		users := service.NewUserService(repository.NewMemoryUserRepository(), cache.NewMemoryUserCache(), []byte("service-secret"))
+		_, err := users.CreateUser(ctx, register)
+		require.NoError(t, err)
+
+		tokens, err := users.LoginUser(ctx, login)
+		require.NoError(t, err)
+		assert.NotEmpty(t, tokens.AccessToken)
+		assert.NotEmpty(t, tokens.RefreshToken)
+
+		wrong := login
+		wrong.Password = "wrong-password"
+		_, err = users.LoginUser(ctx, wrong)
+		assert.ErrorIs(t, err, service.ErrInvalidPassword)
+	})
+
+	t.Run("should detect refresh token reuse", func(t *testing.T) {
+		users := service.NewUserService(repository.NewMemoryUserRepository(), cache.NewMemoryUserCache(), []byte("service-secret"))
+		_, err := users.CreateUser(ctx, register)
+		require.NoError(t, err)
+		tokens, err := users.LoginUser(ctx, login)
+		require.NoError(t, err)
+
+		refreshed, err := users.RefreshToken(ctx, dto.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+		require.NoError(t, err)
+		_, err = users.RefreshToken(ctx, dto.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+		assert.ErrorIs(t, err, service.ErrRefreshTokenReuse)
+		_, err = users.RefreshToken(ctx, dto.RefreshTokenRequest{RefreshToken: refreshed.RefreshToken})
+		assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+	})
+
+	t.Run("should log out the session of the refresh token only", func(t *testing.T) {
+		users := service.NewUserService(repository.NewMemoryUserRepository(), cache.NewMemoryUserCache(), []byte("service-secret"))
+		user, err := users.CreateUser(ctx, register)
+		require.NoError(t, err)
+		laptop, err := users.LoginUser(ctx, login)
+		require.NoError(t, err)
+		phone, err := users.LoginUser(ctx, login)
+		require.NoError(t, err)
+
+		err = users.LogoutUser(ctx, user.ID, laptop.AccessToken, nil, dto.LogoutRequest{RefreshToken: laptop.RefreshToken})
+		require.NoError(t, err)
+
+		_, err = users.RefreshToken(ctx, dto.RefreshTokenRequest{RefreshToken: laptop.RefreshToken})
+		assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+		_, err = users.RefreshToken(ctx, dto.RefreshTokenRequest{RefreshToken: phone.RefreshToken})
+		assert.NoError(t, err)
+	})
+
+	t.Run("should refuse to log out the session of another user", func(t *testing.T) {
+		users := service.NewUserService(repository.NewMemoryUserRepository(), cache.NewMemoryUserCache(), []byte("service-secret"))
+		user, err := users.CreateUser(ctx, register)
+		require.NoError(t, err)
+		tokens, err := users.LoginUser(ctx, login)
+		require.NoError(t, err)
+
+		err = users.LogoutUser(ctx, user.ID+1, "other-access-token", nil, dto.LogoutRequest{RefreshToken: tokens.RefreshToken})
+		assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+		_, err = users.RefreshToken(ctx, dto.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+		assert.NoError(t, err)
+	})
+
+	t.Run("should log out every session", func(t *testing.T) {
+		users := service.NewUserService(repository.NewMemoryUserRepository(), cache.NewMemoryUserCache(), []byte("service-secret"))
+		user, err := users.CreateUser(ctx, register)
+		require.NoError(t, err)
+		laptop, err := users.LoginUser(ctx, login)
+		require.NoError(t, err)
+		phone, err := users.LoginUser(ctx, login)
+		require.NoError(t, err)
+
+		require.NoError(t, users.LogoutAllSessions(ctx, user.ID, laptop.AccessToken, nil))
+
+		for _, tokens := range []*dto.TokenResponse{laptop, phone} {
+			_, err = users.RefreshToken(ctx, dto.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+			assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+		}
+	})
+
+	t.Run("should cache profile and refresh it on role change", func(t *testing.T) {
+		users := service.NewUserService(repository.NewMemoryUserRepository(), cache.NewMemoryUserCache(), []byte("service-secret"))
+		user, err := users.CreateUser(ctx, register)
+		require.NoError(t, err)
+
+		_, cached, err := users.GetUserProfile(ctx, user.ID)
+		require.NoError(t, err)
+		assert.False(t, cached)
+
+		_, err = users.UpdateUserRole(ctx, user.ID, dto.UpdateUserRoleRequest{Role: "admin"})
+		require.NoError(t, err)
+		profile, cached, err := users.GetUserProfile(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, cached)
+		assert.Equal(t, "admin", string(profile.Role))
+	})
+
+	t.Run("should grant extra permissions carried in the next tokens", func(t *testing.T) {
+		users := service.NewUserService(repository.NewMemoryUserRepository(), cache.NewMemoryUserCache(), []byte("service-secret"))
+		user, err := users.CreateUser(ctx, register)
+		require.NoError(t, err)
+
+		updated, err := users.UpdateUserPermissions(ctx, user.ID, dto.UpdateUserPermissionsRequest{
+			Permissions: []string{"blogs:manage", "blogs:manage"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []model.Permission{model.PermManageBlogs}, updated.Permissions)
+
+		tokens, err := users.LoginUser(ctx, login)
+		require.NoError(t, err)
+		claims, err := util.ParseToken(tokens.AccessToken, []byte("service-secret"))
+		require.NoError(t, err)
+		assert.True(t, claims.HasPermission(string(model.PermManageBlogs)))
+
+		_, err = users.UpdateUserPermissions(ctx, user.ID, dto.UpdateUserPermissionsRequest{Permissions: []string{"blogs:destroy"}})
+		assert.ErrorIs(t, err, service.ErrInvalidPermission)
+		_, err = users.UpdateUserPermissions(ctx, 999, dto.UpdateUserPermissionsRequest{})
+		assert.ErrorIs(t, err, service.ErrUserNotFound)
+	})
+}
+
+func TestCommentService(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should nest replies and flag threads with replies left out", func(t *testing.T) {
+		blogRepo := repository.NewMemoryBlogRepository()
+		blog, err := service.NewBlogService(blogRepo).CreateBlog(ctx, dto.CreateBlogRequest{Title: "Hello", Content: "World"}, 1)
+		require.NoError(t, err)
+		comments := service.NewCommentService(repository.NewMemoryCommentRepository(), blogRepo)
+		blogID := fmt.Sprint(blog.ID)
+
+		root, err := comments.CreateComment(ctx, blogID, dto.CreateCommentRequest{Content: "Root"}, 2, false)
+		require.NoError(t, err)
+		for range model.MaxThreadReplies + 1 {
+			_, err := comments.CreateComment(ctx, blogID, dto.CreateCommentRequest{Content: "Reply", ParentID: &root.ID}, 2, false)
+			require.NoError(t, err)
+		}
+
+		tree, _, err := comments.ListComments(ctx, blogID, dto.PageQuery{Limit: 10, Sort: "created_at"}, 0, false)
+		require.NoError(t, err)
+		require.Len(t, tree, 1)
+		assert.Len(t, tree[0].Replies, model.MaxThreadReplies)
+		assert.True(t, tree[0].MoreReplies)
+	})
+
+	t.Run("should not comment on blogs the user cannot see", func(t *testing.T) {
+		blogRepo := repository.NewMemoryBlogRepository()
+		draft, err := service.NewBlogService(blogRepo).CreateBlog(ctx, dto.CreateBlogRequest{Title: "Hello", Content: "World", Status: "draft"}, 1)
+		require.NoError(t, err)
+		comments := service.NewCommentService(repository.NewMemoryCommentRepository(), blogRepo)
+
+		_, err = comments.CreateComment(ctx, fmt.Sprint(draft.ID), dto.CreateCommentRequest{Content: "Hi"}, 2, false)
+
+		assert.ErrorIs(t, err, service.ErrBlogNotFound)
+	})
+}
@@ -0,0 +1,49 @@
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go_api/internal/app/model"
+	"go_api/internal/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	t.Run("should lowercase and trim", func(t *testing.T) {
+		assert.Equal(t, "golang", util.NormalizeTag("  GoLang "))
+	})
+
+	t.Run("should join words with single hyphens", func(t *testing.T) {
+		assert.Equal(t, "machine-learning", util.NormalizeTag("Machine \t  Learning"))
+	})
+
+	t.Run("should return empty string for whitespace", func(t *testing.T) {
+		assert.Equal(t, "", util.NormalizeTag("   "))
+	})
+}
+
+func TestNormalizeTags(t *testing.T) {
+	t.Run("should drop empty tags and duplicates", func(t *testing.T) {
+		tags := util.NormalizeTags([]string{"Go", " ", "web dev", "go", "Web  Dev", "api"})
+
+		assert.Equal(t, []string{"go", "web-dev", "api"}, tags)
+	})
+
+	t.Run("should return empty slice for no tags", func(t *testing.T) {
+		assert.Empty(t, util.NormalizeTags(nil))
+	})
+}
+
+func TestTagJSON(t *testing.T) {
+	t.Run("should encode tags as their names", func(t *testing.T) {
+		blog := model.Blog{Tags: []model.Tag{{ID: 1, Name: "go"}, {ID: 2, Name: "web-dev"}}}
+
+		data, err := json.Marshal(blog.Tags)
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `["go", "web-dev"]`, string(data))
+		assert.Equal(t, []string{"go", "web-dev"}, model.TagNames(blog.Tags))
+	})
+}
@@ -0,0 +1,44 @@
+package unit
+
+import (
+	"testing"
+
+	"go_api/internal/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateRefreshToken(t *testing.T) {
+	t.Run("should generate refresh token successfully", func(t *testing.T) {
+		token, err := util.GenerateRefreshToken()
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token)
+	})
+
+	t.Run("should generate different tokens each time", func(t *testing.T) {
+		token1, err1 := util.GenerateRefreshToken()
+		token2, err2 := util.GenerateRefreshToken()
+
+		assert.NoError(t, err1)
+		assert.NoError(t, err2)
+		assert.NotEqual(t, token1, token2)
+	})
+}
+
+func TestHashToken(t *testing.T) {
+	t.Run("should hash the same token to the same value", func(t *testing.T) {
+		assert.Equal(t, util.HashToken("token"), util.HashToken("token"))
+	})
+
+	t.Run("should not return the raw token", func(t *testing.T) {
+		hash := util.HashToken("token")
+
+		assert.NotEqual(t, "token", hash)
+		assert.Len(t, hash, 64)
+	})
+
+	t.Run("should hash different tokens to different values", func(t *testing.T) {
+		assert.NotEqual(t, util.HashToken("token1"), util.HashToken("token2"))
+	})
+}
@@ -0,0 +1,104 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go_api/internal/metrics"
+	"go_api/internal/middleware"
+	"go_api/internal/tracing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider recording ended spans for the duration of a test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+// spanAttribute returns the value of a span attribute, or an empty value
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracingSetup(t *testing.T) {
+	t.Run("should be off with the none exporter", func(t *testing.T) {
+		shutdown, err := tracing.Setup(context.Background(), tracing.ExporterNone, 1)
+
+		require.NoError(t, err)
+		assert.NoError(t, shutdown(context.Background()))
+	})
+
+	t.Run("should reject unknown exporters", func(t *testing.T) {
+		_, err := tracing.Setup(context.Background(), "zipkin", 1)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder := recordSpans(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /blogs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("GET /broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	handler := middleware.TracingMiddleware(middleware.MetricsMiddleware(metrics.New())(mux))
+
+	t.Run("should name server spans after the route pattern", func(t *testing.T) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/blogs/42", nil))
+
+		spans := recorder.Ended()
+		require.NotEmpty(t, spans)
+		span := spans[len(spans)-1]
+		assert.Equal(t, "GET /blogs/{id}", span.Name())
+		assert.Equal(t, "42", spanAttribute(span, "blog.id").AsString())
+		assert.Equal(t, int64(http.StatusOK), spanAttribute(span, "http.response.status_code").AsInt64())
+	})
+
+	t.Run("should continue the trace of a traceparent header", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/blogs/7", nil)
+		r.Header.Set("traceparent", validTraceparent)
+
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		spans := recorder.Ended()
+		span := spans[len(spans)-1]
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	})
+
+	t.Run("should mark server errors", func(t *testing.T) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil))
+
+		spans := recorder.Ended()
+		span := spans[len(spans)-1]
+		assert.Equal(t, codes.Error, span.Status().Code)
+	})
+}
@@ -0,0 +1,138 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go_api/internal/app/cache"
+	"go_api/internal/app/model"
+	"go_api/internal/metrics"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryUserCache(t *testing.T) {
+	runUserCacheTests(t, func(t *testing.T) cache.UserCache {
+		return cache.NewMemoryUserCache()
+	})
+}
+
+// TestRedisUserCache runs the conformance tests against Redis when TEST_REDIS_ADDR is set.
+// The selected database is flushed before every test.
+func TestRedisUserCache(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+
+	runUserCacheTests(t, func(t *testing.T) cache.UserCache {
+		require.NoError(t, client.FlushDB(context.Background()).Err())
+		return cache.NewUserCache(client, metrics.New())
+	})
+}
+
+func runUserCacheTests(t *testing.T, newCache func(t *testing.T) cache.UserCache) {
+	ctx := context.Background()
+	session := cache.RefreshSession{UserID: 1, FamilyID: "family"}
+
+	t.Run("should return cached user", func(t *testing.T) {
+		c := newCache(t)
+
+		_, err := c.GetUser(ctx, 1)
+		assert.ErrorIs(t, err, cache.ErrUserNotCached)
+
+		require.NoError(t, c.SetUser(ctx, 1, &model.User{ID: 1, Username: "alice", Role: model.RoleAdmin}))
+		user, err := c.GetUser(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", user.Username)
+		assert.Equal(t, model.RoleAdmin, user.Role)
+	})
+
+	t.Run("should expire blacklisted tokens", func(t *testing.T) {
+		c := newCache(t)
+		require.NoError(t, c.BlacklistToken(ctx, "short", 50*time.Millisecond))
+		require.NoError(t, c.BlacklistToken(ctx, "long", time.Minute))
+
+		time.Sleep(100 * time.Millisecond)
+
+		short, err := c.IsTokenBlacklisted(ctx, "short")
+		require.NoError(t, err)
+		long, err := c.IsTokenBlacklisted(ctx, "long")
+		require.NoError(t, err)
+		unknown, err := c.IsTokenBlacklisted(ctx, "unknown")
+		require.NoError(t, err)
+		assert.False(t, short)
+		assert.True(t, long)
+		assert.False(t, unknown)
+	})
+
+	t.Run("should rotate refresh token within its family", func(t *testing.T) {
+		c := newCache(t)
+		require.NoError(t, c.SaveRefreshToken(ctx, "first", session, time.Minute))
+
+		rotated, err := c.RotateRefreshToken(ctx, "first", "second", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, session, *rotated)
+
+		_, err = c.RotateRefreshToken(ctx, "second", "third", time.Minute)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should reject unknown refresh token", func(t *testing.T) {
+		c := newCache(t)
+
+		_, err := c.RotateRefreshToken(ctx, "unknown", "next", time.Minute)
+
+		assert.ErrorIs(t, err, cache.ErrRefreshTokenNotFound)
+	})
+
+	t.Run("should revoke family when rotated token is reused", func(t *testing.T) {
+		c := newCache(t)
+		require.NoError(t, c.SaveRefreshToken(ctx, "first", session, time.Minute))
+		_, err := c.RotateRefreshToken(ctx, "first", "second", time.Minute)
+		require.NoError(t, err)
+
+		_, err = c.RotateRefreshToken(ctx, "first", "stolen", time.Minute)
+		assert.ErrorIs(t, err, cache.ErrRefreshTokenReused)
+
+		_, err = c.RotateRefreshToken(ctx, "second", "third", time.Minute)
+		assert.ErrorIs(t, err, cache.ErrRefreshTokenNotFound)
+	})
+
+	t.Run("should find the session of rotated refresh tokens", func(t *testing.T) {
+		c := newCache(t)
+		require.NoError(t, c.SaveRefreshToken(ctx, "first", session, time.Minute))
+		_, err := c.RotateRefreshToken(ctx, "first", "second", time.Minute)
+		require.NoError(t, err)
+
+		found, err := c.GetRefreshSession(ctx, "first")
+		require.NoError(t, err)
+		assert.Equal(t, session, *found)
+		_, err = c.GetRefreshSession(ctx, "unknown")
+		assert.ErrorIs(t, err, cache.ErrRefreshTokenNotFound)
+	})
+
+	t.Run("should clean user and refresh families on logout", func(t *testing.T) {
+		c := newCache(t)
+		require.NoError(t, c.SetUser(ctx, 1, &model.User{ID: 1, Username: "alice"}))
+		require.NoError(t, c.SaveRefreshToken(ctx, "first", session, time.Minute))
+		other := cache.RefreshSession{UserID: 2, FamilyID: "family"}
+		require.NoError(t, c.SaveRefreshToken(ctx, "other", other, time.Minute))
+
+		require.NoError(t, c.CleanUserSession(ctx, 1))
+
+		_, err := c.GetUser(ctx, 1)
+		assert.ErrorIs(t, err, cache.ErrUserNotCached)
+		_, err = c.RotateRefreshToken(ctx, "first", "second", time.Minute)
+		assert.ErrorIs(t, err, cache.ErrRefreshTokenNotFound)
+		_, err = c.RotateRefreshToken(ctx, "other", "next", time.Minute)
+		assert.NoError(t, err)
+	})
+}
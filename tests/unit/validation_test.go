@@ -1,11 +1,15 @@
 package unit
 
 import (
+	"errors"
 	"testing"
 
+	"go_api/internal/app/dto"
 	"go_api/internal/util"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Test struct with validation tags
@@ -15,6 +19,31 @@ type TestUser struct {
 	Age      int    `validate:"required,gte=0,lte=130"`
 }
 
+type testAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type testProfile struct {
+	Name      string        `json:"name" validate:"required"`
+	Address   testAddress   `json:"address"`
+	Tags      []string      `json:"tags" validate:"max=2,dive,min=2"`
+	Addresses []testAddress `json:"addresses" validate:"dive"`
+	Code      string        `json:"code" validate:"omitempty,even_length"`
+}
+
+func init() {
+	util.RegisterValidation("even_length", "must have an even length", func(fl validator.FieldLevel) bool {
+		return len(fl.Field().String())%2 == 0
+	})
+}
+
+// fieldErrors returns the field errors of a failed validation
+func fieldErrors(t *testing.T, err error) util.ValidationErrors {
+	var result util.ValidationErrors
+	require.True(t, errors.As(err, &result), "expected validation errors, got %v", err)
+	return result
+}
+
 func TestValidate(t *testing.T) {
 	t.Run("should pass validation for valid struct", func(t *testing.T) {
 		user := TestUser{
@@ -37,7 +66,9 @@ func TestValidate(t *testing.T) {
 
 		err := util.Validate(user)
 
-		assert.Error(t, err)
+		assert.Equal(t, util.ValidationErrors{
+			{Field: "Username", Rule: "required", Message: "is required"},
+		}, fieldErrors(t, err))
 	})
 
 	t.Run("should fail validation for invalid email", func(t *testing.T) {
@@ -49,7 +80,9 @@ func TestValidate(t *testing.T) {
 
 		err := util.Validate(user)
 
-		assert.Error(t, err)
+		assert.Equal(t, util.ValidationErrors{
+			{Field: "Email", Rule: "email", Message: "must be a valid email address"},
+		}, fieldErrors(t, err))
 	})
 
 	t.Run("should fail validation for username too short", func(t *testing.T) {
@@ -61,7 +94,9 @@ func TestValidate(t *testing.T) {
 
 		err := util.Validate(user)
 
-		assert.Error(t, err)
+		assert.Equal(t, util.ValidationErrors{
+			{Field: "Username", Rule: "min", Param: "3", Message: "must be at least 3 characters long"},
+		}, fieldErrors(t, err))
 	})
 
 	t.Run("should fail validation for age out of range", func(t *testing.T) {
@@ -73,6 +108,84 @@ func TestValidate(t *testing.T) {
 
 		err := util.Validate(user)
 
-		assert.Error(t, err)
+		assert.Equal(t, util.ValidationErrors{
+			{Field: "Age", Rule: "lte", Param: "130", Message: "must be at most 130"},
+		}, fieldErrors(t, err))
+	})
+
+	t.Run("should key nested structs and slices by JSON path", func(t *testing.T) {
+		profile := testProfile{
+			Name:      "Jane",
+			Tags:      []string{"go", "x"},
+			Addresses: []testAddress{{City: "Oslo"}, {}},
+		}
+
+		err := util.Validate(profile)
+
+		fields := []string{}
+		for _, fe := range fieldErrors(t, err) {
+			fields = append(fields, fe.Field)
+		}
+		assert.ElementsMatch(t, []string{"address.city", "tags[1]", "addresses[1].city"}, fields)
+	})
+
+	t.Run("should describe item counts of slices", func(t *testing.T) {
+		profile := testProfile{
+			Name:    "Jane",
+			Address: testAddress{City: "Oslo"},
+			Tags:    []string{"go", "api", "web"},
+		}
+
+		err := util.Validate(profile)
+
+		assert.Equal(t, util.ValidationErrors{
+			{Field: "tags", Rule: "max", Param: "2", Message: "must have at most 2 items"},
+		}, fieldErrors(t, err))
+	})
+
+	t.Run("should use the message of custom rules", func(t *testing.T) {
+		profile := testProfile{
+			Name:    "Jane",
+			Address: testAddress{City: "Oslo"},
+			Code:    "abc",
+		}
+
+		err := util.Validate(profile)
+
+		assert.Equal(t, util.ValidationErrors{
+			{Field: "code", Rule: "even_length", Message: "must have an even length"},
+		}, fieldErrors(t, err))
+	})
+}
+
+func TestUserValidationRules(t *testing.T) {
+	valid := dto.CreateUserRequest{Username: "jane_doe-1", Email: "jane@example.com", Password: "Secret123"}
+
+	t.Run("should accept a valid user", func(t *testing.T) {
+		assert.NoError(t, util.Validate(valid))
+	})
+
+	t.Run("should reject usernames outside the character set", func(t *testing.T) {
+		req := valid
+		req.Username = "jane doe!"
+
+		errs := fieldErrors(t, util.Validate(req))
+
+		require.Len(t, errs, 1)
+		assert.Equal(t, "username", errs[0].Field)
+		assert.Equal(t, "username", errs[0].Rule)
+	})
+
+	t.Run("should reject weak passwords", func(t *testing.T) {
+		for _, password := range []string{"alllowercase1", "ALLUPPERCASE1", "NoNumbersHere"} {
+			req := valid
+			req.Password = password
+
+			errs := fieldErrors(t, util.Validate(req))
+
+			require.Len(t, errs, 1, password)
+			assert.Equal(t, "password", errs[0].Field)
+			assert.Equal(t, "password", errs[0].Rule)
+		}
 	})
 }